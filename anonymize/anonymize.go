@@ -0,0 +1,390 @@
+// Package anonymize provides an anonymization pass over parsed requests and
+// responses. Sensitive data like IP addresses, hostnames, credentials,
+// cookies, and email addresses is replaced by hashes or tokens, so that the
+// messages can be serialized again and shared safely.
+package anonymize
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Detector finds sensitive data of a particular kind in a text.
+type Detector interface {
+	// Kind returns the kind of data detected, e.g. "email".
+	Kind() string
+	// FindAll returns the start and end indices of all sensitive substrings.
+	FindAll(s string) [][]int
+}
+
+type regexpDetector struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// RegexpDetector returns a Detector using the given regular expression. If
+// the expression contains a capturing group, only the first group is treated
+// as sensitive.
+func RegexpDetector(kind string, pattern *regexp.Regexp) Detector {
+	return regexpDetector{
+		kind:    kind,
+		pattern: pattern,
+	}
+}
+
+func (r regexpDetector) Kind() string {
+	return r.kind
+}
+
+func (r regexpDetector) FindAll(s string) [][]int {
+	matches := r.pattern.FindAllStringSubmatchIndex(s, -1)
+	indices := make([][]int, 0, len(matches))
+
+	for _, match := range matches {
+		if len(match) >= 4 && match[2] >= 0 {
+			indices = append(indices, match[2:4])
+			continue
+		}
+		indices = append(indices, match[0:2])
+	}
+
+	return indices
+}
+
+// ipDetector detects IP address candidates using a regular expression and
+// only reports those which are actually valid IP addresses.
+type ipDetector struct {
+	candidates *regexp.Regexp
+}
+
+func (i ipDetector) Kind() string {
+	return "ip"
+}
+
+func (i ipDetector) FindAll(s string) [][]int {
+	var indices [][]int
+
+	for _, index := range i.candidates.FindAllStringIndex(s, -1) {
+		if net.ParseIP(s[index[0]:index[1]]) != nil {
+			indices = append(indices, index)
+		}
+	}
+
+	return indices
+}
+
+var (
+	// IPv4Detector detects IPv4 addresses.
+	IPv4Detector Detector = ipDetector{regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)}
+	// IPv6Detector detects IPv6 addresses in their full or compressed form.
+	IPv6Detector Detector = ipDetector{regexp.MustCompile(`(?i)[0-9a-f]{0,4}(?::[0-9a-f]{0,4}){2,7}`)}
+	// EmailDetector detects email addresses.
+	EmailDetector = RegexpDetector("email", regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`))
+	// URLHostDetector detects hostnames within absolute http and https URLs.
+	URLHostDetector = RegexpDetector("host", regexp.MustCompile(`(?i)https?://([^/\s:"'?#]+)`))
+)
+
+// DefaultDetectors returns the detectors used if none are specified.
+func DefaultDetectors() []Detector {
+	return []Detector{IPv4Detector, IPv6Detector, EmailDetector, URLHostDetector}
+}
+
+// Replacer returns the replacement for a sensitive value of a given kind.
+// Replacers must be consistent, i.e. always return the same replacement for
+// the same value, so that anonymized messages remain correlatable.
+type Replacer func(kind, value string) string
+
+// HashReplacer returns a Replacer that replaces values with a keyed hash.
+func HashReplacer(key []byte) Replacer {
+	return func(kind, value string) string {
+		mac := hmac.New(sha256.New, key)
+		_, _ = mac.Write([]byte(value))
+		return fmt.Sprintf("%s-%s", kind, hex.EncodeToString(mac.Sum(nil))[:12])
+	}
+}
+
+// TokenReplacer returns a Replacer that replaces values with sequentially
+// numbered tokens per kind, e.g. "email-1" or "ip-3". It is safe for
+// concurrent use.
+func TokenReplacer() Replacer {
+	var mutex sync.Mutex
+	tokens := make(map[string]string)
+	counters := make(map[string]int)
+
+	return func(kind, value string) string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		key := kind + "\x00" + value
+		if token, ok := tokens[key]; ok {
+			return token
+		}
+
+		counters[kind]++
+		token := kind + "-" + strconv.Itoa(counters[kind])
+		tokens[key] = token
+
+		return token
+	}
+}
+
+// credentialHeaders are header fields whose entire values are replaced.
+var credentialHeaders = map[string]string{
+	"Authorization":       "token",
+	"Proxy-Authorization": "token",
+	"Cookie":              "cookie",
+	"Set-Cookie":          "cookie",
+}
+
+// hostHeaders are header fields that contain a host and an optional port.
+var hostHeaders = map[string]bool{
+	"Host":             true,
+	"X-Forwarded-Host": true,
+}
+
+// textMediaTypes are media types outside of text/* whose bodies are
+// anonymized.
+var textMediaTypes = map[string]bool{
+	"application/json":                  true,
+	"application/xml":                   true,
+	"application/javascript":            true,
+	"application/x-www-form-urlencoded": true,
+}
+
+// Anonymizer replaces sensitive data in parsed requests and responses.
+type Anonymizer struct {
+	replace   Replacer
+	detectors []Detector
+}
+
+// New creates a new Anonymizer using the given Replacer. If no detectors are
+// given, DefaultDetectors are used.
+func New(replace Replacer, detectors ...Detector) *Anonymizer {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+
+	return &Anonymizer{
+		replace:   replace,
+		detectors: detectors,
+	}
+}
+
+// String replaces all sensitive substrings found by the detectors.
+func (a *Anonymizer) String(s string) string {
+	type match struct {
+		start, end int
+		kind       string
+	}
+
+	var matches []match
+
+	for _, detector := range a.detectors {
+		for _, index := range detector.FindAll(s) {
+			matches = append(matches, match{index[0], index[1], detector.Kind()})
+		}
+	}
+
+	if len(matches) == 0 {
+		return s
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].start == matches[j].start {
+			return matches[i].end > matches[j].end
+		}
+		return matches[i].start < matches[j].start
+	})
+
+	var buf bytes.Buffer
+	var last int
+
+	for _, m := range matches {
+		// Skip matches overlapping with an already replaced one.
+		if m.start < last {
+			continue
+		}
+		buf.WriteString(s[last:m.start])
+		buf.WriteString(a.replace(m.kind, s[m.start:m.end]))
+		last = m.end
+	}
+	buf.WriteString(s[last:])
+
+	return buf.String()
+}
+
+// Request anonymizes the target URL, the header fields, and the body of a
+// request in place.
+func (a *Anonymizer) Request(r *http.Request) error {
+	if r.URL != nil {
+		if r.URL.Host != "" {
+			r.URL.Host = a.host(r.URL.Host)
+		}
+		r.URL.User = nil
+		// The raw path is dropped, since it can't be kept consistent with
+		// the anonymized path. The path is escaped again when serialized.
+		r.URL.Path = a.String(r.URL.Path)
+		r.URL.RawPath = ""
+		r.URL.RawQuery = a.String(r.URL.RawQuery)
+	}
+
+	if r.Host != "" {
+		r.Host = a.host(r.Host)
+	}
+
+	if r.RemoteAddr != "" {
+		r.RemoteAddr = a.host(r.RemoteAddr)
+	}
+
+	a.header(r.Header)
+
+	body, err := a.body(r.Body, r.Header)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = updateContentLength(r.Header, body)
+	}
+
+	return nil
+}
+
+// Response anonymizes the header fields and the body of a response in
+// place.
+func (a *Anonymizer) Response(r *http.Response) error {
+	a.header(r.Header)
+
+	body, err := a.body(r.Body, r.Header)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.ContentLength = updateContentLength(r.Header, body)
+	}
+
+	return nil
+}
+
+func (a *Anonymizer) header(header http.Header) {
+	for name, values := range header {
+		kind, isCredential := credentialHeaders[name]
+
+		for i, value := range values {
+			switch {
+			case isCredential:
+				values[i] = a.replace(kind, value)
+			case hostHeaders[name]:
+				values[i] = a.host(value)
+			default:
+				values[i] = a.String(value)
+			}
+		}
+	}
+}
+
+// host replaces a host with an optional port, keeping the port.
+func (a *Anonymizer) host(hostport string) string {
+	// A bare IP address can't be told apart from a host and a port by
+	// splitting, e.g. "2001:db8::1", so it is detected first.
+	if ip := net.ParseIP(hostport); ip != nil {
+		return a.replace("ip", hostport)
+	}
+
+	if strings.HasPrefix(hostport, "[") && strings.HasSuffix(hostport, "]") {
+		if ip := net.ParseIP(hostport[1 : len(hostport)-1]); ip != nil {
+			return "[" + a.replace("ip", hostport[1:len(hostport)-1]) + "]"
+		}
+	}
+
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return a.replace("host", hostport)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return net.JoinHostPort(a.replace("ip", host), port)
+	}
+
+	return net.JoinHostPort(a.replace("host", host), port)
+}
+
+// body anonymizes a text body. Other bodies, e.g. images or compressed data,
+// are returned unchanged since replacing bytes would corrupt them.
+func (a *Anonymizer) body(body io.ReadCloser, header http.Header) ([]byte, error) {
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	if !isText(header, content) {
+		return content, nil
+	}
+
+	return []byte(a.String(string(content))), nil
+}
+
+// isText reports whether a body is uncompressed text, judging by its
+// Content-Type or, if there is none, by its content.
+func isText(header http.Header, content []byte) bool {
+	if encoding := header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return false
+	}
+
+	for _, coding := range header.Values("Transfer-Encoding") {
+		for _, token := range strings.Split(coding, ",") {
+			if token = strings.TrimSpace(token); token != "" && !strings.EqualFold(token, "chunked") {
+				return false
+			}
+		}
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mediaType, "text/") || textMediaTypes[mediaType] ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// updateContentLength updates the Content-Length header field if present,
+// since replacements change the body length.
+func updateContentLength(header http.Header, body []byte) int64 {
+	length := len(body)
+
+	if header.Get("Content-Length") != "" {
+		header.Set("Content-Length", strconv.Itoa(length))
+	}
+
+	return int64(length)
+}
@@ -0,0 +1,233 @@
+package anonymize
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestAnonymizer_String(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected string
+	}{
+		"IPv4 address": {
+			input:    "client 192.168.0.10 connected",
+			expected: "client ip-1 connected",
+		},
+		"IPv6 address": {
+			input:    "client 2001:db8::1 connected",
+			expected: "client ip-1 connected",
+		},
+		"time is no IPv6 address": {
+			input:    "Mon, 02 Jan 2006 15:04:05 GMT",
+			expected: "Mon, 02 Jan 2006 15:04:05 GMT",
+		},
+		"email address": {
+			input:    "contact alice@example.com or bob@example.com",
+			expected: "contact email-1 or email-2",
+		},
+		"URL host": {
+			input:    "see https://internal.example.com/docs",
+			expected: "see https://host-1/docs",
+		},
+		"repeated values": {
+			input:    "10.0.0.1, 10.0.0.2, 10.0.0.1",
+			expected: "ip-1, ip-2, ip-1",
+		},
+	}
+
+	for name, tc := range testCases {
+		anonymizer := New(TokenReplacer())
+
+		if actual := anonymizer.String(tc.input); actual != tc.expected {
+			t.Errorf("'%s': expected %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestAnonymizer_Request(t *testing.T) {
+	source := "POST /login?user=alice@example.com HTTP/1.1\r\n" +
+		"Host: api.example.com:8080\r\n" +
+		"Authorization: Bearer secret\r\n" +
+		"Cookie: session=abc\r\n" +
+		"X-Forwarded-For: 203.0.113.7\r\n" +
+		"Content-Length: 27\r\n" +
+		"\r\n" +
+		`{"email":"bob@example.com"}`
+
+	request, err := gohttp.ParseRequest(bufio.NewReader(strings.NewReader(source)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if err := New(TokenReplacer()).Request(request); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expectedHeaders := map[string]string{
+		"Host":            "host-1:8080",
+		"Authorization":   "token-1",
+		"Cookie":          "cookie-1",
+		"X-Forwarded-For": "ip-1",
+		"Content-Length":  "19",
+	}
+
+	for name, expected := range expectedHeaders {
+		if actual := request.Header.Get(name); actual != expected {
+			t.Errorf("expected %s header %s, got %s", name, expected, actual)
+		}
+	}
+
+	if request.URL.RawQuery != "user=email-1" {
+		t.Errorf("expected query %s, got %s", "user=email-1", request.URL.RawQuery)
+	}
+
+	body, _ := ioutil.ReadAll(request.Body)
+
+	if string(body) != `{"email":"email-2"}` {
+		t.Errorf("expected body %s, got %s", `{"email":"email-2"}`, string(body))
+	}
+
+	if request.ContentLength != int64(len(body)) {
+		t.Errorf("expected content length %d, got %d", len(body), request.ContentLength)
+	}
+}
+
+func TestAnonymizer_Target(t *testing.T) {
+	testCases := map[string]struct {
+		target       string
+		host         string
+		expectedURL  string
+		expectedHost string
+	}{
+		"host with port": {
+			target:       "/",
+			host:         "api.example.com:8080",
+			expectedURL:  "/",
+			expectedHost: "host-1:8080",
+		},
+		"IPv4 address": {
+			target:       "/",
+			host:         "203.0.113.7",
+			expectedURL:  "/",
+			expectedHost: "ip-1",
+		},
+		"IPv6 address": {
+			target:       "/",
+			host:         "[2001:db8::1]",
+			expectedURL:  "/",
+			expectedHost: "[ip-1]",
+		},
+		"path": {
+			target:       "/users/alice@example.com/sessions/10.0.0.1",
+			host:         "example.com",
+			expectedURL:  "/users/email-1/sessions/ip-1",
+			expectedHost: "host-1",
+		},
+		"escaped path": {
+			target:       "/users/alice%40example.com",
+			host:         "example.com",
+			expectedURL:  "/users/email-1",
+			expectedHost: "host-1",
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "GET " + tc.target + " HTTP/1.1\r\nHost: " + tc.host + "\r\n\r\n"
+
+		request, err := gohttp.ParseRequest(bufio.NewReader(strings.NewReader(source)))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if err := New(TokenReplacer()).Request(request); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if request.URL.String() != tc.expectedURL {
+			t.Errorf("'%s': expected URL %s, got %s", name, tc.expectedURL, request.URL.String())
+		}
+
+		if request.Header.Get("Host") != tc.expectedHost {
+			t.Errorf("'%s': expected host %s, got %s", name, tc.expectedHost, request.Header.Get("Host"))
+		}
+	}
+}
+
+func TestAnonymizer_Body(t *testing.T) {
+	testCases := map[string]struct {
+		header   string
+		body     string
+		expected string
+	}{
+		"text": {
+			header:   "Content-Type: text/plain\r\n",
+			body:     "from alice@example.com",
+			expected: "from email-1",
+		},
+		"JSON subtype": {
+			header:   "Content-Type: application/problem+json\r\n",
+			body:     `{"ip":"10.0.0.1"}`,
+			expected: `{"ip":"ip-1"}`,
+		},
+		"binary": {
+			header:   "Content-Type: application/octet-stream\r\n",
+			body:     "from alice@example.com",
+			expected: "from alice@example.com",
+		},
+		"compressed": {
+			header:   "Content-Type: text/plain\r\nContent-Encoding: gzip\r\n",
+			body:     "from alice@example.com",
+			expected: "from alice@example.com",
+		},
+		"sniffed binary": {
+			body:     "\x89PNG\r\n\x1a\n10.0.0.1",
+			expected: "\x89PNG\r\n\x1a\n10.0.0.1",
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "HTTP/1.1 200 OK\r\n" + tc.header +
+			"Content-Length: " + strconv.Itoa(len(tc.body)) + "\r\n\r\n" + tc.body
+
+		response, err := gohttp.ParseResponse(bufio.NewReader(strings.NewReader(source)))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if err := New(TokenReplacer()).Response(response); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		body, _ := ioutil.ReadAll(response.Body)
+
+		if string(body) != tc.expected {
+			t.Errorf("'%s': expected body %q, got %q", name, tc.expected, string(body))
+		}
+	}
+}
+
+func TestHashReplacer(t *testing.T) {
+	replace := HashReplacer([]byte("key"))
+
+	first := replace("ip", "10.0.0.1")
+	second := replace("ip", "10.0.0.1")
+	other := replace("ip", "10.0.0.2")
+
+	if first != second {
+		t.Errorf("expected consistent replacement, got %s and %s", first, second)
+	}
+
+	if first == other {
+		t.Errorf("expected different replacements, got %s twice", first)
+	}
+
+	if !strings.HasPrefix(first, "ip-") {
+		t.Errorf("expected replacement with prefix ip-, got %s", first)
+	}
+}
@@ -0,0 +1,122 @@
+// Package audit records the decisions of policy modules that block or modify
+// requests, e.g. rule hits, rate limiting, authentication failures, and
+// sanitization. Each decision is written as a line of NDJSON, carrying the
+// request ID and the identifier of the rule that caused it.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+	"github.com/dominikbraun/gohttp/rules"
+)
+
+// Actions taken by policy modules.
+const (
+	// ActionBlock indicates a rejected request.
+	ActionBlock = "block"
+	// ActionModify indicates a modified request, e.g. a stripped header.
+	ActionModify = "modify"
+	// ActionLog indicates a request that has only been flagged.
+	ActionLog = "log"
+)
+
+// Decision is a single entry of the audit log.
+type Decision struct {
+	Time time.Time `json:"time"`
+	// TransactionID is the request ID, see gohttp.EnsureRequestID.
+	TransactionID string `json:"transaction_id,omitempty"`
+	// Module is the policy module that made the decision, e.g. "rules",
+	// "ratelimit", or "auth".
+	Module string `json:"module"`
+	// RuleID identifies the rule within the module, if any.
+	RuleID string `json:"rule_id,omitempty"`
+	Action string `json:"action"`
+	// StatusCode is the status code a blocked request has been answered
+	// with.
+	StatusCode int    `json:"status_code,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Target     string `json:"target,omitempty"`
+}
+
+// Log writes decisions as NDJSON. It is safe for concurrent use.
+type Log struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	now     func() time.Time
+}
+
+// NewLog creates a new Log writing to w.
+func NewLog(w io.Writer) *Log {
+	return &Log{
+		encoder: json.NewEncoder(w),
+		now:     time.Now,
+	}
+}
+
+// Record writes a decision about a request. The time, the transaction ID,
+// the method, and the target are taken from the request unless they are
+// set in the decision already.
+func (l *Log) Record(r *http.Request, decision Decision) error {
+	if decision.Time.IsZero() {
+		decision.Time = l.now()
+	}
+
+	if r != nil {
+		if decision.TransactionID == "" {
+			decision.TransactionID = requestID(r)
+		}
+		if decision.Method == "" {
+			decision.Method = r.Method
+		}
+		if decision.Target == "" && r.URL != nil {
+			decision.Target = r.URL.RequestURI()
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.encoder.Encode(decision)
+}
+
+// RecordRules writes a decision for a request blocked by the rule engine.
+// Requests that haven't been blocked aren't recorded.
+func (l *Log) RecordRules(r *http.Request, result rules.Result) error {
+	if !result.Blocked {
+		return nil
+	}
+
+	return l.Record(r, Decision{
+		Module:     "rules",
+		RuleID:     result.BlockedBy,
+		Action:     ActionBlock,
+		StatusCode: result.StatusCode,
+	})
+}
+
+// RuleLogger returns a function to be passed to rules.Engine.OnLog, which
+// records matches of Log rules.
+func (l *Log) RuleLogger() func(ruleID string, request *http.Request) {
+	return func(ruleID string, request *http.Request) {
+		_ = l.Record(request, Decision{
+			Module: "rules",
+			RuleID: ruleID,
+			Action: ActionLog,
+		})
+	}
+}
+
+// requestID returns the ID of a request from its context or its header
+// fields, without generating a new one.
+func requestID(r *http.Request) string {
+	if id := gohttp.RequestIDFromContext(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(gohttp.RequestIDHeader)
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+	"github.com/dominikbraun/gohttp/rules"
+)
+
+func TestLog_Record(t *testing.T) {
+	var buf bytes.Buffer
+
+	log := NewLog(&buf)
+	log.now = func() time.Time {
+		return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+
+	request := httptest.NewRequest("POST", "/login?next=/", nil)
+	request.Header.Set(gohttp.RequestIDHeader, "abc")
+
+	if err := log.Record(request, Decision{Module: "ratelimit", Action: ActionBlock, StatusCode: 429}); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := `{"time":"2020-01-02T03:04:05Z","transaction_id":"abc","module":"ratelimit","action":"block","status_code":429,"method":"POST","target":"/login?next=/"}` + "\n"
+
+	if buf.String() != expected {
+		t.Errorf("expected line %s, got %s", expected, buf.String())
+	}
+}
+
+func TestLog_Rules(t *testing.T) {
+	engine, err := rules.Compile(
+		rules.Spec{ID: "flag-admin", Target: "^/admin", Action: rules.Log},
+		rules.Spec{ID: "block-delete", Methods: []string{"DELETE"}, Action: rules.Block},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+
+	log := NewLog(&buf)
+	engine.OnLog(log.RuleLogger())
+
+	request := httptest.NewRequest("DELETE", "/admin/users", nil)
+	request = request.WithContext(gohttp.ContextWithRequestID(request.Context(), "xyz"))
+
+	if err := log.RecordRules(request, engine.Evaluate(request, nil)); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected %d decisions, got %d", 2, len(lines))
+	}
+
+	expected := []Decision{
+		{TransactionID: "xyz", Module: "rules", RuleID: "flag-admin", Action: ActionLog},
+		{TransactionID: "xyz", Module: "rules", RuleID: "block-delete", Action: ActionBlock, StatusCode: 403},
+	}
+
+	for i, line := range lines {
+		var decision Decision
+		if err := json.Unmarshal([]byte(line), &decision); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		if decision.TransactionID != expected[i].TransactionID || decision.RuleID != expected[i].RuleID ||
+			decision.Action != expected[i].Action || decision.StatusCode != expected[i].StatusCode {
+			t.Errorf("expected decision %+v, got %+v", expected[i], decision)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Package auth provides Basic and Bearer authentication for parsed requests
+// (RFC 7617, RFC 6750). Credentials are checked by pluggable verifiers, and
+// failed attempts are answered with 401 responses carrying the appropriate
+// challenge, ready to be serialized.
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// BasicVerifier verifies a username and a password, like an htpasswd file.
+type BasicVerifier interface {
+	VerifyBasic(username, password string) bool
+}
+
+// BasicVerifierFunc is a function implementing BasicVerifier.
+type BasicVerifierFunc func(username, password string) bool
+
+// VerifyBasic calls f(username, password).
+func (f BasicVerifierFunc) VerifyBasic(username, password string) bool {
+	return f(username, password)
+}
+
+// BearerVerifier verifies a bearer token, e.g. a JWT or an opaque token.
+type BearerVerifier interface {
+	VerifyBearer(token string) bool
+}
+
+// BearerVerifierFunc is a function implementing BearerVerifier.
+type BearerVerifierFunc func(token string) bool
+
+// VerifyBearer calls f(token).
+func (f BearerVerifierFunc) VerifyBearer(token string) bool {
+	return f(token)
+}
+
+// Credentials is a BasicVerifier holding plaintext passwords by username.
+// Passwords are compared in constant time.
+type Credentials map[string]string
+
+// VerifyBasic reports whether the password matches the one of the user.
+func (c Credentials) VerifyBasic(username, password string) bool {
+	expected, ok := c[username]
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1
+}
+
+// Authenticator authenticates requests.
+type Authenticator interface {
+	// Authenticate returns true if the request is authenticated. Otherwise,
+	// it returns the 401 response the request should be answered with.
+	Authenticate(r *http.Request) (*http.Response, bool)
+}
+
+// ParseAuthorization splits the Authorization header field of a request into
+// the authentication scheme and the credentials (RFC 7235, section 2.1.).
+// The scheme is returned in lower case.
+func ParseAuthorization(r *http.Request) (string, string, bool) {
+	value := strings.TrimSpace(r.Header.Get("Authorization"))
+	if value == "" {
+		return "", "", false
+	}
+
+	tokens := strings.SplitN(value, " ", 2)
+	if len(tokens) != 2 {
+		return strings.ToLower(tokens[0]), "", true
+	}
+
+	return strings.ToLower(tokens[0]), strings.TrimSpace(tokens[1]), true
+}
+
+// Basic authenticates requests using the Basic scheme.
+type Basic struct {
+	Realm    string
+	Verifier BasicVerifier
+}
+
+// Authenticate implements Authenticator.
+func (b Basic) Authenticate(r *http.Request) (*http.Response, bool) {
+	scheme, credentials, ok := ParseAuthorization(r)
+
+	if ok && scheme == "basic" {
+		if username, password, ok := parseBasicCredentials(credentials); ok {
+			if b.Verifier.VerifyBasic(username, password) {
+				return nil, true
+			}
+		}
+	}
+
+	return unauthorized("Basic " + quotedParam("realm", b.Realm) + `, charset="UTF-8"`), false
+}
+
+// Bearer authenticates requests using the Bearer scheme.
+type Bearer struct {
+	Realm    string
+	Verifier BearerVerifier
+}
+
+// Authenticate implements Authenticator.
+func (b Bearer) Authenticate(r *http.Request) (*http.Response, bool) {
+	scheme, token, ok := ParseAuthorization(r)
+
+	// Requests without credentials get a challenge without error code
+	// (RFC 6750, section 3.1.).
+	if !ok || scheme != "bearer" {
+		return unauthorized("Bearer " + quotedParam("realm", b.Realm)), false
+	}
+
+	if token != "" && b.Verifier.VerifyBearer(token) {
+		return nil, true
+	}
+
+	return unauthorized("Bearer " + quotedParam("realm", b.Realm) + `, error="invalid_token"`), false
+}
+
+// Middleware returns an http.Handler passing authenticated requests to next
+// and answering all other requests with the 401 response.
+func Middleware(authenticator Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ok := authenticator.Authenticate(r)
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for name, values := range response.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(response.StatusCode)
+	})
+}
+
+func parseBasicCredentials(credentials string) (string, string, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return "", "", false
+	}
+
+	tokens := strings.SplitN(string(decoded), ":", 2)
+	if len(tokens) != 2 {
+		return "", "", false
+	}
+
+	return tokens[0], tokens[1], true
+}
+
+func unauthorized(challenge string) *http.Response {
+	response := gohttp.NewResponse(http.StatusUnauthorized, nil)
+	response.Header.Set("WWW-Authenticate", challenge)
+
+	return response
+}
+
+func quotedParam(name, value string) string {
+	return name + `="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasic_Authenticate(t *testing.T) {
+	basic := Basic{
+		Realm:    "admin",
+		Verifier: Credentials{"alice": "secret"},
+	}
+
+	testCases := map[string]struct {
+		authorization string
+		expected      bool
+	}{
+		"valid credentials": {
+			authorization: "Basic YWxpY2U6c2VjcmV0",
+			expected:      true,
+		},
+		"lower case scheme": {
+			authorization: "basic YWxpY2U6c2VjcmV0",
+			expected:      true,
+		},
+		"wrong password": {
+			authorization: "Basic YWxpY2U6d3Jvbmc=",
+		},
+		"invalid base64": {
+			authorization: "Basic !!!",
+		},
+		"other scheme": {
+			authorization: "Bearer YWxpY2U6c2VjcmV0",
+		},
+		"missing": {},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest("GET", "/", nil)
+		if tc.authorization != "" {
+			request.Header.Set("Authorization", tc.authorization)
+		}
+
+		response, ok := basic.Authenticate(request)
+
+		if ok != tc.expected {
+			t.Errorf("'%s': expected result %v, got %v", name, tc.expected, ok)
+		}
+
+		if ok {
+			continue
+		}
+
+		expected := `Basic realm="admin", charset="UTF-8"`
+		if actual := response.Header.Get("WWW-Authenticate"); actual != expected {
+			t.Errorf("'%s': expected challenge %s, got %s", name, expected, actual)
+		}
+	}
+}
+
+func TestBearer_Authenticate(t *testing.T) {
+	bearer := Bearer{
+		Realm: "api",
+		Verifier: BearerVerifierFunc(func(token string) bool {
+			return token == "valid"
+		}),
+	}
+
+	testCases := map[string]struct {
+		authorization     string
+		expected          bool
+		expectedChallenge string
+	}{
+		"valid token": {
+			authorization: "Bearer valid",
+			expected:      true,
+		},
+		"invalid token": {
+			authorization:     "Bearer invalid",
+			expectedChallenge: `Bearer realm="api", error="invalid_token"`,
+		},
+		"missing token": {
+			expectedChallenge: `Bearer realm="api"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest("GET", "/", nil)
+		if tc.authorization != "" {
+			request.Header.Set("Authorization", tc.authorization)
+		}
+
+		response, ok := bearer.Authenticate(request)
+
+		if ok != tc.expected {
+			t.Errorf("'%s': expected result %v, got %v", name, tc.expected, ok)
+		}
+
+		if ok {
+			continue
+		}
+
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Errorf("'%s': expected status code %d, got %d", name, http.StatusUnauthorized, response.StatusCode)
+		}
+
+		if actual := response.Header.Get("WWW-Authenticate"); actual != tc.expectedChallenge {
+			t.Errorf("'%s': expected challenge %s, got %s", name, tc.expectedChallenge, actual)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(Basic{Realm: "admin", Verifier: Credentials{"alice": "secret"}},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+
+	testCases := map[string]struct {
+		authorization string
+		expected      int
+	}{
+		"authenticated":     {authorization: "Basic YWxpY2U6c2VjcmV0", expected: http.StatusAccepted},
+		"not authenticated": {expected: http.StatusUnauthorized},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest("GET", "/", nil)
+		if tc.authorization != "" {
+			request.Header.Set("Authorization", tc.authorization)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != tc.expected {
+			t.Errorf("'%s': expected status code %d, got %d", name, tc.expected, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package blobstore defines a storage interface for large binary objects
+// like captured traffic or cached responses, along with an implementation
+// backed by the local filesystem. Implementations for object stores like S3
+// can be provided by other packages without adding dependencies here.
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned if no object exists for a key.
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey is returned for keys that are empty, absolute, or
+	// contain ".." segments.
+	ErrInvalidKey = errors.New("invalid key")
+)
+
+// Store stores objects by keys. Keys are slash-separated paths like
+// "captures/2020-06-01/0001.warc". Objects are streamed in both directions.
+type Store interface {
+	// Put stores the content of r under the given key, replacing an existing
+	// object. Readers of the key never observe a partially written object.
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Get returns a reader for the object with the given key, or ErrNotFound.
+	// The caller has to close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns all keys starting with the given prefix in sorted order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Delete removes the object with the given key. Deleting a non-existent
+	// object returns ErrNotFound.
+	Delete(ctx context.Context, key string) error
+}
+
+// FileStore is a Store keeping each object in a file below a root directory.
+type FileStore struct {
+	root string
+}
+
+// NewFileStore creates a FileStore storing objects below the given root
+// directory, which is created if necessary.
+func NewFileStore(root string) (*FileStore, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		root: root,
+	}, nil
+}
+
+// Put writes the object to a temporary file first and renames it afterwards,
+// so that the object is replaced atomically.
+func (f *FileStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, contextReader{ctx: ctx, r: r}); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the file of the object.
+func (f *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+// List walks the root directory. Temporary files of ongoing writes are
+// omitted.
+func (f *FileStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+
+	err := filepath.Walk(f.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(f.root, path)
+		if err != nil {
+			return err
+		}
+
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Delete removes the file of the object.
+func (f *FileStore) Delete(ctx context.Context, key string) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// path returns the file path for a key, making sure that it is located
+// below the root directory.
+func (f *FileStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", ErrInvalidKey
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.HasPrefix(segment, ".tmp-") {
+			return "", ErrInvalidKey
+		}
+	}
+
+	return filepath.Join(f.root, filepath.FromSlash(key)), nil
+}
+
+// contextReader stops reading once its context has been cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
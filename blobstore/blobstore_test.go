@@ -0,0 +1,80 @@
+package blobstore
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "blobstore")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	store, err := NewFileStore(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	ctx := context.Background()
+
+	objects := map[string]string{
+		"captures/b.warc": "second",
+		"captures/a.warc": "first",
+		"cache/entry":     "cached",
+	}
+
+	for key, content := range objects {
+		if err := store.Put(ctx, key, strings.NewReader(content)); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", key, err.Error())
+		}
+	}
+
+	if err := store.Put(ctx, "captures/a.warc", strings.NewReader("replaced")); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	reader, err := store.Get(ctx, "captures/a.warc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	content, _ := ioutil.ReadAll(reader)
+	_ = reader.Close()
+
+	if string(content) != "replaced" {
+		t.Errorf("expected content %s, got %s", "replaced", string(content))
+	}
+
+	keys, err := store.List(ctx, "captures/")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if expected := []string{"captures/a.warc", "captures/b.warc"}; !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected keys %v, got %v", expected, keys)
+	}
+
+	if err := store.Delete(ctx, "cache/entry"); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if _, err := store.Get(ctx, "cache/entry"); err != ErrNotFound {
+		t.Errorf("expected error %v, got %v", ErrNotFound, err)
+	}
+
+	if err := store.Delete(ctx, "cache/entry"); err != ErrNotFound {
+		t.Errorf("expected error %v, got %v", ErrNotFound, err)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "a//b", "a/./b"} {
+		if err := store.Put(ctx, key, strings.NewReader("")); err != ErrInvalidKey {
+			t.Errorf("'%s': expected error %v, got %v", key, ErrInvalidKey, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrWrongBodyLength is returned when reading a message body that ends
+	// before the announced length has been reached.
+	ErrWrongBodyLength = errors.New("wrong body length")
+	// ErrInvalidContentLength indicates a Content-Length value that isn't a
+	// sequence of digits, e.g. because it is empty or has a sign.
+	ErrInvalidContentLength = errors.New("invalid content length")
+	// ErrContentLengthOverflow indicates a Content-Length value that doesn't
+	// fit into an int64.
+	ErrContentLengthOverflow = errors.New("content length overflows int64")
+	// ErrConflictingContentLength indicates multiple Content-Length values
+	// that differ from each other.
+	ErrConflictingContentLength = errors.New("conflicting content lengths")
+)
+
+// ContentLengthError is returned by the parser for a malformed Content-Length
+// header field. It wraps one of the ErrInvalidContentLength,
+// ErrContentLengthOverflow, and ErrConflictingContentLength errors.
+type ContentLengthError struct {
+	// Value is the offending field value.
+	Value string
+	// Err is the reason why the value has been rejected.
+	Err error
+}
+
+func (c *ContentLengthError) Error() string {
+	return fmt.Sprintf("%s: %q", c.Err.Error(), c.Value)
+}
+
+func (c *ContentLengthError) Unwrap() error {
+	return c.Err
+}
+
+// StatusCode returns the status code for responding to a request with a
+// malformed Content-Length, which is 400 (RFC 7230, section 3.3.3.).
+func (c *ContentLengthError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// bodyReader streams a body of a known length from a source. It never reads
+// beyond the body, so that the next message can be parsed from the source.
+type bodyReader struct {
+	source    Source
+	remaining int64
+}
+
+// newBodyReader returns a body reader for the given length, or http.NoBody
+// if the message doesn't have a body.
+func newBodyReader(source Source, length int64) io.ReadCloser {
+	if length <= 0 {
+		return http.NoBody
+	}
+
+	return &bodyReader{
+		source:    source,
+		remaining: length,
+	}
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err := b.source.Read(p)
+	b.remaining -= int64(n)
+
+	if errors.Is(err, io.EOF) {
+		if b.remaining > 0 {
+			return n, ErrWrongBodyLength
+		}
+		return n, nil
+	}
+
+	return n, err
+}
+
+// Close doesn't close the underlying source, which may hold further
+// messages.
+func (b *bodyReader) Close() error {
+	return nil
+}
+
+// contentLength returns the value for the ContentLength field of a message
+// with the given body length: -1 if the length is unknown or determined by
+// transfer codings, and the body length otherwise.
+func contentLength(headers http.Header, length int64) int64 {
+	if hasTransferCodings(headers) || length < 0 {
+		return -1
+	}
+	return length
+}
+
+// parseContentLength parses the values of all Content-Length header fields.
+// Each value may be a comma-separated list as produced by proxies combining
+// duplicate fields. Such duplicates are accepted if they are equal (RFC 7230,
+// section 3.3.2.).
+func parseContentLength(values []string) (int64, error) {
+	length := int64(-1)
+
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			element = strings.TrimSpace(element)
+
+			parsed, err := parseDigits(element)
+			if err != nil {
+				return 0, &ContentLengthError{Value: value, Err: err}
+			}
+
+			if length >= 0 && parsed != length {
+				return 0, &ContentLengthError{Value: value, Err: ErrConflictingContentLength}
+			}
+			length = parsed
+		}
+	}
+
+	return length, nil
+}
+
+// parseDigits parses a non-negative decimal integer consisting of digits
+// only, unlike strconv.ParseInt which also accepts a sign.
+func parseDigits(s string) (int64, error) {
+	if s == "" {
+		return 0, ErrInvalidContentLength
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, ErrInvalidContentLength
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, ErrContentLengthOverflow
+	}
+
+	return n, nil
+}
@@ -0,0 +1,167 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// zeroReader produces n zero bytes and counts the bytes read.
+type zeroReader struct {
+	n    int64
+	read int64
+}
+
+func (z *zeroReader) Read(p []byte) (int, error) {
+	if z.read >= z.n {
+		return 0, io.EOF
+	}
+
+	if remaining := z.n - z.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	for i := range p {
+		p[i] = 0
+	}
+	z.read += int64(len(p))
+
+	return len(p), nil
+}
+
+func TestParseRequest_LargeBody(t *testing.T) {
+	if testing.Short() {
+		t.Skip("streaming a 5 GiB body is slow, especially with the race detector")
+	}
+
+	const length = 5 << 30
+
+	body := &zeroReader{n: length}
+	header := fmt.Sprintf("POST /upload HTTP/1.1\r\nContent-Length: %d\r\n\r\n", int64(length))
+	reader := bufio.NewReader(io.MultiReader(strings.NewReader(header), body))
+
+	request, err := ParseRequest(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if request.ContentLength != length {
+		t.Errorf("expected content length %d, got %d", int64(length), request.ContentLength)
+	}
+
+	if body.read > int64(reader.Size()) {
+		t.Errorf("expected body to be streamed, but %d bytes have been read upfront", body.read)
+	}
+
+	n, err := io.Copy(ioutil.Discard, request.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if n != length {
+		t.Errorf("expected %d body bytes, got %d", int64(length), n)
+	}
+}
+
+func TestParseRequest_BodyLength(t *testing.T) {
+	testCases := map[string]struct {
+		source        string
+		expectedBody  string
+		expectedError error
+	}{
+		"exact body": {
+			source:       "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello",
+			expectedBody: "hello",
+		},
+		"next message": {
+			source:       "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhelloGET / HTTP/1.1\r\n\r\n",
+			expectedBody: "hello",
+		},
+		"truncated body": {
+			source:        "POST / HTTP/1.1\r\nContent-Length: 10\r\n\r\nhello",
+			expectedBody:  "hello",
+			expectedError: ErrWrongBodyLength,
+		},
+	}
+
+	for name, tc := range testCases {
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(tc.source)))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(request.Body)
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if string(body) != tc.expectedBody {
+			t.Errorf("'%s': expected body %s, got %s", name, tc.expectedBody, string(body))
+		}
+	}
+}
+
+func TestParseContentLength(t *testing.T) {
+	testCases := map[string]struct {
+		values        []string
+		expected      int64
+		expectedError error
+	}{
+		"single value": {
+			values:   []string{"1024"},
+			expected: 1024,
+		},
+		"equal duplicates": {
+			values:   []string{"42, 42", "42"},
+			expected: 42,
+		},
+		"differing duplicates": {
+			values:        []string{"42, 43"},
+			expectedError: ErrConflictingContentLength,
+		},
+		"negative": {
+			values:        []string{"-1"},
+			expectedError: ErrInvalidContentLength,
+		},
+		"plus sign": {
+			values:        []string{"+5"},
+			expectedError: ErrInvalidContentLength,
+		},
+		"empty": {
+			values:        []string{""},
+			expectedError: ErrInvalidContentLength,
+		},
+		"hex": {
+			values:        []string{"0x10"},
+			expectedError: ErrInvalidContentLength,
+		},
+		"overflow": {
+			values:        []string{"9223372036854775808"},
+			expectedError: ErrContentLengthOverflow,
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, err := parseContentLength(tc.values)
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+			continue
+		}
+
+		if err != nil {
+			var lengthError *ContentLengthError
+			if !errors.As(err, &lengthError) || lengthError.StatusCode() != 400 {
+				t.Errorf("'%s': expected a ContentLengthError mapping to 400, got %v", name, err)
+			}
+			continue
+		}
+
+		if actual != tc.expected {
+			t.Errorf("'%s': expected content length %d, got %d", name, tc.expected, actual)
+		}
+	}
+}
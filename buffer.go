@@ -0,0 +1,232 @@
+package gohttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// ErrBufferReleased is returned when reading a BufferedBody whose buffer has
+// already been released.
+var ErrBufferReleased = errors.New("body buffer has been released")
+
+type bufferPolicy struct {
+	enabled     bool
+	memoryLimit int64
+	maxSize     int64
+}
+
+// WithBodyBuffering reads bodies completely during parsing, so that they can
+// be read again, e.g. for retries or inspection. The body of a parsed message
+// is a *BufferedBody then.
+//
+// Bodies of up to memoryLimit bytes are kept in memory, and larger bodies are
+// spilled to a temporary file. Bodies exceeding maxSize bytes are rejected
+// with ErrBodyTooLarge.
+func WithBodyBuffering(memoryLimit, maxSize int64) Option {
+	return func(c *config) {
+		c.bufferPolicy = bufferPolicy{
+			enabled:     true,
+			memoryLimit: memoryLimit,
+			maxSize:     maxSize,
+		}
+	}
+}
+
+// BufferedBody is a body that has been read completely into memory or into a
+// temporary file. Closing it doesn't discard the buffer, so that the content
+// can be read again using Reopen.
+//
+// A temporary file is deleted once the buffer is released, either explicitly
+// via Release or when it is garbage collected.
+type BufferedBody struct {
+	reader *io.SectionReader
+	buffer *bodyBuffer
+}
+
+// Read reads from the buffered content.
+func (b *BufferedBody) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+// Close implements io.Closer. The buffer remains available.
+func (b *BufferedBody) Close() error {
+	return nil
+}
+
+// Len returns the length of the buffered content.
+func (b *BufferedBody) Len() int64 {
+	return b.reader.Size()
+}
+
+// InMemory reports whether the content is buffered in memory rather than in
+// a temporary file.
+func (b *BufferedBody) InMemory() bool {
+	return b.buffer.file == nil
+}
+
+// Reopen returns a new BufferedBody sharing the buffer, which reads the
+// content from the start.
+func (b *BufferedBody) Reopen() *BufferedBody {
+	return newBufferedBody(b.buffer, b.reader.Size())
+}
+
+// Release discards the buffer and deletes a temporary file. Subsequent reads
+// from all bodies sharing the buffer return ErrBufferReleased.
+func (b *BufferedBody) Release() error {
+	return b.buffer.release()
+}
+
+// getBody returns a GetBody function for a request with the given body,
+// which is either a BufferedBody or http.NoBody.
+func getBody(body io.ReadCloser) func() (io.ReadCloser, error) {
+	buffered, ok := body.(*BufferedBody)
+	if !ok {
+		return func() (io.ReadCloser, error) {
+			return http.NoBody, nil
+		}
+	}
+
+	return func() (io.ReadCloser, error) {
+		if buffered.buffer.isReleased() {
+			return nil, ErrBufferReleased
+		}
+		return buffered.Reopen(), nil
+	}
+}
+
+func newBufferedBody(buffer *bodyBuffer, size int64) *BufferedBody {
+	return &BufferedBody{
+		reader: io.NewSectionReader(buffer, 0, size),
+		buffer: buffer,
+	}
+}
+
+// bodyBuffer holds the buffered content either in memory or in a file.
+type bodyBuffer struct {
+	content  io.ReaderAt
+	file     *os.File
+	removed  bool
+	mutex    sync.Mutex
+	released bool
+}
+
+func (b *bodyBuffer) ReadAt(p []byte, off int64) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.released {
+		return 0, ErrBufferReleased
+	}
+
+	return b.content.ReadAt(p, off)
+}
+
+func (b *bodyBuffer) isReleased() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.released
+}
+
+func (b *bodyBuffer) release() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.released {
+		return nil
+	}
+
+	b.released = true
+	b.content = nil
+
+	if b.file == nil {
+		return nil
+	}
+
+	err := b.file.Close()
+
+	if !b.removed {
+		if removeErr := os.Remove(b.file.Name()); err == nil {
+			err = removeErr
+		}
+	}
+
+	return err
+}
+
+// bufferBodyContent reads a body according to the buffer policy and returns
+// a BufferedBody for it.
+func bufferBodyContent(body io.ReadCloser, config config) (io.ReadCloser, error) {
+	policy := config.bufferPolicy
+
+	if !policy.enabled || body == http.NoBody {
+		return body, nil
+	}
+
+	limit := policy.memoryLimit
+	if limit > policy.maxSize {
+		limit = policy.maxSize
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(content)) <= limit {
+		buffer := &bodyBuffer{content: bytes.NewReader(content)}
+		return newBufferedBody(buffer, int64(len(content))), nil
+	}
+
+	if int64(len(content)) > policy.maxSize {
+		return nil, ErrBodyTooLarge
+	}
+
+	return spillBody(content, body, policy.maxSize)
+}
+
+// spillBody writes the content read so far and the rest of the body to a
+// temporary file.
+func spillBody(content []byte, body io.Reader, maxSize int64) (io.ReadCloser, error) {
+	file, err := ioutil.TempFile("", "gohttp-body-")
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the file right away where the operating system permits it, so
+	// that it disappears once it is closed even if the process crashes.
+	buffer := &bodyBuffer{
+		content: file,
+		file:    file,
+		removed: os.Remove(file.Name()) == nil,
+	}
+	runtime.SetFinalizer(buffer, func(b *bodyBuffer) {
+		_ = b.release()
+	})
+
+	written, err := file.Write(content)
+	if err != nil {
+		_ = buffer.release()
+		return nil, err
+	}
+
+	n, err := io.Copy(file, io.LimitReader(body, maxSize-int64(written)+1))
+	if err != nil {
+		_ = buffer.release()
+		return nil, err
+	}
+
+	size := int64(written) + n
+	if size > maxSize {
+		_ = buffer.release()
+		return nil, ErrBodyTooLarge
+	}
+
+	return newBufferedBody(buffer, size), nil
+}
@@ -0,0 +1,111 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWithBodyBuffering(t *testing.T) {
+	testCases := map[string]struct {
+		body             string
+		expectedInMemory bool
+		expectedError    error
+	}{
+		"in memory": {
+			body:             "hello",
+			expectedInMemory: true,
+		},
+		"spilled to disk": {
+			body:             strings.Repeat("a", 32),
+			expectedInMemory: false,
+		},
+		"too large": {
+			body:          strings.Repeat("a", 65),
+			expectedError: ErrBodyTooLarge,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := fmt.Sprintf("POST / HTTP/1.1\r\nContent-Length: %d\r\n\r\n%s", len(tc.body), tc.body)
+
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), WithBodyBuffering(16, 64))
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		body, ok := request.Body.(*BufferedBody)
+		if !ok {
+			t.Fatalf("'%s': expected a buffered body, got %T", name, request.Body)
+		}
+
+		if body.InMemory() != tc.expectedInMemory {
+			t.Errorf("'%s': expected in memory %v, got %v", name, tc.expectedInMemory, body.InMemory())
+		}
+
+		for i := 0; i < 2; i++ {
+			content, err := ioutil.ReadAll(body)
+			if err != nil {
+				t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+			}
+
+			if string(content) != tc.body {
+				t.Errorf("'%s': expected body %s, got %s", name, tc.body, string(content))
+			}
+
+			_ = body.Close()
+			body = body.Reopen()
+		}
+
+		if err := body.Release(); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if _, err := ioutil.ReadAll(body.Reopen()); !errors.Is(err, ErrBufferReleased) {
+			t.Errorf("'%s': expected error %v, got %v", name, ErrBufferReleased, err)
+		}
+	}
+}
+
+func TestWithBodyBuffering_GetBody(t *testing.T) {
+	source := "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello"
+
+	request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), WithBodyBuffering(16, 64))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if request.GetBody == nil {
+		t.Fatalf("expected GetBody to be set")
+	}
+
+	_, _ = ioutil.ReadAll(request.Body)
+	_ = request.Body.Close()
+
+	body, err := request.GetBody()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if content, _ := ioutil.ReadAll(body); string(content) != "hello" {
+		t.Errorf("expected body %s, got %s", "hello", string(content))
+	}
+
+	_ = request.Body.(*BufferedBody).Release()
+
+	if _, err := request.GetBody(); !errors.Is(err, ErrBufferReleased) {
+		t.Errorf("expected error %v, got %v", ErrBufferReleased, err)
+	}
+
+	unbuffered, _ := ParseRequest(bufio.NewReader(strings.NewReader(source)))
+	if unbuffered.GetBody != nil {
+		t.Errorf("expected GetBody not to be set without buffering")
+	}
+}
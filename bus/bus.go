@@ -0,0 +1,257 @@
+// Package bus ships parsed transactions to a message bus and re-materializes
+// them on the consuming side. Messages are encoded with the codec package,
+// so that downstream consumers don't need to parse raw HTTP again.
+//
+// Adapters for a concrete broker such as Kafka or NATS implement Sink and
+// Source on top of their client library. This package provides in-memory
+// and stream-based reference adapters.
+package bus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/dominikbraun/gohttp/codec"
+)
+
+// defaultMaxFrameSize is the maximum payload size of a StreamSource unless
+// specified otherwise.
+const defaultMaxFrameSize = 64 << 20
+
+// ErrClosed is returned when sending to a closed Channel.
+var ErrClosed = errors.New("bus is closed")
+
+// ErrInvalidPayload is returned for payloads that don't hold an encoded
+// transaction.
+var ErrInvalidPayload = errors.New("invalid transaction payload")
+
+// Sink publishes encoded transactions.
+type Sink interface {
+	Send(ctx context.Context, payload []byte) error
+}
+
+// Source receives encoded transactions. Receive returns io.EOF if there are
+// no more transactions.
+type Source interface {
+	Receive(ctx context.Context) ([]byte, error)
+}
+
+// Send encodes a request and its response and publishes them to the sink.
+// The response may be nil. The bodies of both messages remain readable.
+func Send(ctx context.Context, sink Sink, request *http.Request, response *http.Response) error {
+	encodedRequest, err := codec.MarshalRequest(request)
+	if err != nil {
+		return err
+	}
+
+	var encodedResponse []byte
+	if response != nil {
+		if encodedResponse, err = codec.MarshalResponse(response); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	writeFrame(&buf, encodedRequest)
+	buf.Write(encodedResponse)
+
+	return sink.Send(ctx, buf.Bytes())
+}
+
+// Receive receives a transaction from the source and decodes it. The
+// response is nil if none has been sent.
+func Receive(ctx context.Context, source Source) (*http.Request, *http.Response, error) {
+	payload, err := source.Receive(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader := bytes.NewReader(payload)
+
+	encodedRequest, err := readFrame(reader, uint64(reader.Len()))
+	if err != nil {
+		return nil, nil, ErrInvalidPayload
+	}
+
+	request, err := codec.UnmarshalRequest(encodedRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if reader.Len() == 0 {
+		return request, nil, nil
+	}
+
+	response, err := codec.UnmarshalResponse(payload[len(payload)-reader.Len():])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return request, response, nil
+}
+
+// Channel is an in-memory Sink and Source, e.g. for connecting pipeline
+// stages within a process or for testing.
+type Channel struct {
+	payloads chan []byte
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewChannel creates a new Channel buffering the given number of payloads.
+func NewChannel(size int) *Channel {
+	return &Channel{
+		payloads: make(chan []byte, size),
+		done:     make(chan struct{}),
+	}
+}
+
+// Send implements Sink. It blocks while the buffer is full.
+func (c *Channel) Send(ctx context.Context, payload []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case c.payloads <- payload:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receive implements Source. Once the channel is closed, the buffered
+// payloads are returned, followed by io.EOF.
+func (c *Channel) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case payload := <-c.payloads:
+		return payload, nil
+	case <-c.done:
+		select {
+		case payload := <-c.payloads:
+			return payload, nil
+		default:
+			return nil, io.EOF
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the channel for sending.
+func (c *Channel) Close() error {
+	c.once.Do(func() {
+		close(c.done)
+	})
+	return nil
+}
+
+// StreamSink is a Sink writing length-prefixed payloads to a stream, e.g. a
+// file or a pipe into a broker client.
+type StreamSink struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+// NewStreamSink creates a new StreamSink writing to w.
+func NewStreamSink(w io.Writer) *StreamSink {
+	return &StreamSink{
+		w: w,
+	}
+}
+
+// Send implements Sink.
+func (s *StreamSink) Send(_ context.Context, payload []byte) error {
+	var buf bytes.Buffer
+	writeFrame(&buf, payload)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// StreamSource is a Source reading payloads written by a StreamSink.
+type StreamSource struct {
+	// MaxFrameSize is the maximum size of a payload in bytes. Larger
+	// payloads are rejected with ErrInvalidPayload before allocating them.
+	// If zero, a maximum of 64 MiB is used.
+	MaxFrameSize int64
+
+	mutex sync.Mutex
+	r     *bufio.Reader
+}
+
+// NewStreamSource creates a new StreamSource reading from r.
+func NewStreamSource(r io.Reader) *StreamSource {
+	return &StreamSource{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Receive implements Source. The context is not observed while reading.
+func (s *StreamSource) Receive(_ context.Context) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	max := s.MaxFrameSize
+	if max <= 0 {
+		max = defaultMaxFrameSize
+	}
+
+	payload, err := readFrame(s.r, uint64(max))
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, ErrInvalidPayload
+	}
+
+	return payload, err
+}
+
+func writeFrame(buf *bytes.Buffer, data []byte) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], uint64(len(data)))])
+	buf.Write(data)
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readFrame reads a length-prefixed frame of at most max bytes. It returns
+// io.EOF if there is no data at all, io.ErrUnexpectedEOF for truncated
+// frames, and ErrInvalidPayload for invalid or too large lengths.
+func readFrame(r byteReader, max uint64) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		return nil, ErrInvalidPayload
+	}
+
+	if n > max {
+		return nil, ErrInvalidPayload
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return data, nil
+}
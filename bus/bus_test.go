@@ -0,0 +1,126 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+type sinkSource interface {
+	Sink
+	Source
+}
+
+func TestSendReceive(t *testing.T) {
+	var buf bytes.Buffer
+	stream := struct {
+		*StreamSink
+		*StreamSource
+	}{NewStreamSink(&buf), NewStreamSource(&buf)}
+
+	testCases := map[string]sinkSource{
+		"channel": NewChannel(2),
+		"stream":  stream,
+	}
+
+	for name, bus := range testCases {
+		ctx := context.Background()
+
+		request := httptest.NewRequest("POST", "/orders", strings.NewReader("order"))
+		response := gohttp.NewResponse(201, []byte("created"))
+
+		if err := Send(ctx, bus, request, response); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+		if err := Send(ctx, bus, httptest.NewRequest("GET", "/", nil), nil); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		receivedRequest, receivedResponse, err := Receive(ctx, bus)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if body, _ := ioutil.ReadAll(receivedRequest.Body); receivedRequest.URL.Path != "/orders" || string(body) != "order" {
+			t.Errorf("'%s': unexpected request %s %s", name, receivedRequest.URL.Path, string(body))
+		}
+
+		if receivedResponse == nil || receivedResponse.StatusCode != 201 {
+			t.Errorf("'%s': expected a 201 response, got %v", name, receivedResponse)
+		}
+
+		if _, receivedResponse, err = Receive(ctx, bus); err != nil || receivedResponse != nil {
+			t.Errorf("'%s': expected a transaction without response, got %v (%v)", name, receivedResponse, err)
+		}
+
+		if channel, ok := bus.(*Channel); ok {
+			_ = channel.Close()
+		}
+
+		if _, _, err := Receive(ctx, bus); !errors.Is(err, io.EOF) {
+			t.Errorf("'%s': expected error %v, got %v", name, io.EOF, err)
+		}
+	}
+}
+
+func TestChannel_Closed(t *testing.T) {
+	channel := NewChannel(1)
+	_ = channel.Close()
+
+	if err := channel.Send(context.Background(), []byte("x")); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected error %v, got %v", ErrClosed, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := NewChannel(0).Receive(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestStreamSource_Invalid(t *testing.T) {
+	testCases := map[string]struct {
+		data         []byte
+		maxFrameSize int64
+	}{
+		"truncated": {
+			data: []byte{5, 'a', 'b'},
+		},
+		"huge length": {
+			data: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		},
+		"overflowing length": {
+			data: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		},
+		"exceeded maximum": {
+			data:         []byte{5, 'a', 'b', 'c', 'd', 'e'},
+			maxFrameSize: 4,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := NewStreamSource(bytes.NewReader(tc.data))
+		source.MaxFrameSize = tc.maxFrameSize
+
+		if _, err := source.Receive(context.Background()); !errors.Is(err, ErrInvalidPayload) {
+			t.Errorf("'%s': expected error %v, got %v", name, ErrInvalidPayload, err)
+		}
+	}
+}
+
+func TestReceive_InvalidLength(t *testing.T) {
+	channel := NewChannel(1)
+	_ = channel.Send(context.Background(), []byte{0xff, 0xff, 0xff, 0xff, 0x0f, 'a'})
+
+	if _, _, err := Receive(context.Background(), channel); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("expected error %v, got %v", ErrInvalidPayload, err)
+	}
+}
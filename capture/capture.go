@@ -0,0 +1,188 @@
+// Package capture decides which transactions are worth persisting, so that
+// always-on capture via the recorder or WARC writer stays affordable. It
+// provides composable filters and a sampler with per-host and per-status
+// rates.
+package capture
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// Filter decides whether a transaction should be captured. The response may
+// be nil if the transaction doesn't have a response.
+type Filter func(request *http.Request, response *http.Response) bool
+
+// Method matches requests with one of the given methods.
+func Method(methods ...string) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		for _, method := range methods {
+			if request.Method == method {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// PathMatches matches requests whose path matches the regular expression.
+func PathMatches(pattern *regexp.Regexp) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		return pattern.MatchString(request.URL.Path)
+	}
+}
+
+// HeaderEquals matches requests with a header field of the given value.
+// Values are compared case-insensitively.
+func HeaderEquals(name, value string) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		for _, v := range request.Header.Values(name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Host matches requests for one of the given hosts, ignoring the port.
+func Host(hosts ...string) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		host := hostname(request)
+		for _, h := range hosts {
+			if strings.EqualFold(host, h) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// StatusRange matches responses with a status code between min and max,
+// including both.
+func StatusRange(min, max int) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		return response != nil && response.StatusCode >= min && response.StatusCode <= max
+	}
+}
+
+// All matches if all of the given filters match.
+func All(filters ...Filter) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		for _, filter := range filters {
+			if !filter(request, response) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any matches if at least one of the given filters matches.
+func Any(filters ...Filter) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		for _, filter := range filters {
+			if filter(request, response) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches if the given filter doesn't match.
+func Not(filter Filter) Filter {
+	return func(request *http.Request, response *http.Response) bool {
+		return !filter(request, response)
+	}
+}
+
+// Sampler captures a random share of transactions. The rate for a specific
+// status code takes precedence over the rate for a specific host, which in
+// turn takes precedence over the default rate. It is safe for concurrent use.
+type Sampler struct {
+	mutex       sync.Mutex
+	rate        float64
+	hostRates   map[string]float64
+	statusRates map[int]float64
+	random      func() float64
+}
+
+// NewSampler creates a Sampler capturing the given share of transactions,
+// where 1 captures all transactions and 0 captures none.
+func NewSampler(rate float64) *Sampler {
+	source := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	return &Sampler{
+		rate:        rate,
+		hostRates:   make(map[string]float64),
+		statusRates: make(map[int]float64),
+		random:      source.Float64,
+	}
+}
+
+// SetHostRate sets the rate for requests to the given host.
+func (s *Sampler) SetHostRate(host string, rate float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.hostRates[strings.ToLower(host)] = rate
+}
+
+// SetStatusRate sets the rate for responses with the given status code, e.g.
+// 1 for capturing all server errors.
+func (s *Sampler) SetStatusRate(statusCode int, rate float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.statusRates[statusCode] = rate
+}
+
+// Sample decides randomly whether the transaction is captured. Its
+// signature matches Filter, so it can be combined with other filters.
+func (s *Sampler) Sample(request *http.Request, response *http.Response) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rate := s.rate
+
+	if hostRate, ok := s.hostRates[strings.ToLower(hostname(request))]; ok {
+		rate = hostRate
+	}
+
+	if response != nil {
+		if statusRate, ok := s.statusRates[response.StatusCode]; ok {
+			rate = statusRate
+		}
+	}
+
+	return s.random() < rate
+}
+
+// Transaction applies a filter to a serialized transaction. Transactions
+// that can't be parsed are never captured.
+func Transaction(filter Filter) func(transaction gohttp.Transaction) bool {
+	return func(transaction gohttp.Transaction) bool {
+		request, response, err := transaction.Parse()
+		if err != nil {
+			return false
+		}
+
+		return filter(request, response)
+	}
+}
+
+func hostname(request *http.Request) string {
+	host := gohttp.RequestHost(request)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.Trim(host, "[]")
+}
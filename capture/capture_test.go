@@ -0,0 +1,122 @@
+package capture
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestFilter(t *testing.T) {
+	filter := All(
+		Method(http.MethodPost, http.MethodPut),
+		PathMatches(regexp.MustCompile(`^/api/`)),
+		Not(HeaderEquals("X-Health-Check", "true")),
+		Any(Host("example.com"), StatusRange(500, 599)),
+	)
+
+	testCases := map[string]struct {
+		method     string
+		target     string
+		header     http.Header
+		statusCode int
+		expected   bool
+	}{
+		"matching request": {
+			method:     http.MethodPost,
+			target:     "http://example.com:8080/api/users",
+			statusCode: 200,
+			expected:   true,
+		},
+		"other host with server error": {
+			method:     http.MethodPut,
+			target:     "http://other.org/api/users",
+			statusCode: 502,
+			expected:   true,
+		},
+		"other host": {
+			method:     http.MethodPost,
+			target:     "http://other.org/api/users",
+			statusCode: 200,
+		},
+		"wrong method": {
+			method:     http.MethodGet,
+			target:     "http://example.com/api/users",
+			statusCode: 200,
+		},
+		"health check": {
+			method:     http.MethodPost,
+			target:     "http://example.com/api/health",
+			header:     http.Header{"X-Health-Check": {"TRUE"}},
+			statusCode: 200,
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest(tc.method, tc.target, nil)
+		for key, values := range tc.header {
+			request.Header[key] = values
+		}
+
+		response := gohttp.NewResponse(tc.statusCode, nil)
+
+		if actual := filter(request, response); actual != tc.expected {
+			t.Errorf("'%s': expected %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestSampler(t *testing.T) {
+	sampler := NewSampler(0.1)
+	sampler.SetHostRate("example.com", 0.5)
+	sampler.SetStatusRate(500, 1)
+	sampler.random = func() float64 { return 0.3 }
+
+	testCases := map[string]struct {
+		target     string
+		statusCode int
+		expected   bool
+	}{
+		"default rate": {
+			target:     "http://other.org/",
+			statusCode: 200,
+		},
+		"host rate": {
+			target:     "http://example.com/",
+			statusCode: 200,
+			expected:   true,
+		},
+		"status rate": {
+			target:     "http://other.org/",
+			statusCode: 500,
+			expected:   true,
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest(http.MethodGet, tc.target, nil)
+
+		if actual := sampler.Sample(request, gohttp.NewResponse(tc.statusCode, nil)); actual != tc.expected {
+			t.Errorf("'%s': expected %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	filter := Transaction(StatusRange(400, 599))
+
+	transaction := gohttp.Transaction{
+		Request:  []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		Response: []byte("HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n"),
+	}
+
+	if !filter(transaction) {
+		t.Errorf("expected transaction to be captured")
+	}
+
+	if filter(gohttp.Transaction{Request: []byte("invalid")}) {
+		t.Errorf("expected invalid transaction not to be captured")
+	}
+}
@@ -0,0 +1,350 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMalformedChunk indicates a chunk that violates the chunked transfer
+	// coding (RFC 7230, section 4.1.).
+	ErrMalformedChunk = errors.New("malformed chunk")
+	// ErrChunkExtensionRejected is returned for chunk extensions if the
+	// policy ChunkExtensionsReject is set.
+	ErrChunkExtensionRejected = errors.New("chunk extensions are not allowed")
+	// ErrChunkExtensionTooLong is returned if the extensions of a chunk
+	// exceed the length set with WithMaxChunkExtensionLength.
+	ErrChunkExtensionTooLong = errors.New("chunk extensions too long")
+)
+
+// ChunkExtension is a name-value pair following the chunk size (RFC 7230,
+// section 4.1.1.). The value is empty if the extension has no value.
+type ChunkExtension struct {
+	Name  string
+	Value string
+}
+
+// ChunkExtensionPolicy determines how the parser treats chunk extensions.
+type ChunkExtensionPolicy int
+
+const (
+	// ChunkExtensionsAccept parses chunk extensions and passes them to the
+	// handler set with WithChunkExtensionHandler, if any.
+	ChunkExtensionsAccept ChunkExtensionPolicy = iota
+	// ChunkExtensionsStrip discards chunk extensions without parsing them.
+	ChunkExtensionsStrip
+	// ChunkExtensionsReject fails the body with ErrChunkExtensionRejected if
+	// a chunk has extensions.
+	ChunkExtensionsReject
+)
+
+// WithChunkExtensionPolicy sets the policy for chunk extensions. Since
+// chunk extensions are rarely used legitimately but are a known vector for
+// request smuggling, middleboxes should strip or reject them.
+func WithChunkExtensionPolicy(policy ChunkExtensionPolicy) Option {
+	return func(c *config) {
+		c.chunkExtensionPolicy = policy
+	}
+}
+
+// WithMaxChunkExtensionLength limits the length of the extensions of a
+// single chunk to n bytes. A value of 0 disables the limit.
+func WithMaxChunkExtensionLength(n int) Option {
+	return func(c *config) {
+		c.maxChunkExtensionLength = n
+	}
+}
+
+// WithChunkExtensionHandler sets a function that is called with the
+// extensions of each chunk that has extensions. Returning an error fails
+// reading the body with that error.
+func WithChunkExtensionHandler(handler func(extensions []ChunkExtension) error) Option {
+	return func(c *config) {
+		c.chunkExtensionHandler = handler
+	}
+}
+
+// chunkedReader decodes a body using the chunked transfer coding. Trailer
+// fields are added to the trailer header once the final chunk has been read.
+type chunkedReader struct {
+	source    Source
+	config    config
+	trailer   http.Header
+	remaining int64
+	err       error
+}
+
+func newChunkedReader(source Source, config config, trailer http.Header) *chunkedReader {
+	return &chunkedReader{
+		source:  source,
+		config:  config,
+		trailer: trailer,
+	}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	if c.remaining == 0 {
+		if c.err = c.nextChunk(); c.err != nil {
+			return 0, c.err
+		}
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.source.Read(p)
+	c.remaining -= int64(n)
+
+	if c.remaining == 0 && err == nil {
+		err = c.readChunkEnd()
+	}
+
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	c.err = err
+
+	return n, err
+}
+
+// Close doesn't close the underlying source, which may hold further
+// messages.
+func (c *chunkedReader) Close() error {
+	return nil
+}
+
+// nextChunk reads the next chunk-size line. For the last chunk, the trailer
+// section is read and io.EOF is returned.
+func (c *chunkedReader) nextChunk() error {
+	line, err := readLine(c.source, c.config)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	size, extensions, err := parseChunkSizeLine(line, c.config)
+	if err != nil {
+		return err
+	}
+
+	if len(extensions) > 0 && c.config.chunkExtensionHandler != nil {
+		if err := c.config.chunkExtensionHandler(extensions); err != nil {
+			return err
+		}
+	}
+
+	if size == 0 {
+		if err := c.readTrailer(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	c.remaining = size
+
+	return nil
+}
+
+// readChunkEnd reads the line break terminating the chunk data.
+func (c *chunkedReader) readChunkEnd() error {
+	line, err := readLine(c.source, c.config)
+	if err != nil {
+		return err
+	}
+
+	if !isNewLine(line, c.config) {
+		return ErrMalformedChunk
+	}
+
+	return nil
+}
+
+func (c *chunkedReader) readTrailer() error {
+	for {
+		line, err := readLine(c.source, c.config)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+
+		if isNewLine(line, c.config) {
+			return nil
+		}
+
+		fieldName, fieldValue, err := parseHeaderField(line)
+		if err != nil {
+			return err
+		}
+
+		if c.trailer != nil {
+			c.trailer.Add(fieldName, fieldValue)
+		}
+	}
+}
+
+// parseChunkSizeLine parses the chunk size and the chunk extensions of a
+// chunk-size line, applying the configured chunk extension policy.
+func parseChunkSizeLine(line string, config config) (int64, []ChunkExtension, error) {
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+	var extensions string
+
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line, extensions = line[:i], line[i+1:]
+	}
+
+	size, err := parseChunkSize(strings.TrimRight(line, " \t"))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if extensions == "" {
+		return size, nil, nil
+	}
+
+	if config.maxChunkExtensionLength > 0 && len(extensions) > config.maxChunkExtensionLength {
+		return 0, nil, ErrChunkExtensionTooLong
+	}
+
+	switch config.chunkExtensionPolicy {
+	case ChunkExtensionsStrip:
+		return size, nil, nil
+	case ChunkExtensionsReject:
+		return 0, nil, ErrChunkExtensionRejected
+	}
+
+	parsed, err := parseChunkExtensions(extensions)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return size, parsed, nil
+}
+
+// parseChunkSize parses a chunk size consisting of hex digits only.
+func parseChunkSize(s string) (int64, error) {
+	if s == "" {
+		return 0, ErrMalformedChunk
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", rune(s[i])) {
+			return 0, ErrMalformedChunk
+		}
+	}
+
+	size, err := strconv.ParseInt(s, 16, 64)
+	if err != nil {
+		return 0, ErrMalformedChunk
+	}
+
+	return size, nil
+}
+
+// parseChunkExtensions parses the extensions following the first semicolon
+// of a chunk-size line.
+func parseChunkExtensions(s string) ([]ChunkExtension, error) {
+	elements, err := splitQuoted(s, ';')
+	if err != nil {
+		return nil, ErrMalformedChunk
+	}
+
+	extensions := make([]ChunkExtension, 0, len(elements))
+
+	for _, element := range elements {
+		tokens := strings.SplitN(element, "=", 2)
+		extension := ChunkExtension{
+			Name: strings.Trim(tokens[0], " \t"),
+		}
+
+		if !isToken(extension.Name) {
+			return nil, ErrMalformedChunk
+		}
+
+		if len(tokens) == 2 {
+			value := strings.Trim(tokens[1], " \t")
+			if !strings.HasPrefix(value, `"`) && !isToken(value) {
+				return nil, ErrMalformedChunk
+			}
+			if extension.Value, err = unquote(value); err != nil {
+				return nil, ErrMalformedChunk
+			}
+		}
+
+		extensions = append(extensions, extension)
+	}
+
+	return extensions, nil
+}
+
+// isToken reports whether s is a non-empty token (RFC 7230, section 3.2.6.).
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// chunkedWriter frames the data written to it as chunks. It is used for
+// serializing messages whose final transfer coding is chunked, since the
+// body of a parsed message has already been de-chunked.
+type chunkedWriter struct {
+	writer io.Writer
+}
+
+func (c *chunkedWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if _, err := fmt.Fprintf(c.writer, "%x\r\n", len(p)); err != nil {
+		return 0, err
+	}
+
+	if _, err := c.writer.Write(p); err != nil {
+		return 0, err
+	}
+
+	if _, err := io.WriteString(c.writer, "\r\n"); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// close writes the last chunk, the trailer fields, and the empty line
+// terminating the message.
+func (c *chunkedWriter) close(trailer http.Header) error {
+	if _, err := io.WriteString(c.writer, "0\r\n"); err != nil {
+		return err
+	}
+
+	return writeHeaderFields(trailer, c.writer)
+}
+
+// isChunked reports whether chunked is the final transfer coding of a
+// message.
+func isChunked(headers http.Header) bool {
+	codings, err := parseTransferEncoding(headers)
+	return err == nil && len(codings) > 0 && codings[len(codings)-1] == "chunked"
+}
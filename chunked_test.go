@@ -0,0 +1,245 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChunkedBody(t *testing.T) {
+	testCases := map[string]struct {
+		body               string
+		options            []Option
+		expectedBody       string
+		expectedExtensions []ChunkExtension
+		expectedTrailer    string
+		expectedError      error
+	}{
+		"multiple chunks": {
+			body:         "5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n",
+			expectedBody: "hello world",
+		},
+		"trailer": {
+			body:            "5\r\nhello\r\n0\r\nChecksum: abc\r\n\r\n",
+			expectedBody:    "hello",
+			expectedTrailer: "abc",
+		},
+		"extensions": {
+			body:         "5;name=value;quoted=\"a;b\";flag\r\nhello\r\n0\r\n\r\n",
+			expectedBody: "hello",
+			expectedExtensions: []ChunkExtension{
+				{Name: "name", Value: "value"},
+				{Name: "quoted", Value: "a;b"},
+				{Name: "flag"},
+			},
+		},
+		"stripped extensions": {
+			body:         "5;name=value\r\nhello\r\n0\r\n\r\n",
+			options:      []Option{WithChunkExtensionPolicy(ChunkExtensionsStrip)},
+			expectedBody: "hello",
+		},
+		"rejected extensions": {
+			body:          "5;name=value\r\nhello\r\n0\r\n\r\n",
+			options:       []Option{WithChunkExtensionPolicy(ChunkExtensionsReject)},
+			expectedError: ErrChunkExtensionRejected,
+		},
+		"extensions too long": {
+			body:          "5;name=value\r\nhello\r\n0\r\n\r\n",
+			options:       []Option{WithMaxChunkExtensionLength(4)},
+			expectedError: ErrChunkExtensionTooLong,
+		},
+		"invalid extension": {
+			body:          "5;na me\r\nhello\r\n0\r\n\r\n",
+			expectedError: ErrMalformedChunk,
+		},
+		"invalid chunk size": {
+			body:          "-5\r\nhello\r\n0\r\n\r\n",
+			expectedError: ErrMalformedChunk,
+		},
+		"missing chunk end": {
+			body:          "5\r\nhello world\r\n0\r\n\r\n",
+			expectedBody:  "hello",
+			expectedError: ErrMalformedChunk,
+		},
+	}
+
+	for name, tc := range testCases {
+		var extensions []ChunkExtension
+		options := append(tc.options, WithChunkExtensionHandler(func(e []ChunkExtension) error {
+			extensions = append(extensions, e...)
+			return nil
+		}))
+
+		source := "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n" + tc.body
+
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), options...)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(request.Body)
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if string(body) != tc.expectedBody {
+			t.Errorf("'%s': expected body %s, got %s", name, tc.expectedBody, string(body))
+		}
+
+		if !reflect.DeepEqual(extensions, tc.expectedExtensions) {
+			t.Errorf("'%s': expected extensions %v, got %v", name, tc.expectedExtensions, extensions)
+		}
+
+		if trailer := request.Trailer.Get("Checksum"); trailer != tc.expectedTrailer {
+			t.Errorf("'%s': expected trailer %s, got %s", name, tc.expectedTrailer, trailer)
+		}
+	}
+}
+
+func TestChunkedBody_RoundTrip(t *testing.T) {
+	testCases := map[string]struct {
+		source          string
+		expectedBody    string
+		expectedTrailer http.Header
+	}{
+		"chunked": {
+			source:       "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n",
+			expectedBody: "hello world",
+		},
+		"chunked with trailer": {
+			source:          "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\nX-Sum: 5\r\n\r\n",
+			expectedBody:    "hello",
+			expectedTrailer: http.Header{"X-Sum": {"5"}},
+		},
+		"empty chunked": {
+			source: "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(tc.source)))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		transaction, err := NewTransaction(request, nil)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		// The message is followed by another one, which must not be
+		// consumed as part of the re-parsed body.
+		reader := bufio.NewReader(strings.NewReader(string(transaction.Request) + "GET /next HTTP/1.1\r\n\r\n"))
+
+		reparsed, err := ParseRequest(reader)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(reparsed.Body)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(body) != tc.expectedBody {
+			t.Errorf("'%s': expected body %q, got %q", name, tc.expectedBody, string(body))
+		}
+
+		if len(tc.expectedTrailer) > 0 && !reflect.DeepEqual(reparsed.Trailer, tc.expectedTrailer) {
+			t.Errorf("'%s': expected trailer %v, got %v", name, tc.expectedTrailer, reparsed.Trailer)
+		}
+
+		if next, err := ParseRequest(reader); err != nil || next.URL.Path != "/next" {
+			t.Errorf("'%s': expected the next request to be intact, got error %v", name, err)
+		}
+	}
+}
+
+func TestWriteResponse_Chunked(t *testing.T) {
+	source := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\nX-Sum: 5\r\n\r\n"
+
+	response, err := ParseResponse(bufio.NewReader(strings.NewReader(source)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := WriteResponse(&buf, response); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if buf.String() != source {
+		t.Errorf("expected response %q, got %q", source, buf.String())
+	}
+}
+
+func TestChunkedBody_Empty(t *testing.T) {
+	testCases := map[string]struct {
+		body   io.ReadCloser
+		stream bool
+	}{
+		"serialize nil body": {},
+		"serialize no body": {
+			body: http.NoBody,
+		},
+		"write nil body": {
+			stream: true,
+		},
+		"write no body": {
+			body:   http.NoBody,
+			stream: true,
+		},
+	}
+
+	expected := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n0\r\n\r\n"
+
+	for name, tc := range testCases {
+		response := &http.Response{
+			Status: "200 OK",
+			Proto:  "HTTP/1.1",
+			Header: http.Header{"Transfer-Encoding": {"chunked"}},
+			Body:   tc.body,
+		}
+
+		var message []byte
+		var err error
+
+		if tc.stream {
+			var buf bytes.Buffer
+			err = WriteResponse(&buf, response)
+			message = buf.Bytes()
+		} else {
+			message, err = SerializeResponse(response)
+		}
+
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(message) != expected {
+			t.Errorf("'%s': expected response %q, got %q", name, expected, string(message))
+		}
+
+		next := "HTTP/1.1 204 No Content\r\n\r\n"
+		reader := bufio.NewReader(strings.NewReader(string(message) + next))
+
+		parsed, err := ParseResponse(reader)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if body, err := ioutil.ReadAll(parsed.Body); err != nil || len(body) != 0 {
+			t.Errorf("'%s': expected empty body, got %q (%v)", name, body, err)
+		}
+
+		if parsed, err := ParseResponse(reader); err != nil || parsed.StatusCode != http.StatusNoContent {
+			t.Errorf("'%s': expected the next response to be intact, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+// Package codec provides a compact binary encoding for parsed requests and
+// responses. Captured traffic can be passed between pipeline stages, e.g.
+// through a message queue, without serializing and re-parsing the raw HTTP
+// messages at every stage.
+//
+// An encoded message starts with a magic byte, a version, and the message
+// kind, followed by length-prefixed fields. Strings and byte slices are
+// prefixed with their length as an unsigned varint.
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const (
+	magic   = 'G'
+	version = 1
+
+	kindRequest  = 1
+	kindResponse = 2
+)
+
+// ErrInvalidMessage is returned for data that isn't an encoded message of
+// the expected kind.
+var ErrInvalidMessage = errors.New("invalid encoded message")
+
+// MarshalRequest encodes a request. The body is read completely and
+// replaced, so that the request can still be used afterwards.
+func MarshalRequest(r *http.Request) ([]byte, error) {
+	body, err := readBody(&r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var e encoder
+	e.header(kindRequest)
+	e.string(r.Method)
+	e.string(r.URL.String())
+	e.string(r.Proto)
+	e.string(r.Host)
+	e.fields(r.Header)
+	e.fields(r.Trailer)
+	e.bytes(body)
+
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalRequest decodes a request encoded with MarshalRequest.
+func UnmarshalRequest(data []byte) (*http.Request, error) {
+	d := decoder{r: bytes.NewReader(data)}
+	d.header(kindRequest)
+
+	request := &http.Request{
+		Method: d.string(),
+	}
+
+	target := d.string()
+	request.Proto = d.string()
+	request.Host = d.string()
+	request.Header = d.fields()
+	request.Trailer = d.fields()
+	body := d.bytes()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	var err error
+	if request.URL, err = url.Parse(target); err != nil {
+		return nil, err
+	}
+
+	request.ProtoMajor, request.ProtoMinor, _ = http.ParseHTTPVersion(request.Proto)
+	request.ContentLength = int64(len(body))
+	request.Body = newBody(body)
+
+	return request, nil
+}
+
+// MarshalResponse encodes a response. The body is read completely and
+// replaced, so that the response can still be used afterwards.
+func MarshalResponse(r *http.Response) ([]byte, error) {
+	body, err := readBody(&r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var e encoder
+	e.header(kindResponse)
+	e.uvarint(uint64(r.StatusCode))
+	e.string(r.Status)
+	e.string(r.Proto)
+	e.fields(r.Header)
+	e.fields(r.Trailer)
+	e.bytes(body)
+
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalResponse decodes a response encoded with MarshalResponse.
+func UnmarshalResponse(data []byte) (*http.Response, error) {
+	d := decoder{r: bytes.NewReader(data)}
+	d.header(kindResponse)
+
+	response := &http.Response{
+		StatusCode: int(d.uvarint()),
+		Status:     d.string(),
+		Proto:      d.string(),
+		Header:     d.fields(),
+		Trailer:    d.fields(),
+	}
+	body := d.bytes()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	response.ProtoMajor, response.ProtoMinor, _ = http.ParseHTTPVersion(response.Proto)
+	response.ContentLength = int64(len(body))
+	response.Body = newBody(body)
+
+	return response, nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (e *encoder) header(kind byte) {
+	e.buf.Write([]byte{magic, version, kind})
+}
+
+func (e *encoder) uvarint(n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *encoder) fields(header http.Header) {
+	e.uvarint(uint64(len(header)))
+
+	for name, values := range header {
+		e.string(name)
+		e.uvarint(uint64(len(values)))
+		for _, value := range values {
+			e.string(value)
+		}
+	}
+}
+
+// decoder decodes fields in order. After the first error, all methods
+// return zero values and the error is kept in err.
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *decoder) header(kind byte) {
+	var b [3]byte
+	if _, err := io.ReadFull(d.r, b[:]); err != nil || b[0] != magic || b[2] != kind {
+		d.err = ErrInvalidMessage
+		return
+	}
+
+	if b[1] != version {
+		d.err = fmt.Errorf("unsupported encoding version %d", b[1])
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.err = ErrInvalidMessage
+	}
+
+	return n
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+
+	if n > uint64(d.r.Len()) {
+		d.err = ErrInvalidMessage
+		return nil
+	}
+
+	b := make([]byte, n)
+	_, _ = io.ReadFull(d.r, b)
+
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) fields() http.Header {
+	n := d.uvarint()
+	if d.err != nil || n == 0 {
+		return make(http.Header)
+	}
+
+	if n > uint64(d.r.Len()) {
+		d.err = ErrInvalidMessage
+		return nil
+	}
+
+	header := make(http.Header, n)
+
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		name := d.string()
+
+		count := d.uvarint()
+		if count > uint64(d.r.Len()) {
+			d.err = ErrInvalidMessage
+			break
+		}
+
+		values := make([]string, 0, count)
+		for j := uint64(0); j < count && d.err == nil; j++ {
+			values = append(values, d.string())
+		}
+
+		header[name] = values
+	}
+
+	return header
+}
+
+// readBody reads a body completely and replaces it with a new reader over
+// the read content.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	content, err := ioutil.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := (*body).Close(); err != nil {
+		return nil, err
+	}
+
+	*body = newBody(content)
+
+	return content, nil
+}
+
+func newBody(content []byte) io.ReadCloser {
+	if len(content) == 0 {
+		return http.NoBody
+	}
+	return ioutil.NopCloser(bytes.NewReader(content))
+}
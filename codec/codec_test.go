@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"bufio"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestRequest(t *testing.T) {
+	source := "POST /api?q=1 HTTP/1.1\r\nHost: example.com\r\nAccept: a\r\nAccept: b\r\nContent-Length: 5\r\n\r\nhello"
+
+	request, err := gohttp.ParseRequest(bufio.NewReader(strings.NewReader(source)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	data, err := MarshalRequest(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if body, _ := ioutil.ReadAll(request.Body); string(body) != "hello" {
+		t.Errorf("expected original body to be restored, got %s", string(body))
+	}
+
+	decoded, err := UnmarshalRequest(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if decoded.Method != "POST" || decoded.URL.String() != "/api?q=1" || decoded.ProtoMinor != 1 {
+		t.Errorf("unexpected request line %s %s %s", decoded.Method, decoded.URL.String(), decoded.Proto)
+	}
+
+	if !reflect.DeepEqual(decoded.Header, request.Header) {
+		t.Errorf("expected header fields %v, got %v", request.Header, decoded.Header)
+	}
+
+	if body, _ := ioutil.ReadAll(decoded.Body); string(body) != "hello" {
+		t.Errorf("expected body %s, got %s", "hello", string(body))
+	}
+}
+
+func TestResponse(t *testing.T) {
+	response := gohttp.NewResponse(404, []byte("not found"))
+	response.Header.Set("Content-Type", "text/plain")
+
+	data, err := MarshalResponse(response)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	decoded, err := UnmarshalResponse(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if decoded.StatusCode != 404 || decoded.Status != "404 Not Found" {
+		t.Errorf("expected status %s, got %s", "404 Not Found", decoded.Status)
+	}
+
+	if !reflect.DeepEqual(decoded.Header, response.Header) {
+		t.Errorf("expected header fields %v, got %v", response.Header, decoded.Header)
+	}
+
+	if body, _ := ioutil.ReadAll(decoded.Body); string(body) != "not found" {
+		t.Errorf("expected body %s, got %s", "not found", string(body))
+	}
+}
+
+func TestUnmarshal_Invalid(t *testing.T) {
+	response, _ := MarshalResponse(gohttp.NewResponse(200, []byte("ok")))
+
+	testCases := map[string][]byte{
+		"empty":      nil,
+		"wrong kind": response,
+		"truncated":  response[:len(response)-1],
+	}
+
+	for name, data := range testCases {
+		if name == "truncated" {
+			if _, err := UnmarshalResponse(data); err == nil {
+				t.Errorf("'%s': expected an error, got none", name)
+			}
+			continue
+		}
+
+		if _, err := UnmarshalRequest(data); err == nil {
+			t.Errorf("'%s': expected an error, got none", name)
+		}
+	}
+}
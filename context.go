@@ -0,0 +1,116 @@
+package gohttp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// connInfoKey is the context key for a ConnInfo instance.
+type connInfoKey struct{}
+
+// lastConnID is the ID most recently assigned by NewConnInfo.
+var lastConnID uint64
+
+// ConnInfo holds metadata of the client connection a request has been
+// received on.
+type ConnInfo struct {
+	// ID is a process-wide unique ID of the connection.
+	ID uint64
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+	// LocalAddr is the local address the connection has been accepted on.
+	LocalAddr net.Addr
+	// TLS is the state of the TLS connection, or nil for plain connections.
+	TLS *tls.ConnectionState
+	// Protocol is the protocol negotiated via ALPN, or an empty string.
+	Protocol string
+}
+
+// NewConnInfo creates a new ConnInfo instance for the given connection and
+// assigns a new connection ID to it. For TLS connections, the handshake has
+// to be completed beforehand.
+func NewConnInfo(conn net.Conn) *ConnInfo {
+	info := &ConnInfo{
+		ID:         atomic.AddUint64(&lastConnID, 1),
+		RemoteAddr: conn.RemoteAddr(),
+		LocalAddr:  conn.LocalAddr(),
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		info.TLS = &state
+		info.Protocol = state.NegotiatedProtocol
+	}
+
+	return info
+}
+
+// WithConnInfo attaches the given connection metadata to parsed requests.
+// The metadata is stored in the request context, and the RemoteAddr and TLS
+// fields of the request are populated.
+func WithConnInfo(info *ConnInfo) Option {
+	return func(c *config) {
+		c.connInfo = info
+	}
+}
+
+// ContextWithConnInfo returns a copy of ctx carrying the given ConnInfo. The
+// local address is also stored under http.LocalAddrContextKey.
+func ContextWithConnInfo(ctx context.Context, info *ConnInfo) context.Context {
+	ctx = context.WithValue(ctx, connInfoKey{}, info)
+	return context.WithValue(ctx, http.LocalAddrContextKey, info.LocalAddr)
+}
+
+// ConnInfoFromContext returns the ConnInfo stored in ctx, if any.
+func ConnInfoFromContext(ctx context.Context) (*ConnInfo, bool) {
+	info, ok := ctx.Value(connInfoKey{}).(*ConnInfo)
+	return info, ok
+}
+
+// RemoteAddr returns the client address stored in ctx, or nil.
+func RemoteAddr(ctx context.Context) net.Addr {
+	if info, ok := ConnInfoFromContext(ctx); ok {
+		return info.RemoteAddr
+	}
+	return nil
+}
+
+// LocalAddr returns the local address stored in ctx, or nil.
+func LocalAddr(ctx context.Context) net.Addr {
+	if info, ok := ConnInfoFromContext(ctx); ok {
+		return info.LocalAddr
+	}
+	return nil
+}
+
+// TLSState returns the TLS connection state stored in ctx, or nil.
+func TLSState(ctx context.Context) *tls.ConnectionState {
+	if info, ok := ConnInfoFromContext(ctx); ok {
+		return info.TLS
+	}
+	return nil
+}
+
+// ConnID returns the connection ID stored in ctx, or 0.
+func ConnID(ctx context.Context) uint64 {
+	if info, ok := ConnInfoFromContext(ctx); ok {
+		return info.ID
+	}
+	return 0
+}
+
+// attachConnInfo returns a shallow copy of the request carrying the given
+// connection metadata.
+func attachConnInfo(request *http.Request, info *ConnInfo) *http.Request {
+	request = request.WithContext(ContextWithConnInfo(request.Context(), info))
+
+	if info.RemoteAddr != nil {
+		request.RemoteAddr = info.RemoteAddr.String()
+	}
+	request.TLS = info.TLS
+
+	return request
+}
@@ -0,0 +1,62 @@
+package gohttp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseRequestWithConnInfo(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	first := NewConnInfo(server)
+	second := NewConnInfo(server)
+
+	if second.ID <= first.ID {
+		t.Errorf("expected increasing connection IDs, got %d and %d", first.ID, second.ID)
+	}
+
+	source := "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	reader := bufio.NewReader(strings.NewReader(source))
+
+	request, err := ParseRequest(reader, WithConnInfo(first))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	ctx := request.Context()
+
+	if ConnID(ctx) != first.ID {
+		t.Errorf("expected connection ID %d, got %d", first.ID, ConnID(ctx))
+	}
+
+	if RemoteAddr(ctx) != server.RemoteAddr() {
+		t.Errorf("expected remote address %v, got %v", server.RemoteAddr(), RemoteAddr(ctx))
+	}
+
+	if LocalAddr(ctx) != server.LocalAddr() {
+		t.Errorf("expected local address %v, got %v", server.LocalAddr(), LocalAddr(ctx))
+	}
+
+	if TLSState(ctx) != nil {
+		t.Errorf("expected no TLS state, got %v", TLSState(ctx))
+	}
+
+	if request.RemoteAddr != server.RemoteAddr().String() {
+		t.Errorf("expected request remote address %s, got %s", server.RemoteAddr().String(), request.RemoteAddr)
+	}
+}
+
+func TestConnInfoFromContext(t *testing.T) {
+	if _, ok := ConnInfoFromContext(context.Background()); ok {
+		t.Errorf("expected no connection info in empty context")
+	}
+
+	if ConnID(context.Background()) != 0 {
+		t.Errorf("expected connection ID 0 in empty context")
+	}
+}
@@ -0,0 +1,240 @@
+// Package cors implements Cross-Origin Resource Sharing for parsed requests.
+// Preflight requests are answered with a 204 response that can be
+// serialized directly, and actual requests get the appropriate response
+// header fields injected.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// Request holds the CORS-related information of a parsed request.
+type Request struct {
+	// Origin is the value of the Origin header field.
+	Origin string
+	// Method is the value of the Access-Control-Request-Method header field.
+	Method string
+	// Headers are the values of the Access-Control-Request-Headers header
+	// field in lower case.
+	Headers []string
+	// Preflight indicates whether the request is a preflight request.
+	Preflight bool
+}
+
+// ParseRequest extracts the CORS-related information from a request. It
+// returns false if the request has no Origin header field.
+func ParseRequest(r *http.Request) (Request, bool) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return Request{}, false
+	}
+
+	request := Request{
+		Origin: origin,
+		Method: r.Header.Get("Access-Control-Request-Method"),
+	}
+
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				request.Headers = append(request.Headers, strings.ToLower(name))
+			}
+		}
+	}
+
+	request.Preflight = r.Method == http.MethodOptions && request.Method != ""
+
+	return request, true
+}
+
+// Policy describes which cross-origin requests are allowed.
+type Policy struct {
+	// AllowedOrigins are the allowed origins. An origin may contain a single
+	// wildcard, e.g. "https://*.example.com", and "*" allows all origins.
+	AllowedOrigins []string
+	// AllowedMethods are the allowed methods. Defaults to GET, HEAD, POST.
+	AllowedMethods []string
+	// AllowedHeaders are the allowed request header fields. "*" allows all
+	// header fields.
+	AllowedHeaders []string
+	// ExposedHeaders are the response header fields exposed to scripts.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with credentials. It only applies to
+	// origins matching an entry other than "*", since allowing credentials
+	// for any origin would let every site read responses to requests made
+	// with the user's credentials.
+	AllowCredentials bool
+	// MaxAge is the time preflight results may be cached.
+	MaxAge time.Duration
+}
+
+// AllowsOrigin reports whether the origin is allowed.
+func (p Policy) AllowsOrigin(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) allowsMethod(method string) bool {
+	methods := p.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+
+	for _, allowed := range methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Policy) allowsHeaders(headers []string) bool {
+	for _, header := range headers {
+		var allowed bool
+		for _, a := range p.AllowedHeaders {
+			if a == "*" || strings.EqualFold(a, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Preflight answers a preflight request. It returns false if the request is
+// no preflight request, and the returned 204 response only contains the
+// Access-Control-* header fields if the request is allowed.
+func (p Policy) Preflight(r *http.Request) (*http.Response, bool) {
+	request, ok := ParseRequest(r)
+	if !ok || !request.Preflight {
+		return nil, false
+	}
+
+	response := gohttp.NewResponse(http.StatusNoContent, nil)
+	response.Header.Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+
+	if !p.AllowsOrigin(request.Origin) || !p.allowsMethod(request.Method) || !p.allowsHeaders(request.Headers) {
+		return response, true
+	}
+
+	p.setOrigin(response.Header, request.Origin)
+	response.Header.Set("Access-Control-Allow-Methods", strings.ToUpper(request.Method))
+
+	if len(request.Headers) > 0 {
+		response.Header.Set("Access-Control-Allow-Headers", strings.Join(request.Headers, ", "))
+	}
+
+	if p.MaxAge > 0 {
+		response.Header.Set("Access-Control-Max-Age", strconv.FormatInt(int64(p.MaxAge/time.Second), 10))
+	}
+
+	return response, true
+}
+
+// Apply injects the CORS response header fields for an actual request into
+// the given response header fields.
+func (p Policy) Apply(r *http.Request, header http.Header) {
+	request, ok := ParseRequest(r)
+	if !ok {
+		return
+	}
+
+	header.Add("Vary", "Origin")
+
+	if !p.AllowsOrigin(request.Origin) {
+		return
+	}
+
+	p.setOrigin(header, request.Origin)
+
+	if len(p.ExposedHeaders) > 0 {
+		header.Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+	}
+}
+
+// Middleware returns an http.Handler answering preflight requests directly
+// and applying the policy to all other responses of next.
+func (p Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if response, ok := p.Preflight(r); ok {
+			for name, values := range response.Header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(response.StatusCode)
+			return
+		}
+
+		p.Apply(r, w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setOrigin sets Access-Control-Allow-Origin. The wildcard can't be used in
+// combination with credentials, so the origin is echoed for credentialed
+// requests. This is limited to explicitly allowed origins: an origin only
+// allowed by "*" gets the wildcard and no credentials, as echoing it would
+// allow credentialed requests from any site.
+func (p Policy) setOrigin(header http.Header, origin string) {
+	if p.allowsAnyOrigin() && (!p.AllowCredentials || !p.listsOrigin(origin)) {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+
+	if p.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (p Policy) allowsAnyOrigin() bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// listsOrigin reports whether the origin matches an allowed origin other than
+// "*".
+func (p Policy) listsOrigin(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed != "*" && matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin matches an origin against a pattern with an optional single
+// wildcard.
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return strings.EqualFold(pattern, origin)
+	}
+
+	prefix, suffix := strings.ToLower(pattern[:i]), strings.ToLower(pattern[i+1:])
+	origin = strings.ToLower(origin)
+
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) &&
+		strings.HasSuffix(origin, suffix)
+}
@@ -0,0 +1,237 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRequest(t *testing.T) {
+	testCases := map[string]struct {
+		method   string
+		header   http.Header
+		expected Request
+		ok       bool
+	}{
+		"no origin": {
+			method: "GET",
+			header: http.Header{},
+		},
+		"actual request": {
+			method:   "GET",
+			header:   http.Header{"Origin": {"https://example.com"}},
+			expected: Request{Origin: "https://example.com"},
+			ok:       true,
+		},
+		"preflight request": {
+			method: "OPTIONS",
+			header: http.Header{
+				"Origin":                         {"https://example.com"},
+				"Access-Control-Request-Method":  {"PUT"},
+				"Access-Control-Request-Headers": {"Content-Type, X-Token"},
+			},
+			expected: Request{
+				Origin:    "https://example.com",
+				Method:    "PUT",
+				Headers:   []string{"content-type", "x-token"},
+				Preflight: true,
+			},
+			ok: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest(tc.method, "/", nil)
+		request.Header = tc.header
+
+		actual, ok := ParseRequest(request)
+
+		if ok != tc.ok || !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("'%s': expected %+v (%v), got %+v (%v)", name, tc.expected, tc.ok, actual, ok)
+		}
+	}
+}
+
+func TestPolicy_Preflight(t *testing.T) {
+	policy := Policy{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	testCases := map[string]struct {
+		origin   string
+		method   string
+		headers  string
+		expected map[string]string
+	}{
+		"allowed": {
+			origin:  "https://app.example.com",
+			method:  "PUT",
+			headers: "content-type",
+			expected: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "PUT",
+				"Access-Control-Allow-Headers":     "content-type",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		"origin not allowed": {
+			origin: "https://example.org",
+			method: "PUT",
+			expected: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		"method not allowed": {
+			origin: "https://app.example.com",
+			method: "DELETE",
+			expected: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		"header not allowed": {
+			origin:  "https://app.example.com",
+			method:  "GET",
+			headers: "X-Token",
+			expected: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest("OPTIONS", "/", nil)
+		request.Header.Set("Origin", tc.origin)
+		request.Header.Set("Access-Control-Request-Method", tc.method)
+		if tc.headers != "" {
+			request.Header.Set("Access-Control-Request-Headers", tc.headers)
+		}
+
+		response, ok := policy.Preflight(request)
+		if !ok {
+			t.Fatalf("'%s': expected a preflight response", name)
+		}
+
+		if response.StatusCode != http.StatusNoContent {
+			t.Errorf("'%s': expected status code %d, got %d", name, http.StatusNoContent, response.StatusCode)
+		}
+
+		for field, expected := range tc.expected {
+			if actual := response.Header.Get(field); actual != expected {
+				t.Errorf("'%s': expected %s %q, got %q", name, field, expected, actual)
+			}
+		}
+	}
+}
+
+func TestPolicy_WildcardCredentials(t *testing.T) {
+	policy := Policy{
+		AllowedOrigins:   []string{"*", "https://app.example.com"},
+		AllowCredentials: true,
+	}
+
+	testCases := map[string]struct {
+		origin      string
+		allowed     string
+		credentials string
+	}{
+		"listed origin": {
+			origin:      "https://app.example.com",
+			allowed:     "https://app.example.com",
+			credentials: "true",
+		},
+		"any origin": {
+			origin:  "https://evil.example.org",
+			allowed: "*",
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("Origin", tc.origin)
+
+		header := make(http.Header)
+		policy.Apply(request, header)
+
+		if actual := header.Get("Access-Control-Allow-Origin"); actual != tc.allowed {
+			t.Errorf("'%s': expected origin %q, got %q", name, tc.allowed, actual)
+		}
+
+		if actual := header.Get("Access-Control-Allow-Credentials"); actual != tc.credentials {
+			t.Errorf("'%s': expected credentials %q, got %q", name, tc.credentials, actual)
+		}
+	}
+}
+
+func TestPolicy_Middleware(t *testing.T) {
+	policy := Policy{
+		AllowedOrigins: []string{"*"},
+		ExposedHeaders: []string{"X-Request-Id"},
+	}
+
+	var called bool
+	handler := policy.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Origin", "https://example.com")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if !called {
+		t.Errorf("expected the handler to be called")
+	}
+
+	if recorder.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("expected wildcard origin, got %s", recorder.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	if recorder.Header().Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+		t.Errorf("expected exposed headers, got %s", recorder.Header().Get("Access-Control-Expose-Headers"))
+	}
+
+	called = false
+	preflight := httptest.NewRequest("OPTIONS", "/", nil)
+	preflight.Header.Set("Origin", "https://example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "GET")
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, preflight)
+
+	if called {
+		t.Errorf("expected the preflight request to be short-circuited")
+	}
+
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("expected status code %d, got %d", http.StatusNoContent, recorder.Code)
+	}
+}
+
+func TestMatchOrigin(t *testing.T) {
+	testCases := map[string]struct {
+		pattern  string
+		origin   string
+		expected bool
+	}{
+		"exact":             {pattern: "https://example.com", origin: "https://example.com", expected: true},
+		"case insensitive":  {pattern: "https://example.com", origin: "https://EXAMPLE.com", expected: true},
+		"wildcard":          {pattern: "https://*.example.com", origin: "https://a.example.com", expected: true},
+		"wildcard no label": {pattern: "https://*.example.com", origin: "https://.example.com", expected: false},
+		"other scheme":      {pattern: "https://*.example.com", origin: "http://a.example.com", expected: false},
+		"any":               {pattern: "*", origin: "null", expected: true},
+	}
+
+	for name, tc := range testCases {
+		if actual := matchOrigin(tc.pattern, tc.origin); actual != tc.expected {
+			t.Errorf("'%s': expected result %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ratioThreshold is the decoded size from which on the compression ratio is
+// enforced. Small bodies, e.g. a few hundred spaces, legitimately compress
+// far better than any sensible ratio limit.
+const ratioThreshold = 64 << 10
+
+var (
+	// ErrDecompressedTooLarge indicates a body that exceeds the maximum
+	// decompressed size.
+	ErrDecompressedTooLarge = errors.New("decompressed body too large")
+	// ErrCompressionRatio indicates a body that exceeds the maximum
+	// compression ratio.
+	ErrCompressionRatio = errors.New("compression ratio too high")
+)
+
+// DecompressionError is returned when reading a decoded body that exceeds
+// the limits set using WithDecompressionLimits.
+type DecompressionError struct {
+	// Compressed is the number of compressed bytes read so far.
+	Compressed int64
+	// Decompressed is the number of decompressed bytes read so far.
+	Decompressed int64
+	// Err is the exceeded limit.
+	Err error
+}
+
+func (d *DecompressionError) Error() string {
+	return fmt.Sprintf("%s: %d bytes decompressed from %d bytes", d.Err.Error(), d.Decompressed, d.Compressed)
+}
+
+func (d *DecompressionError) Unwrap() error {
+	return d.Err
+}
+
+// StatusCode returns 413 Payload Too Large (RFC 7231, section 6.5.11.).
+func (d *DecompressionError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+type decompressionLimits struct {
+	maxSize  int64
+	maxRatio float64
+}
+
+// WithDecompressionLimits protects against decompression bombs when
+// transfer codings are decoded using WithTransferDecoding. Reading a body
+// that decompresses to more than maxSize bytes, or more than maxRatio times
+// its compressed size, fails with a DecompressionError. The ratio is only
+// enforced from 64 KiB of decompressed data on. A limit of 0 disables the
+// respective check.
+func WithDecompressionLimits(maxSize int64, maxRatio float64) Option {
+	return func(c *config) {
+		c.decompressionLimits = decompressionLimits{
+			maxSize:  maxSize,
+			maxRatio: maxRatio,
+		}
+	}
+}
+
+func (d decompressionLimits) enabled() bool {
+	return d.maxSize > 0 || d.maxRatio > 0
+}
+
+// compressedCounter counts the compressed bytes read by the decoders.
+type compressedCounter struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *compressedCounter) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// limitedDecoder enforces the decompression limits on the output of the
+// transfer decoders.
+type limitedDecoder struct {
+	reader     io.Reader
+	compressed *compressedCounter
+	limits     decompressionLimits
+	n          int64
+	err        error
+}
+
+func (l *limitedDecoder) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	// Reading at most one byte beyond the maximum size is enough to detect
+	// an exceeded limit without decompressing any further.
+	if l.limits.maxSize > 0 && int64(len(p)) > l.limits.maxSize-l.n+1 {
+		p = p[:l.limits.maxSize-l.n+1]
+	}
+
+	n, err := l.reader.Read(p)
+	l.n += int64(n)
+
+	if l.limits.maxSize > 0 && l.n > l.limits.maxSize {
+		l.err = l.error(ErrDecompressedTooLarge)
+		return 0, l.err
+	}
+
+	if l.limits.maxRatio > 0 && l.n >= ratioThreshold &&
+		float64(l.n) > l.limits.maxRatio*float64(l.compressed.n) {
+		l.err = l.error(ErrCompressionRatio)
+		return 0, l.err
+	}
+
+	return n, err
+}
+
+func (l *limitedDecoder) error(err error) error {
+	return &DecompressionError{
+		Compressed:   l.compressed.n,
+		Decompressed: l.n,
+		Err:          err,
+	}
+}
@@ -0,0 +1,88 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestWithDecompressionLimits(t *testing.T) {
+	compress := func(content []byte) string {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		_, _ = writer.Write(content)
+		_ = writer.Close()
+		return compressed.String()
+	}
+
+	random := make([]byte, 256<<10)
+	_, _ = rand.New(rand.NewSource(1)).Read(random)
+
+	bomb := compress(make([]byte, 1<<20))
+	incompressible := compress(random)
+
+	testCases := map[string]struct {
+		body          string
+		maxSize       int64
+		maxRatio      float64
+		expectedSize  int
+		expectedError error
+	}{
+		"within limits": {
+			body:         incompressible,
+			maxSize:      1 << 20,
+			maxRatio:     10,
+			expectedSize: len(random),
+		},
+		"too large": {
+			body:          incompressible,
+			maxSize:       128 << 10,
+			expectedError: ErrDecompressedTooLarge,
+		},
+		"exactly the maximum size": {
+			body:         incompressible,
+			maxSize:      int64(len(random)),
+			expectedSize: len(random),
+		},
+		"ratio too high": {
+			body:          bomb,
+			maxRatio:      100,
+			expectedError: ErrCompressionRatio,
+		},
+		"ratio disabled": {
+			body:         bomb,
+			maxSize:      2 << 20,
+			expectedSize: 1 << 20,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "HTTP/1.1 200 OK\r\nTransfer-Encoding: gzip\r\n\r\n" + tc.body
+
+		response, err := ParseResponse(bufio.NewReader(strings.NewReader(source)),
+			WithTransferDecoding(true), WithDecompressionLimits(tc.maxSize, tc.maxRatio))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(response.Body)
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+			continue
+		}
+
+		var decompressionErr *DecompressionError
+		if err != nil && (!errors.As(err, &decompressionErr) || decompressionErr.StatusCode() != 413) {
+			t.Errorf("'%s': expected a DecompressionError with status code 413, got %v", name, err)
+		}
+
+		if err == nil && len(body) != tc.expectedSize {
+			t.Errorf("'%s': expected %d bytes, got %d", name, tc.expectedSize, len(body))
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// ErrDuplicateHeader indicates a header field that occurs multiple times
+// although the duplicate policy rejects this.
+var ErrDuplicateHeader = errors.New("duplicate header field")
+
+// DuplicateHeaderError is returned by the parser for a header field that has
+// been rejected by its duplicate policy. It wraps ErrDuplicateHeader.
+type DuplicateHeaderError struct {
+	// Name is the canonical name of the duplicate header field.
+	Name string
+}
+
+func (d *DuplicateHeaderError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrDuplicateHeader.Error(), d.Name)
+}
+
+func (d *DuplicateHeaderError) Unwrap() error {
+	return ErrDuplicateHeader
+}
+
+// StatusCode returns the status code for responding to a request with a
+// rejected duplicate header field, which is 400.
+func (d *DuplicateHeaderError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// DuplicatePolicy determines how the parser treats multiple occurrences of
+// the same header field.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesKeep keeps all values.
+	DuplicatesKeep DuplicatePolicy = iota
+	// DuplicatesReject fails parsing with a DuplicateHeaderError.
+	DuplicatesReject
+	// DuplicatesFirstWins keeps the first value only.
+	DuplicatesFirstWins
+	// DuplicatesLastWins keeps the last value only.
+	DuplicatesLastWins
+	// DuplicatesMerge combines all values into a comma-separated list.
+	DuplicatesMerge
+)
+
+// singletonFields are the header fields that the policy set with
+// WithDuplicatePolicy applies to.
+var singletonFields = map[string]bool{
+	"Authorization":  true,
+	"Content-Length": true,
+	"Host":           true,
+}
+
+// WithDuplicatePolicy sets the policy for duplicates of header fields that
+// may only occur once: Host, Content-Length, and Authorization. Since
+// downstream systems disagree on which occurrence counts, gateways should
+// choose the policy matching their backends. By default, all values are
+// kept.
+//
+// For Content-Length, the policies only apply to identical duplicates.
+// Differing values are always rejected with a ContentLengthError (RFC 7230,
+// section 3.3.2.), since picking one of them could frame the body
+// differently than a peer does.
+func WithDuplicatePolicy(policy DuplicatePolicy) Option {
+	return func(c *config) {
+		c.duplicatePolicy = policy
+	}
+}
+
+// WithHeaderDuplicatePolicy sets the policy for duplicates of the given
+// header field, overriding the policy set with WithDuplicatePolicy. It can
+// be used for any header field, not only for singletons. Differing
+// Content-Length values are rejected regardless of the policy.
+func WithHeaderDuplicatePolicy(name string, policy DuplicatePolicy) Option {
+	return func(c *config) {
+		if c.headerDuplicatePolicies == nil {
+			c.headerDuplicatePolicies = make(map[string]DuplicatePolicy)
+		}
+		c.headerDuplicatePolicies[textproto.CanonicalMIMEHeaderKey(name)] = policy
+	}
+}
+
+// applyDuplicatePolicies applies the configured duplicate policies to the
+// parsed header fields.
+func applyDuplicatePolicies(header http.Header, config config) error {
+	if config.duplicatePolicy == DuplicatesKeep && len(config.headerDuplicatePolicies) == 0 {
+		return nil
+	}
+
+	for name, values := range header {
+		if len(values) < 2 {
+			continue
+		}
+
+		policy, ok := config.headerDuplicatePolicies[name]
+		if !ok {
+			if !singletonFields[name] {
+				continue
+			}
+			policy = config.duplicatePolicy
+		}
+
+		// Conflicting lengths must never be resolved by a policy.
+		if name == "Content-Length" && policy != DuplicatesReject {
+			if _, err := parseContentLength(values); err != nil {
+				return err
+			}
+		}
+
+		switch policy {
+		case DuplicatesReject:
+			return &DuplicateHeaderError{Name: name}
+		case DuplicatesFirstWins:
+			header[name] = values[:1]
+		case DuplicatesLastWins:
+			header[name] = values[len(values)-1:]
+		case DuplicatesMerge:
+			header[name] = []string{strings.Join(values, ", ")}
+		}
+	}
+
+	return nil
+}
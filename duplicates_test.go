@@ -0,0 +1,123 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDuplicatePolicies(t *testing.T) {
+	source := "GET / HTTP/1.1\r\nHost: a.com\r\nHost: b.com\r\nAccept: x\r\nAccept: y\r\n\r\n"
+
+	testCases := map[string]struct {
+		options        []Option
+		expectedHost   []string
+		expectedAccept []string
+		expectedError  error
+	}{
+		"keep by default": {
+			expectedHost:   []string{"a.com", "b.com"},
+			expectedAccept: []string{"x", "y"},
+		},
+		"reject": {
+			options:       []Option{WithDuplicatePolicy(DuplicatesReject)},
+			expectedError: ErrDuplicateHeader,
+		},
+		"first wins": {
+			options:        []Option{WithDuplicatePolicy(DuplicatesFirstWins)},
+			expectedHost:   []string{"a.com"},
+			expectedAccept: []string{"x", "y"},
+		},
+		"last wins": {
+			options:        []Option{WithDuplicatePolicy(DuplicatesLastWins)},
+			expectedHost:   []string{"b.com"},
+			expectedAccept: []string{"x", "y"},
+		},
+		"merge": {
+			options:        []Option{WithDuplicatePolicy(DuplicatesMerge)},
+			expectedHost:   []string{"a.com, b.com"},
+			expectedAccept: []string{"x", "y"},
+		},
+		"per-header override": {
+			options: []Option{
+				WithDuplicatePolicy(DuplicatesReject),
+				WithHeaderDuplicatePolicy("host", DuplicatesFirstWins),
+				WithHeaderDuplicatePolicy("accept", DuplicatesMerge),
+			},
+			expectedHost:   []string{"a.com"},
+			expectedAccept: []string{"x, y"},
+		},
+	}
+
+	for name, tc := range testCases {
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), tc.options...)
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if !reflect.DeepEqual(request.Header["Host"], tc.expectedHost) {
+			t.Errorf("'%s': expected Host %v, got %v", name, tc.expectedHost, request.Header["Host"])
+		}
+
+		if !reflect.DeepEqual(request.Header["Accept"], tc.expectedAccept) {
+			t.Errorf("'%s': expected Accept %v, got %v", name, tc.expectedAccept, request.Header["Accept"])
+		}
+	}
+}
+
+func TestDuplicatePolicies_ContentLength(t *testing.T) {
+	testCases := map[string]struct {
+		lengths        string
+		policy         DuplicatePolicy
+		expectedLength int64
+		expectedError  error
+	}{
+		"identical first wins": {
+			lengths:        "Content-Length: 5\r\nContent-Length: 5\r\n",
+			policy:         DuplicatesFirstWins,
+			expectedLength: 5,
+		},
+		"differing first wins": {
+			lengths:       "Content-Length: 5\r\nContent-Length: 10\r\n",
+			policy:        DuplicatesFirstWins,
+			expectedError: ErrConflictingContentLength,
+		},
+		"differing last wins": {
+			lengths:       "Content-Length: 5\r\nContent-Length: 10\r\n",
+			policy:        DuplicatesLastWins,
+			expectedError: ErrConflictingContentLength,
+		},
+		"differing list merged": {
+			lengths:       "Content-Length: 5, 10\r\nContent-Length: 5\r\n",
+			policy:        DuplicatesMerge,
+			expectedError: ErrConflictingContentLength,
+		},
+		"identical rejected": {
+			lengths:       "Content-Length: 5\r\nContent-Length: 5\r\n",
+			policy:        DuplicatesReject,
+			expectedError: ErrDuplicateHeader,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "POST / HTTP/1.1\r\nHost: a.com\r\n" + tc.lengths + "\r\nhello world"
+
+		for _, option := range []Option{WithDuplicatePolicy(tc.policy), WithHeaderDuplicatePolicy("Content-Length", tc.policy)} {
+			request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), option)
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+				continue
+			}
+
+			if err == nil && request.ContentLength != tc.expectedLength {
+				t.Errorf("'%s': expected length %d, got %d", name, tc.expectedLength, request.ContentLength)
+			}
+		}
+	}
+}
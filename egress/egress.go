@@ -0,0 +1,316 @@
+// Package egress provides a policy for outbound requests, allowing or
+// denying them by scheme, port, host, and destination IP address.
+//
+// URLs are checked before a request is sent, and the destination IP
+// addresses are checked again after DNS resolution, right before a
+// connection is established. This defends against server-side request
+// forgery (SSRF) with user-controlled URLs: a host name resolving to a
+// private or loopback address is rejected even if the name itself is
+// allowed, and DNS rebinding between the checks doesn't help either.
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrDenied is returned for destinations denied by the policy.
+var ErrDenied = errors.New("egress denied")
+
+// DeniedError is returned for a denied destination and wraps ErrDenied.
+type DeniedError struct {
+	// Destination is the denied scheme, host, port, or address.
+	Destination string
+	// Reason describes the rule denying the destination.
+	Reason string
+}
+
+func (d *DeniedError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrDenied.Error(), d.Destination, d.Reason)
+}
+
+func (d *DeniedError) Unwrap() error {
+	return ErrDenied
+}
+
+// privateNetworks are the loopback, private, link-local, multicast, reserved,
+// and otherwise non-public ranges denied unless explicitly allowed. NAT64
+// addresses are included since they may embed any of the IPv4 ranges.
+var privateNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"fc00::/7",
+	"fe80::/10",
+	"fec0::/10",
+	"ff00::/8",
+)
+
+// Config declares an egress policy.
+type Config struct {
+	// Schemes restricts outbound requests to the given schemes. Defaults
+	// to http and https.
+	Schemes []string
+	// Ports restricts outbound requests to the given ports. If empty, all
+	// ports are allowed.
+	Ports []int
+	// AllowHosts restricts outbound requests to the given hosts. A pattern
+	// like "*.example.com" matches all subdomains of example.com. If empty,
+	// all hosts not denied are allowed.
+	AllowHosts []string
+	// DenyHosts denies the given hosts and takes precedence over
+	// AllowHosts.
+	DenyHosts []string
+	// AllowNetworks lists CIDR ranges that are allowed even though they are
+	// private, e.g. "10.1.0.0/16" for an internal service.
+	AllowNetworks []string
+	// DenyNetworks lists additional CIDR ranges that are denied. It takes
+	// precedence over AllowNetworks.
+	DenyNetworks []string
+	// AllowPrivate allows all private and loopback addresses.
+	AllowPrivate bool
+}
+
+// Policy decides whether outbound requests are allowed. It is safe for
+// concurrent use.
+type Policy struct {
+	schemes      map[string]bool
+	ports        map[int]bool
+	allowHosts   []string
+	denyHosts    []string
+	allow        []*net.IPNet
+	deny         []*net.IPNet
+	allowPrivate bool
+}
+
+// New creates a new Policy from the given configuration.
+func New(config Config) (*Policy, error) {
+	allow, err := parseCIDRs(config.AllowNetworks)
+	if err != nil {
+		return nil, err
+	}
+
+	deny, err := parseCIDRs(config.DenyNetworks)
+	if err != nil {
+		return nil, err
+	}
+
+	schemes := config.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+
+	policy := &Policy{
+		schemes:      make(map[string]bool, len(schemes)),
+		allowHosts:   normalizeHosts(config.AllowHosts),
+		denyHosts:    normalizeHosts(config.DenyHosts),
+		allow:        allow,
+		deny:         deny,
+		allowPrivate: config.AllowPrivate,
+	}
+
+	for _, scheme := range schemes {
+		policy.schemes[strings.ToLower(scheme)] = true
+	}
+
+	if len(config.Ports) > 0 {
+		policy.ports = make(map[int]bool, len(config.Ports))
+		for _, port := range config.Ports {
+			policy.ports[port] = true
+		}
+	}
+
+	return policy, nil
+}
+
+// CheckURL checks the scheme, host, and port of an outbound request URL.
+// If the host is an IP address, it is checked as well. Host names are
+// checked after resolution by the Dialer.
+func (p *Policy) CheckURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if !p.schemes[scheme] {
+		return &DeniedError{Destination: u.Scheme, Reason: "scheme not allowed"}
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(scheme)
+	}
+
+	if err := p.checkHostPort(u.Hostname(), port); err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		return p.CheckIP(ip)
+	}
+
+	return nil
+}
+
+// CheckIP checks a resolved destination IP address.
+func (p *Policy) CheckIP(ip net.IP) error {
+	if contains(p.deny, ip) {
+		return &DeniedError{Destination: ip.String(), Reason: "network denied"}
+	}
+
+	if !p.allowPrivate && contains(privateNetworks, ip) && !contains(p.allow, ip) {
+		return &DeniedError{Destination: ip.String(), Reason: "private address"}
+	}
+
+	return nil
+}
+
+// Dialer returns a copy of the dialer that checks the resolved destination
+// of each connection against the policy before connecting. If dialer is
+// nil, a zero net.Dialer is used.
+//
+// The Dialer only sees the address it connects to. If an HTTP proxy is
+// configured, e.g. via the HTTP_PROXY environment variable, that is the
+// address of the proxy rather than the origin, so only the proxy is checked.
+// In that case, the origin has to be checked using CheckURL, and the proxy
+// has to enforce the policy for resolved addresses itself.
+func (p *Policy) Dialer(dialer *net.Dialer) *net.Dialer {
+	var d net.Dialer
+	if dialer != nil {
+		d = *dialer
+	}
+
+	control := d.Control
+
+	d.Control = func(network, address string, c syscall.RawConn) error {
+		if err := p.checkAddress(address); err != nil {
+			return err
+		}
+		if control != nil {
+			return control(network, address, c)
+		}
+		return nil
+	}
+
+	return &d
+}
+
+// checkAddress checks a resolved address in host:port form.
+func (p *Policy) checkAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return &DeniedError{Destination: address, Reason: "unresolved address"}
+	}
+
+	if p.ports != nil {
+		if err := p.checkPort(port); err != nil {
+			return err
+		}
+	}
+
+	return p.CheckIP(ip)
+}
+
+func (p *Policy) checkHostPort(host, port string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if matchesAny(p.denyHosts, host) {
+		return &DeniedError{Destination: host, Reason: "host denied"}
+	}
+
+	if len(p.allowHosts) > 0 && !matchesAny(p.allowHosts, host) {
+		return &DeniedError{Destination: host, Reason: "host not allowed"}
+	}
+
+	return p.checkPort(port)
+}
+
+func (p *Policy) checkPort(port string) error {
+	if p.ports == nil {
+		return nil
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil || !p.ports[n] {
+		return &DeniedError{Destination: port, Reason: "port not allowed"}
+	}
+
+	return nil
+}
+
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "https", "wss":
+		return "443"
+	default:
+		return "80"
+	}
+}
+
+func normalizeHosts(hosts []string) []string {
+	normalized := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		normalized = append(normalized, strings.TrimSuffix(strings.ToLower(host), "."))
+	}
+	return normalized
+}
+
+func matchesAny(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if pattern == host {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks, err := parseCIDRs(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
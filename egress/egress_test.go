@@ -0,0 +1,130 @@
+package egress
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestPolicy_CheckURL(t *testing.T) {
+	policy, err := New(Config{
+		Ports:         []int{80, 443, 8443},
+		DenyHosts:     []string{"metadata.example.com"},
+		AllowNetworks: []string{"10.1.0.0/16"},
+		DenyNetworks:  []string{"203.0.113.0/24"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	testCases := map[string]struct {
+		url         string
+		expectedErr bool
+	}{
+		"public host":          {url: "https://example.com/"},
+		"public host and port": {url: "https://example.com:8443/"},
+		"denied port":          {url: "http://example.com:22/", expectedErr: true},
+		"denied scheme":        {url: "file:///etc/passwd", expectedErr: true},
+		"denied host":          {url: "http://Metadata.Example.com./", expectedErr: true},
+		"loopback":             {url: "http://127.0.0.1/", expectedErr: true},
+		"IPv4-mapped loopback": {url: "http://[::ffff:127.0.0.1]/", expectedErr: true},
+		"IPv6 loopback":        {url: "http://[::1]/", expectedErr: true},
+		"link-local metadata":  {url: "http://169.254.169.254/latest/", expectedErr: true},
+		"private":              {url: "http://192.168.1.1/", expectedErr: true},
+		"multicast":            {url: "http://224.0.0.1/", expectedErr: true},
+		"IPv6 multicast":       {url: "http://[ff02::1]/", expectedErr: true},
+		"reserved":             {url: "http://240.0.0.1/", expectedErr: true},
+		"broadcast":            {url: "http://255.255.255.255/", expectedErr: true},
+		"benchmarking":         {url: "http://198.18.0.1/", expectedErr: true},
+		"IETF protocol":        {url: "http://192.0.0.8/", expectedErr: true},
+		"NAT64 loopback":       {url: "http://[64:ff9b::7f00:1]/", expectedErr: true},
+		"IPv6 site-local":      {url: "http://[fec0::1]/", expectedErr: true},
+		"allowed private":      {url: "http://10.1.2.3/"},
+		"other private":        {url: "http://10.2.0.1/", expectedErr: true},
+		"denied network":       {url: "http://203.0.113.7/", expectedErr: true},
+	}
+
+	for name, tc := range testCases {
+		u, _ := url.Parse(tc.url)
+
+		err := policy.CheckURL(u)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedErr, err)
+		}
+
+		if err != nil && !errors.Is(err, ErrDenied) {
+			t.Errorf("'%s': expected error %v, got %v", name, ErrDenied, err)
+		}
+	}
+}
+
+func TestPolicy_AllowHosts(t *testing.T) {
+	policy, _ := New(Config{AllowHosts: []string{"api.example.com", "*.cdn.example.com"}})
+
+	testCases := map[string]struct {
+		url         string
+		expectedErr bool
+	}{
+		"allowed host":      {url: "https://api.example.com/"},
+		"allowed subdomain": {url: "https://a.b.cdn.example.com/"},
+		"wildcard parent":   {url: "https://cdn.example.com/", expectedErr: true},
+		"other host":        {url: "https://example.org/", expectedErr: true},
+	}
+
+	for name, tc := range testCases {
+		u, _ := url.Parse(tc.url)
+
+		if err := policy.CheckURL(u); (err != nil) != tc.expectedErr {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestPolicy_Dialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	denying, _ := New(Config{})
+	allowing, _ := New(Config{AllowNetworks: []string{"127.0.0.0/8"}})
+
+	testCases := map[string]struct {
+		policy      *Policy
+		address     string
+		expectedErr bool
+	}{
+		"loopback denied":      {policy: denying, address: "127.0.0.1:" + port, expectedErr: true},
+		"resolved name denied": {policy: denying, address: "localhost:" + port, expectedErr: true},
+		"loopback whitelisted": {policy: allowing, address: "127.0.0.1:" + port},
+	}
+
+	for name, tc := range testCases {
+		conn, err := tc.policy.Dialer(nil).Dial("tcp4", tc.address)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedErr, err)
+		}
+
+		if err != nil && !errors.Is(err, ErrDenied) {
+			t.Errorf("'%s': expected error %v, got %v", name, ErrDenied, err)
+		}
+
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"log"
 	"net"
+	"net/http"
 
 	"github.com/dominikbraun/gohttp"
 )
@@ -20,13 +22,49 @@ func main() {
 			panic(err)
 		}
 
-		go func() {
-			connReader := bufio.NewReader(conn)
-			request, err := gohttp.ParseRequest(connReader)
-			if err != nil {
-				panic(err)
-			}
-			fmt.Printf("%v\n", request)
-		}()
+		go handle(conn)
 	}
 }
+
+// handle parses a request from the connection. Neither a malformed request
+// nor a panic while handling it may bring down the accept loop.
+func handle(conn net.Conn) {
+	defer conn.Close()
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("recovered from panic: %v", r)
+			writeError(conn, http.StatusInternalServerError)
+		}
+	}()
+
+	connReader := bufio.NewReader(conn)
+	request, err := gohttp.ParseRequest(connReader)
+	if err != nil {
+		log.Printf("failed to parse request: %v", err)
+		writeError(conn, http.StatusBadRequest)
+		return
+	}
+
+	fmt.Printf("%v\n", request)
+}
+
+func writeError(conn net.Conn, statusCode int) {
+	response := &http.Response{
+		Proto:  "HTTP/1.1",
+		Status: fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		Header: http.Header{
+			"Content-Length": {"0"},
+			"Connection":     {"close"},
+		},
+		Body: http.NoBody,
+	}
+
+	serializedResponse, err := gohttp.SerializeResponse(response)
+	if err != nil {
+		log.Printf("failed to serialize response: %v", err)
+		return
+	}
+
+	_, _ = conn.Write(serializedResponse)
+}
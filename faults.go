@@ -0,0 +1,108 @@
+package gohttp
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Fault represents a protocol anomaly that can be injected deliberately when
+// serializing a message. Faults can be combined using a bitwise OR.
+type Fault uint
+
+const (
+	// FaultWrongContentLength sets a Content-Length header field that is one
+	// byte larger than the actual body length.
+	FaultWrongContentLength Fault = 1 << iota
+	// FaultTruncatedBody only writes the first half of the body while keeping
+	// the Content-Length of the full body. A chunked body is cut off after
+	// the chunk containing the first half, omitting the last chunk.
+	FaultTruncatedBody
+	// FaultDuplicateHeaders writes each header field twice.
+	FaultDuplicateHeaders
+	// FaultGarbageAfterFinalChunk appends garbage bytes after the body, i.e.
+	// after the final chunk if the message is chunked.
+	FaultGarbageAfterFinalChunk
+)
+
+// faultGarbage is the data appended by FaultGarbageAfterFinalChunk.
+const faultGarbage = "garbage\r\n"
+
+// WithFaults makes the serializer emit the given protocol anomalies. This is
+// meant for resilience testing of servers and proxies. Use with care!
+func WithFaults(faults Fault) Option {
+	return func(c *config) {
+		c.faults = faults
+	}
+}
+
+// writeFaultyMessage writes the header section and the body of a message just
+// like writeMessage does, including the chunked framing, but injects the given
+// faults.
+func writeFaultyMessage(headers, trailer http.Header, body []byte, chunked bool, faults Fault, buf *bytes.Buffer) error {
+	headers = headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+
+	truncated := faults&FaultTruncatedBody != 0
+
+	if truncated {
+		if headers.Get("Content-Length") == "" && headers.Get("Transfer-Encoding") == "" {
+			headers.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		body = body[:len(body)/2]
+	}
+
+	if faults&FaultWrongContentLength != 0 {
+		headers.Set("Content-Length", strconv.Itoa(len(body)+1))
+	}
+
+	var section bytes.Buffer
+
+	if err := writeHeaderFields(headers, &section); err != nil {
+		return err
+	}
+
+	if faults&FaultDuplicateHeaders != 0 {
+		buf.WriteString(duplicateHeaderLines(section.String()))
+	} else {
+		buf.Write(section.Bytes())
+	}
+
+	if chunked {
+		framed := &chunkedWriter{writer: buf}
+		_, _ = framed.Write(body)
+
+		if !truncated {
+			if err := framed.close(trailer); err != nil {
+				return err
+			}
+		}
+	} else {
+		buf.Write(body)
+	}
+
+	if faults&FaultGarbageAfterFinalChunk != 0 {
+		buf.WriteString(faultGarbage)
+	}
+
+	return nil
+}
+
+// duplicateHeaderLines repeats each header field line of a serialized header
+// section, keeping the empty line terminating the section intact.
+func duplicateHeaderLines(section string) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(section, "\r\n"), "\r\n")
+
+	var builder strings.Builder
+
+	for _, line := range lines {
+		builder.WriteString(line)
+		builder.WriteString(line)
+	}
+	builder.WriteString("\r\n")
+
+	return builder.String()
+}
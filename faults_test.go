@@ -0,0 +1,154 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSerializeResponseWithFaults(t *testing.T) {
+	testCases := map[string]struct {
+		faults        Fault
+		headers       http.Header
+		body          string
+		expected      string
+		expectedBody  string
+		expectedError bool
+		expectedRest  string
+	}{
+		"wrong content length": {
+			faults:  FaultWrongContentLength,
+			headers: http.Header{"Content-Length": {"5"}},
+			body:    "hello",
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 6\r\n" +
+				"\r\n" +
+				"hello",
+			expectedBody:  "hello",
+			expectedError: true,
+		},
+		"truncated body": {
+			faults: FaultTruncatedBody,
+			body:   "hello world!",
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 12\r\n" +
+				"\r\n" +
+				"hello ",
+			expectedBody:  "hello ",
+			expectedError: true,
+		},
+		"truncated chunked body": {
+			faults:  FaultTruncatedBody,
+			headers: http.Header{"Transfer-Encoding": {"chunked"}},
+			body:    "hello world!",
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"\r\n" +
+				"6\r\nhello \r\n",
+			expectedBody:  "hello ",
+			expectedError: true,
+		},
+		"duplicate headers": {
+			faults:  FaultDuplicateHeaders,
+			headers: http.Header{"Content-Length": {"5"}},
+			body:    "hello",
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 5\r\n" +
+				"Content-Length: 5\r\n" +
+				"\r\n" +
+				"hello",
+			expectedBody: "hello",
+		},
+		"duplicate chunked headers": {
+			faults:  FaultDuplicateHeaders,
+			headers: http.Header{"Transfer-Encoding": {"chunked"}},
+			body:    "hello",
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"\r\n" +
+				"5\r\nhello\r\n0\r\n\r\n",
+			expectedError: true,
+		},
+		"garbage after final chunk": {
+			faults:  FaultGarbageAfterFinalChunk,
+			headers: http.Header{"Transfer-Encoding": {"chunked"}},
+			body:    "hello",
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"\r\n" +
+				"5\r\nhello\r\n0\r\n\r\n" +
+				faultGarbage,
+			expectedBody: "hello",
+			expectedRest: faultGarbage,
+		},
+	}
+
+	for name, tc := range testCases {
+		response := &http.Response{
+			Proto:  "HTTP/1.1",
+			Status: "200 OK",
+			Header: tc.headers,
+			Body:   ioutil.NopCloser(strings.NewReader(tc.body)),
+		}
+
+		actual, err := SerializeResponse(response, WithFaults(tc.faults))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(actual) != tc.expected {
+			t.Errorf("'%s': expected response %q, got %q", name, tc.expected, string(actual))
+		}
+
+		// Parsing the faulty message must surface exactly the injected
+		// fault, and nothing else.
+		reader := bufio.NewReader(bytes.NewReader(actual))
+
+		var body []byte
+
+		parsed, err := ParseResponse(reader)
+		if err == nil {
+			body, err = ioutil.ReadAll(parsed.Body)
+		}
+
+		if (err != nil) != tc.expectedError {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if string(body) != tc.expectedBody {
+			t.Errorf("'%s': expected body %q, got %q", name, tc.expectedBody, string(body))
+		}
+
+		if rest, _ := ioutil.ReadAll(reader); !tc.expectedError && string(rest) != tc.expectedRest {
+			t.Errorf("'%s': expected remaining data %q, got %q", name, tc.expectedRest, string(rest))
+		}
+	}
+}
+
+func TestDuplicateHeaderLines(t *testing.T) {
+	testCases := map[string]struct {
+		section  string
+		expected string
+	}{
+		"single header field": {
+			section:  "Host: example.com\r\n\r\n",
+			expected: "Host: example.com\r\nHost: example.com\r\n\r\n",
+		},
+		"no header fields": {
+			section:  "\r\n",
+			expected: "\r\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		actual := duplicateHeaderLines(tc.section)
+
+		if actual != tc.expected {
+			t.Errorf("'%s': expected section %q, got %q", name, tc.expected, actual)
+		}
+	}
+}
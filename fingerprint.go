@@ -0,0 +1,135 @@
+package gohttp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// Target forms of a request target (RFC 7230, section 5.3.).
+const (
+	OriginForm    = "origin"
+	AbsoluteForm  = "absolute"
+	AuthorityForm = "authority"
+	AsteriskForm  = "asterisk"
+)
+
+// Fingerprint describes how a client has formatted a request, as opposed to
+// what it has requested. Different client implementations differ in header
+// field order, casing, and request line details, so a fingerprint allows to
+// tell clients apart even if they claim the same User-Agent, e.g. for bot
+// detection or analytics on captured traffic.
+type Fingerprint struct {
+	// Method is the request method.
+	Method string
+	// Proto is the protocol version of the request line.
+	Proto string
+	// TargetForm is the form of the request target, e.g. OriginForm.
+	TargetForm string
+	// EmptyLines is the number of empty lines preceding the request line.
+	EmptyLines int
+	// LFLineEndings indicates that the head contains LF line endings.
+	LFLineEndings bool
+	// HeaderNames are the header field names in the order and casing they
+	// have been sent, including fields removed by a header filter.
+	HeaderNames []string
+}
+
+// WithFingerprint records the fingerprint of a parsed request in
+// fingerprint. It has no effect on responses.
+func WithFingerprint(fingerprint *Fingerprint) Option {
+	return func(c *config) {
+		c.fingerprint = fingerprint
+	}
+}
+
+// String returns the canonical representation of the fingerprint, which is
+// stable across parser runs and suitable for hashing.
+func (f Fingerprint) String() string {
+	var b strings.Builder
+
+	b.WriteString(f.Method)
+	b.WriteByte('|')
+	b.WriteString(f.Proto)
+	b.WriteByte('|')
+	b.WriteString(f.TargetForm)
+	b.WriteByte('|')
+	b.WriteString(strconv.Itoa(f.EmptyLines))
+	b.WriteByte('|')
+
+	if f.LFLineEndings {
+		b.WriteString("lf")
+	} else {
+		b.WriteString("crlf")
+	}
+
+	b.WriteByte('|')
+	b.WriteString(strings.Join(f.HeaderNames, ","))
+
+	return b.String()
+}
+
+// Hash returns a short, stable hash of the fingerprint as 24 hexadecimal
+// characters.
+func (f Fingerprint) Hash() string {
+	sum := sha256.Sum256([]byte(f.String()))
+	return hex.EncodeToString(sum[:12])
+}
+
+// targetForm returns the form of a request target.
+func targetForm(method, target string) string {
+	switch {
+	case target == "*":
+		return AsteriskForm
+	case strings.HasPrefix(target, "/"):
+		return OriginForm
+	case method == "CONNECT":
+		return AuthorityForm
+	default:
+		return AbsoluteForm
+	}
+}
+
+func fingerprintEmptyLine(line string, config config) {
+	if config.fingerprint == nil {
+		return
+	}
+
+	config.fingerprint.EmptyLines++
+	fingerprintLineEnding(line, config)
+}
+
+func fingerprintRequestLine(line, method, protocol string, config config) {
+	if config.fingerprint == nil {
+		return
+	}
+
+	config.fingerprint.Method = method
+	config.fingerprint.Proto = protocol
+
+	if tokens := strings.Split(strings.TrimRight(line, "\r\n"), " "); len(tokens) == 3 {
+		config.fingerprint.TargetForm = targetForm(method, tokens[1])
+	}
+
+	fingerprintLineEnding(line, config)
+}
+
+func fingerprintHeaderField(line, name string, config config) {
+	if config.fingerprint == nil {
+		return
+	}
+
+	config.fingerprint.HeaderNames = append(config.fingerprint.HeaderNames, name)
+	fingerprintLineEnding(line, config)
+}
+
+func fingerprintLineEnding(line string, config config) {
+	if config.fingerprint == nil {
+		return
+	}
+
+	if strings.HasSuffix(line, "\n") && !strings.HasSuffix(line, "\r\n") {
+		config.fingerprint.LFLineEndings = true
+	}
+}
@@ -0,0 +1,112 @@
+package gohttp
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithFingerprint(t *testing.T) {
+	testCases := map[string]struct {
+		source   string
+		options  []Option
+		expected Fingerprint
+	}{
+		"origin form": {
+			source: "GET /index.html HTTP/1.1\r\nHost: example.com\r\nUser-Agent: curl/7.68.0\r\naccept: */*\r\n\r\n",
+			expected: Fingerprint{
+				Method:      "GET",
+				Proto:       "HTTP/1.1",
+				TargetForm:  OriginForm,
+				HeaderNames: []string{"Host", "User-Agent", "accept"},
+			},
+		},
+		"absolute form with empty line": {
+			source: "\r\nGET http://example.com/ HTTP/1.0\r\nHOST: example.com\r\n\r\n",
+			expected: Fingerprint{
+				Method:      "GET",
+				Proto:       "HTTP/1.0",
+				TargetForm:  AbsoluteForm,
+				EmptyLines:  1,
+				HeaderNames: []string{"HOST"},
+			},
+		},
+		"authority form with LF line endings": {
+			source:  "CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\n\n",
+			options: []Option{WithLFLineEndings(true)},
+			expected: Fingerprint{
+				Method:        "CONNECT",
+				Proto:         "HTTP/1.1",
+				TargetForm:    AuthorityForm,
+				LFLineEndings: true,
+				HeaderNames:   []string{"Host"},
+			},
+		},
+		"asterisk form": {
+			source: "OPTIONS * HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: Fingerprint{
+				Method:      "OPTIONS",
+				Proto:       "HTTP/1.1",
+				TargetForm:  AsteriskForm,
+				HeaderNames: []string{"Host"},
+			},
+		},
+		"filtered header fields": {
+			source:  "GET / HTTP/1.1\r\nHost: example.com\r\nX-Debug: 1\r\n\r\n",
+			options: []Option{WithDroppedHeaders("X-Debug")},
+			expected: Fingerprint{
+				Method:      "GET",
+				Proto:       "HTTP/1.1",
+				TargetForm:  OriginForm,
+				HeaderNames: []string{"Host", "X-Debug"},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		var fingerprint Fingerprint
+
+		options := append(tc.options, WithFingerprint(&fingerprint))
+		if _, err := ParseRequest(bufio.NewReader(strings.NewReader(tc.source)), options...); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if !reflect.DeepEqual(fingerprint, tc.expected) {
+			t.Errorf("'%s': expected fingerprint %+v, got %+v", name, tc.expected, fingerprint)
+		}
+	}
+}
+
+func TestFingerprint_Hash(t *testing.T) {
+	fingerprint := func(source string) string {
+		var fingerprint Fingerprint
+		_, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), WithFingerprint(&fingerprint))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		return fingerprint.Hash()
+	}
+
+	base := fingerprint("GET / HTTP/1.1\r\nHost: a.com\r\nAccept: */*\r\n\r\n")
+
+	testCases := map[string]struct {
+		source        string
+		expectedEqual bool
+	}{
+		"different values":  {source: "GET /other HTTP/1.1\r\nHost: b.com\r\nAccept: text/html\r\n\r\n", expectedEqual: true},
+		"different order":   {source: "GET / HTTP/1.1\r\nAccept: */*\r\nHost: a.com\r\n\r\n"},
+		"different casing":  {source: "GET / HTTP/1.1\r\nhost: a.com\r\nAccept: */*\r\n\r\n"},
+		"different version": {source: "GET / HTTP/1.0\r\nHost: a.com\r\nAccept: */*\r\n\r\n"},
+	}
+
+	for name, tc := range testCases {
+		if equal := fingerprint(tc.source) == base; equal != tc.expectedEqual {
+			t.Errorf("'%s': expected equal hashes %v, got %v", name, tc.expectedEqual, equal)
+		}
+	}
+
+	if len(base) != 24 {
+		t.Errorf("expected a hash of 24 characters, got %d", len(base))
+	}
+}
@@ -0,0 +1,341 @@
+package gohttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Hop represents a single proxy hop as described by the Forwarded header
+// field (RFC 7239) or the X-Forwarded-* header fields.
+type Hop struct {
+	// For identifies the node making the request to the proxy, e.g. an IP
+	// address, an IP address with a port, "unknown", or an obfuscated ID.
+	For string
+	// By identifies the interface the request came in to the proxy.
+	By string
+	// Host is the Host header field as received by the proxy.
+	Host string
+	// Proto is the protocol used to make the request, e.g. "https".
+	Proto string
+}
+
+// IP returns the IP address of the For node, or nil if the node isn't an IP
+// address.
+func (h Hop) IP() net.IP {
+	return nodeIP(h.For)
+}
+
+// String formats the hop as a Forwarded element.
+func (h Hop) String() string {
+	var pairs []string
+
+	if h.By != "" {
+		pairs = append(pairs, "by="+formatNode(h.By))
+	}
+	if h.For != "" {
+		pairs = append(pairs, "for="+formatNode(h.For))
+	}
+	if h.Host != "" {
+		pairs = append(pairs, "host="+quoteIfNeeded(h.Host))
+	}
+	if h.Proto != "" {
+		pairs = append(pairs, "proto="+quoteIfNeeded(h.Proto))
+	}
+
+	return strings.Join(pairs, ";")
+}
+
+// ForwardedHops returns the hops of a request, from the original client to
+// the most recent proxy. The Forwarded header field takes precedence over
+// the X-Forwarded-* header fields.
+func ForwardedHops(header http.Header) ([]Hop, error) {
+	if len(header.Values("Forwarded")) > 0 {
+		return ParseForwarded(header)
+	}
+	return ParseXForwarded(header), nil
+}
+
+// ParseForwarded parses all Forwarded header fields (RFC 7239, section 4.).
+func ParseForwarded(header http.Header) ([]Hop, error) {
+	var hops []Hop
+
+	for _, value := range header.Values("Forwarded") {
+		elements, err := splitQuoted(value, ',')
+		if err != nil {
+			return nil, err
+		}
+
+		for _, element := range elements {
+			hop, err := parseForwardedElement(element)
+			if err != nil {
+				return nil, err
+			}
+			hops = append(hops, hop)
+		}
+	}
+
+	return hops, nil
+}
+
+// ParseXForwarded parses the X-Forwarded-For header fields into hops. The
+// X-Forwarded-Proto and X-Forwarded-Host header fields describe the request
+// of the original client and are therefore assigned to the first hop.
+func ParseXForwarded(header http.Header) []Hop {
+	var hops []Hop
+
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, node := range strings.Split(value, ",") {
+			if node = strings.TrimSpace(node); node != "" {
+				hops = append(hops, Hop{For: node})
+			}
+		}
+	}
+
+	proto := firstListElement(header.Get("X-Forwarded-Proto"))
+	host := firstListElement(header.Get("X-Forwarded-Host"))
+
+	if len(hops) == 0 && (proto != "" || host != "") {
+		hops = append(hops, Hop{})
+	}
+
+	if len(hops) > 0 {
+		hops[0].Proto = proto
+		hops[0].Host = host
+	}
+
+	return hops
+}
+
+// AppendForwarded appends the given hop to the Forwarded and X-Forwarded-For
+// header fields.
+func AppendForwarded(header http.Header, hop Hop) {
+	appendListValue(header, "Forwarded", hop.String())
+
+	if hop.For != "" {
+		appendListValue(header, "X-Forwarded-For", nodeHost(hop.For))
+	}
+
+	if hop.Proto != "" && header.Get("X-Forwarded-Proto") == "" {
+		header.Set("X-Forwarded-Proto", hop.Proto)
+	}
+
+	if hop.Host != "" && header.Get("X-Forwarded-Host") == "" {
+		header.Set("X-Forwarded-Host", hop.Host)
+	}
+}
+
+// TrustPolicy decides which proxies are trusted to report forwarding
+// information truthfully.
+type TrustPolicy struct {
+	trusted []*net.IPNet
+}
+
+// NewTrustPolicy creates a TrustPolicy trusting all proxies within the given
+// CIDR ranges, e.g. "10.0.0.0/8".
+func NewTrustPolicy(cidrs ...string) (*TrustPolicy, error) {
+	policy := &TrustPolicy{}
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		policy.trusted = append(policy.trusted, network)
+	}
+
+	return policy, nil
+}
+
+// IsTrusted reports whether the given IP address belongs to a trusted proxy.
+func (t *TrustPolicy) IsTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range t.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the IP address of the real client. Starting with the
+// address of the direct peer, the hops are traversed from right to left as
+// long as they have been added by trusted proxies. The first address that
+// isn't trusted is the client.
+func (t *TrustPolicy) ClientIP(hops []Hop, remoteAddr string) net.IP {
+	ip := nodeIP(remoteAddr)
+
+	for i := len(hops) - 1; i >= 0 && t.IsTrusted(ip); i-- {
+		hopIP := hops[i].IP()
+		if hopIP == nil {
+			break
+		}
+		ip = hopIP
+	}
+
+	return ip
+}
+
+// Rewrite adds the given hop to the forwarding header fields of a request
+// received from remoteAddr. If the peer isn't trusted, any forwarding
+// information it sent is discarded first.
+func (t *TrustPolicy) Rewrite(header http.Header, remoteAddr string, hop Hop) {
+	if !t.IsTrusted(nodeIP(remoteAddr)) {
+		for _, name := range []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host"} {
+			header.Del(name)
+		}
+	}
+
+	AppendForwarded(header, hop)
+}
+
+func parseForwardedElement(element string) (Hop, error) {
+	var hop Hop
+
+	pairs, err := splitQuoted(element, ';')
+	if err != nil {
+		return Hop{}, err
+	}
+
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		tokens := strings.SplitN(pair, "=", 2)
+		if len(tokens) != 2 {
+			return Hop{}, errors.New("invalid forwarded pair syntax")
+		}
+
+		value, err := unquote(strings.TrimSpace(tokens[1]))
+		if err != nil {
+			return Hop{}, err
+		}
+
+		switch strings.ToLower(strings.TrimSpace(tokens[0])) {
+		case "for":
+			hop.For = value
+		case "by":
+			hop.By = value
+		case "host":
+			hop.Host = value
+		case "proto":
+			hop.Proto = value
+		}
+	}
+
+	return hop, nil
+}
+
+// splitQuoted splits s at each separator that is not within a quoted-string.
+func splitQuoted(s string, separator byte) ([]string, error) {
+	var parts []string
+	var quoted, escaped bool
+	var start int
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == separator:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	if quoted {
+		return nil, errors.New("unterminated quoted-string")
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+// unquote removes the quotes of a quoted-string (RFC 7230, section 3.2.6.).
+// Tokens are returned as they are.
+func unquote(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+
+	if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+		return "", errors.New("invalid quoted-string")
+	}
+
+	var builder strings.Builder
+
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		builder.WriteByte(s[i])
+	}
+
+	return builder.String(), nil
+}
+
+// quoteIfNeeded returns s as a quoted-string if it isn't a valid token.
+func quoteIfNeeded(s string) string {
+	for i := 0; i < len(s); i++ {
+		if !isTokenChar(s[i]) {
+			return quoteString(s)
+		}
+	}
+	return s
+}
+
+// quoteString formats s as a quoted-string, escaping double quotes and
+// backslashes (RFC 7230, section 3.2.6.).
+func quoteString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// formatNode formats a node identifier. IPv6 addresses are enclosed in
+// square brackets as required by RFC 7239, section 6.
+func formatNode(node string) string {
+	if ip := net.ParseIP(node); ip != nil && ip.To4() == nil {
+		node = "[" + node + "]"
+	}
+	return quoteIfNeeded(node)
+}
+
+// nodeHost returns the node without brackets and port.
+func nodeHost(node string) string {
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(node, "["), "]")
+}
+
+func nodeIP(node string) net.IP {
+	return net.ParseIP(nodeHost(node))
+}
+
+func firstListElement(value string) string {
+	return strings.TrimSpace(strings.SplitN(value, ",", 2)[0])
+}
+
+func appendListValue(header http.Header, name, value string) {
+	if existing := header.Get(name); existing != "" {
+		value = strings.Join(append(header.Values(name), value), ", ")
+	}
+	header.Set(name, value)
+}
+
+// isTokenChar reports whether c is a tchar (RFC 7230, section 3.2.6.).
+func isTokenChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
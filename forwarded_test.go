@@ -0,0 +1,174 @@
+package gohttp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestForwardedHops(t *testing.T) {
+	testCases := map[string]struct {
+		header   http.Header
+		expected []Hop
+	}{
+		"Forwarded with multiple elements": {
+			header: http.Header{
+				"Forwarded": {`for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"`},
+			},
+			expected: []Hop{
+				{For: "192.0.2.60", By: "203.0.113.43", Proto: "http"},
+				{For: "[2001:db8:cafe::17]:4711"},
+			},
+		},
+		"Forwarded with multiple fields": {
+			header: http.Header{
+				"Forwarded": {`For="_gazonk"`, `for=unknown;host="example.com"`},
+			},
+			expected: []Hop{
+				{For: "_gazonk"},
+				{For: "unknown", Host: "example.com"},
+			},
+		},
+		"X-Forwarded-*": {
+			header: http.Header{
+				"X-Forwarded-For":   {"203.0.113.195, 70.41.3.18", "150.172.238.178"},
+				"X-Forwarded-Proto": {"https"},
+				"X-Forwarded-Host":  {"example.com"},
+			},
+			expected: []Hop{
+				{For: "203.0.113.195", Proto: "https", Host: "example.com"},
+				{For: "70.41.3.18"},
+				{For: "150.172.238.178"},
+			},
+		},
+		"Forwarded takes precedence": {
+			header: http.Header{
+				"Forwarded":       {"for=192.0.2.60"},
+				"X-Forwarded-For": {"203.0.113.195"},
+			},
+			expected: []Hop{
+				{For: "192.0.2.60"},
+			},
+		},
+		"none": {
+			header: http.Header{},
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, err := ForwardedHops(tc.header)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("'%s': expected hops %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseForwarded_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"unterminated quoted-string": `for="192.0.2.60`,
+		"missing value":              "for",
+	}
+
+	for name, value := range testCases {
+		if _, err := ParseForwarded(http.Header{"Forwarded": {value}}); err == nil {
+			t.Errorf("'%s': expected an error, got none", name)
+		}
+	}
+}
+
+func TestHop_String(t *testing.T) {
+	testCases := map[string]struct {
+		hop      Hop
+		expected string
+	}{
+		"IPv4": {
+			hop:      Hop{For: "192.0.2.60", Proto: "https"},
+			expected: "for=192.0.2.60;proto=https",
+		},
+		"IPv6": {
+			hop:      Hop{For: "2001:db8:cafe::17", By: "10.0.0.1"},
+			expected: `by=10.0.0.1;for="[2001:db8:cafe::17]"`,
+		},
+		"host with port": {
+			hop:      Hop{Host: "example.com:8080"},
+			expected: `host="example.com:8080"`,
+		},
+	}
+
+	for name, tc := range testCases {
+		if actual := tc.hop.String(); actual != tc.expected {
+			t.Errorf("'%s': expected element %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestTrustPolicy_ClientIP(t *testing.T) {
+	policy, err := NewTrustPolicy("10.0.0.0/8", "192.168.0.0/16")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	hops := []Hop{{For: "198.51.100.1"}, {For: "203.0.113.7"}, {For: "10.0.0.2"}}
+
+	testCases := map[string]struct {
+		remoteAddr string
+		expected   string
+	}{
+		"trusted peer": {
+			remoteAddr: "192.168.1.1:51234",
+			expected:   "203.0.113.7",
+		},
+		"untrusted peer": {
+			remoteAddr: "198.51.100.99:51234",
+			expected:   "198.51.100.99",
+		},
+	}
+
+	for name, tc := range testCases {
+		if actual := policy.ClientIP(hops, tc.remoteAddr); actual.String() != tc.expected {
+			t.Errorf("'%s': expected client IP %s, got %s", name, tc.expected, actual.String())
+		}
+	}
+}
+
+func TestTrustPolicy_Rewrite(t *testing.T) {
+	policy, _ := NewTrustPolicy("10.0.0.0/8")
+
+	testCases := map[string]struct {
+		remoteAddr        string
+		expectedForwarded string
+		expectedFor       string
+	}{
+		"trusted peer": {
+			remoteAddr:        "10.0.0.5:4000",
+			expectedForwarded: "for=198.51.100.1, for=10.0.0.5",
+			expectedFor:       "198.51.100.1, 10.0.0.5",
+		},
+		"untrusted peer": {
+			remoteAddr:        "203.0.113.7:4000",
+			expectedForwarded: "for=203.0.113.7",
+			expectedFor:       "203.0.113.7",
+		},
+	}
+
+	for name, tc := range testCases {
+		header := http.Header{
+			"Forwarded":       {"for=198.51.100.1"},
+			"X-Forwarded-For": {"198.51.100.1"},
+		}
+
+		policy.Rewrite(header, tc.remoteAddr, Hop{For: nodeHost(tc.remoteAddr)})
+
+		if actual := header.Get("Forwarded"); actual != tc.expectedForwarded {
+			t.Errorf("'%s': expected Forwarded %s, got %s", name, tc.expectedForwarded, actual)
+		}
+
+		if actual := header.Get("X-Forwarded-For"); actual != tc.expectedFor {
+			t.Errorf("'%s': expected X-Forwarded-For %s, got %s", name, tc.expectedFor, actual)
+		}
+	}
+}
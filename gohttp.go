@@ -13,11 +13,35 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
-	"unicode"
 )
 
 type config struct {
-	allowLFLineEndings bool
+	allowLFLineEndings      bool
+	faults                  Fault
+	connInfo                *ConnInfo
+	chunkExtensionPolicy    ChunkExtensionPolicy
+	maxChunkExtensionLength int
+	chunkExtensionHandler   func(extensions []ChunkExtension) error
+	decodeTransferCodings   bool
+	requestMethod           string
+	bodyValidators          []bodyValidator
+	bufferPolicy            bufferPolicy
+	internTable             *InternTable
+	maxLineLength           int
+	readBufferSize          int
+	bareCRPolicy            ControlBytePolicy
+	nulPolicy               ControlBytePolicy
+	duplicatePolicy         DuplicatePolicy
+	headerDuplicatePolicies map[string]DuplicatePolicy
+	headerFilter            headerFilter
+	maxURILength            int
+	maxMethodLength         int
+	methodPolicy            MethodPolicy
+	allowedMethods          []string
+	sizes                   *Sizes
+	timing                  *MessageTiming
+	fingerprint             *Fingerprint
+	decompressionLimits     decompressionLimits
 }
 
 func newConfig(options ...Option) config {
@@ -41,35 +65,85 @@ func WithLFLineEndings(allow bool) Option {
 	}
 }
 
+// WithRequestMethod sets the method of the request a response is sent for.
+// Responses to HEAD requests are serialized with all header fields, including
+// a Content-Length computed from the body, but without the body itself (RFC
+// 7231, section 4.3.2.). Likewise, they are parsed without a body regardless
+// of their header fields.
+func WithRequestMethod(method string) Option {
+	return func(c *config) {
+		c.requestMethod = method
+	}
+}
+
 // ParseRequest reads a given source and parses an http.Request instance
 // from it.
 //
 // If the user allows LF line endings, the header fields and the empty
 // line terminating the header section may be LF instead of CRLF endings.
+//
+// The option WithConnInfo attaches metadata of the client connection to the
+// request context, which can be retrieved using ConnInfoFromContext.
+//
+// The body isn't read upfront but streamed from the source, so it has to be
+// consumed before parsing the next message from the same source. Reading a
+// body that is shorter than announced returns ErrWrongBodyLength.
+//
+// The option WithBodyValidator validates and buffers bodies of certain media
+// types during parsing instead. The option WithBodyBuffering buffers all
+// bodies, spilling large ones to temporary files, and sets GetBody so that
+// the request can be retransmitted.
 func ParseRequest(reader *bufio.Reader, options ...Option) (*http.Request, error) {
-	config := newConfig(options...)
+	return ParseRequestSource(NewBufioSource(reader), options...)
+}
+
+// ParseRequestSource works like ParseRequest, but reads from a Source.
+func ParseRequestSource(source Source, options ...Option) (*http.Request, error) {
+	return parseRequest(source, newConfig(options...))
+}
+
+func parseRequest(source Source, config config) (*http.Request, error) {
 	request := http.Request{}
 
+	if config.sizes != nil {
+		*config.sizes = Sizes{}
+	}
+
+	if config.fingerprint != nil {
+		*config.fingerprint = Fingerprint{}
+	}
+
+	startTiming(source, config)
+
 	// RFC 7230, section 3.5. states that a robust parser implementation
 	// should ignore at least one empty line prior to the request line.
 	for {
-		line, err := reader.ReadString('\n')
+		line, err := readHeadLine(source, config)
 		if err != nil {
 			return nil, err
 		}
 
 		if !isNewLine(line, config) {
-			method, targetUrl, protocol, err := parseRequestLine(line)
+			method, targetUrl, protocol, err := parseRequestLine(line, config)
 			if err != nil {
 				return nil, err
 			}
 
+			fingerprintRequestLine(line, method, protocol, config)
+
 			request.Method = method
 			request.URL = targetUrl
 			request.Proto = protocol
+			request.ProtoMajor, request.ProtoMinor, _ = http.ParseHTTPVersion(protocol)
+
+			if config.sizes != nil {
+				config.sizes.StartLine = int64(len(line))
+			}
 
 			break
 		}
+
+		fingerprintEmptyLine(line, config)
 	}
 
 	request.Header = make(http.Header)
@@ -77,16 +151,21 @@ func ParseRequest(reader *bufio.Reader, options ...Option) (*http.Request, error
 	var line string
 	var err error
 	for {
-		line, err = reader.ReadString('\n')
+		line, err = readHeadLine(source, config)
 		if err != nil && !errors.Is(err, io.EOF) {
 			return nil, err
 		}
 
+		if config.sizes != nil {
+			config.sizes.Header += int64(len(line))
+		}
+
 		if errors.Is(err, io.EOF) {
 			break
 		}
 
 		if isNewLine(line, config) {
+			fingerprintLineEnding(line, config)
 			break
 		}
 
@@ -95,56 +174,106 @@ func ParseRequest(reader *bufio.Reader, options ...Option) (*http.Request, error
 			return nil, err
 		}
 
-		request.Header.Add(fieldName, fieldValue)
+		fingerprintHeaderField(line, fieldName, config)
+
+		keep, err := filterHeaderField(fieldName, config)
+		if err != nil {
+			return nil, err
+		}
+
+		if keep {
+			addHeaderField(request.Header, fieldName, fieldValue, config)
+		}
 	}
 
 	if !isNewLine(line, config) {
 		return nil, errors.New("empty line after header section is missing")
 	}
 
-	length, err := determineBodyLength(request.Header, reader)
+	if err := applyDuplicatePolicies(request.Header, config); err != nil {
+		return nil, err
+	}
+
+	codings, err := parseTransferEncoding(request.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	// The body length of a request can only be determined if chunked is the
+	// final transfer coding (RFC 7230, section 3.3.3.).
+	if len(codings) > 0 && codings[len(codings)-1] != "chunked" {
+		return nil, ErrChunkedNotFinal
+	}
+
+	length, err := determineBodyLength(request.Header)
 	if err != nil {
 		return nil, err
 	}
 
-	if length > 0 {
-		var body = make([]byte, length)
-		n, err := reader.Read(body)
+	// A request without any framing header fields has no body at all, as
+	// stated by RFC 7230, section 3.3.3.
+	if length < 0 {
+		length = 0
+	}
+
+	request.ContentLength = contentLength(request.Header, length)
+	if config.sizes != nil {
+		source = &countingSource{Source: source, count: &config.sizes.Body}
+	}
+
+	request.Body = newBodyReader(source, length)
+
+	if len(codings) > 0 {
+		request.Trailer = make(http.Header)
+
+		request.Body, codings, err = newTransferReader(source, codings, config, request.Trailer)
 		if err != nil {
 			return nil, err
 		}
-		if n != length {
-			return nil, errors.New("wrong body length")
+
+		request.TransferEncoding = codings
+
+		if config.decodeTransferCodings {
+			setTransferEncoding(request.Header, codings)
 		}
 	}
 
+	request.Body = completeHeaders(request.Body, config)
+
+	if request.Body, err = validateBody(request.Header, request.Body, config); err != nil {
+		return nil, err
+	}
+
+	if request.Body, err = bufferBodyContent(request.Body, config); err != nil {
+		return nil, err
+	}
+
+	if config.bufferPolicy.enabled {
+		request.GetBody = getBody(request.Body)
+	}
+
+	if config.connInfo != nil {
+		return attachConnInfo(&request, config.connInfo), nil
+	}
+
 	return &request, nil
 }
 
 // SerializeRequest converts an http.Request instance into a byte slice.
 //
 // SerializeRequest uses CRLF line endings when serializing the request
-// instance, regardless whether the user allows LF line endings or not.
-func SerializeRequest(r *http.Request) ([]byte, error) {
+// instance, regardless whether the user allows LF line endings or not. The
+// option WithFaults deliberately injects protocol anomalies.
+func SerializeRequest(r *http.Request, options ...Option) ([]byte, error) {
+	config := newConfig(options...)
 	var buf bytes.Buffer
 
 	buf.WriteString(fmt.Sprintf("%s %s %s\r\n", r.Method, r.URL.String(), r.Proto))
 
-	if err := writeHeaderFields(r.Header, &buf); err != nil {
-		return nil, err
-	}
-
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
+	if err := writeMessage(r.Header, r.Trailer, r.Body, config, &buf); err != nil {
 		return nil, err
 	}
 
-	if len(body) == 0 {
-		return buf.Bytes(), nil
-	}
-
-	buf.Write(body)
-
 	return buf.Bytes(), nil
 }
 
@@ -152,12 +281,31 @@ func SerializeRequest(r *http.Request) ([]byte, error) {
 // from it.
 //
 // The option WithLFLineEndings allows the header fields and the empty line
-// terminating the header section to be LF instead of CRLF endings.
+// terminating the header section to be LF instead of CRLF endings. Just like
+// the request body, the response body is streamed from the source.
+//
+// 1xx, 204, and 304 responses never have a body, and neither do responses to
+// HEAD requests if the request method is passed using WithRequestMethod (RFC
+// 7230, section 3.3.3.).
 func ParseResponse(reader *bufio.Reader, options ...Option) (*http.Response, error) {
-	config := newConfig(options...)
+	return ParseResponseSource(NewBufioSource(reader), options...)
+}
+
+// ParseResponseSource works like ParseResponse, but reads from a Source.
+func ParseResponseSource(source Source, options ...Option) (*http.Response, error) {
+	return parseResponse(source, newConfig(options...))
+}
+
+func parseResponse(source Source, config config) (*http.Response, error) {
 	response := http.Response{}
 
-	line, err := reader.ReadString('\n')
+	if config.sizes != nil {
+		*config.sizes = Sizes{}
+	}
+
+	startTiming(source, config)
+
+	line, err := readHeadLine(source, config)
 	if err != nil {
 		return nil, err
 	}
@@ -168,17 +316,26 @@ func ParseResponse(reader *bufio.Reader, options ...Option) (*http.Response, err
 	}
 
 	response.Proto = protocol
+	response.ProtoMajor, response.ProtoMinor, _ = http.ParseHTTPVersion(protocol)
 	response.StatusCode = statusCode
 	response.Status = fmt.Sprintf("%d %s", statusCode, reasonPhrase)
 
+	if config.sizes != nil {
+		config.sizes.StartLine = int64(len(line))
+	}
+
 	response.Header = make(http.Header)
 
 	for {
-		line, err = reader.ReadString('\n')
+		line, err = readHeadLine(source, config)
 		if err != nil && !errors.Is(err, io.EOF) {
 			return nil, err
 		}
 
+		if config.sizes != nil {
+			config.sizes.Header += int64(len(line))
+		}
+
 		if errors.Is(err, io.EOF) {
 			break
 		}
@@ -192,60 +349,130 @@ func ParseResponse(reader *bufio.Reader, options ...Option) (*http.Response, err
 			return nil, err
 		}
 
-		response.Header.Add(fieldName, fieldValue)
+		keep, err := filterHeaderField(fieldName, config)
+		if err != nil {
+			return nil, err
+		}
+
+		if keep {
+			addHeaderField(response.Header, fieldName, fieldValue, config)
+		}
 	}
 
 	if !isNewLine(line, config) {
 		return nil, errors.New("empty line after header section is missing")
 	}
 
-	length, err := determineBodyLength(response.Header, reader)
+	if err := applyDuplicatePolicies(response.Header, config); err != nil {
+		return nil, err
+	}
+
+	codings, err := parseTransferEncoding(response.Header)
 	if err != nil {
 		return nil, err
 	}
 
-	if length > 0 {
-		var body = make([]byte, length)
-		n, err := reader.Read(body)
+	length, err := determineBodyLength(response.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	response.ContentLength = contentLength(response.Header, length)
+
+	if config.requestMethod == http.MethodHead || !bodyAllowedForStatus(statusCode) {
+		length = 0
+		codings = nil
+	}
+
+	if config.sizes != nil {
+		source = &countingSource{Source: source, count: &config.sizes.Body}
+	}
+
+	response.Body = newBodyReader(source, length)
+
+	if len(codings) > 0 {
+		response.Trailer = make(http.Header)
+
+		response.Body, codings, err = newTransferReader(source, codings, config, response.Trailer)
 		if err != nil {
 			return nil, err
 		}
-		if n != length {
-			return nil, errors.New("wrong body length")
+
+		response.TransferEncoding = codings
+
+		if config.decodeTransferCodings {
+			setTransferEncoding(response.Header, codings)
 		}
 	}
 
+	response.Body = completeHeaders(response.Body, config)
+
+	if response.Body, err = validateBody(response.Header, response.Body, config); err != nil {
+		return nil, err
+	}
+
+	if response.Body, err = bufferBodyContent(response.Body, config); err != nil {
+		return nil, err
+	}
+
 	return &response, nil
 }
 
 // SerializeResponse converts an http.Response instance into a byte slice.
 //
 // SerializeResponse uses CRLF line endings when serializing the response
-// instance, regardless whether the user allows LF line endings or not.
-func SerializeResponse(r *http.Response) ([]byte, error) {
+// instance, regardless whether the user allows LF line endings or not. The
+// option WithFaults deliberately injects protocol anomalies, and the option
+// WithRequestMethod suppresses the body of responses to HEAD requests.
+func SerializeResponse(r *http.Response, options ...Option) ([]byte, error) {
+	config := newConfig(options...)
 	var buf bytes.Buffer
 
 	buf.WriteString(fmt.Sprintf("%s %s\r\n", r.Proto, r.Status))
 
-	if err := writeHeaderFields(r.Header, &buf); err != nil {
+	if err := writeMessage(r.Header, r.Trailer, r.Body, config, &buf); err != nil {
 		return nil, err
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return nil, err
+	return buf.Bytes(), nil
+}
+
+// WriteResponse serializes an http.Response instance and streams it to the
+// given io.Writer. Unlike SerializeResponse, it doesn't buffer the body. A
+// chunked response is always terminated by the last chunk, unless it is the
+// response to a HEAD request.
+func WriteResponse(w io.Writer, r *http.Response) error {
+	if _, err := fmt.Fprintf(w, "%s %s\r\n", r.Proto, r.Status); err != nil {
+		return err
 	}
 
-	if len(body) == 0 {
-		return buf.Bytes(), nil
+	if err := writeHeaderFields(r.Header, w); err != nil {
+		return err
 	}
 
-	buf.Write(body)
+	if r.Request != nil && r.Request.Method == http.MethodHead {
+		return nil
+	}
 
-	return buf.Bytes(), nil
+	if isChunked(r.Header) {
+		chunked := &chunkedWriter{writer: w}
+		if hasBody(r.Body) {
+			if _, err := io.Copy(chunked, r.Body); err != nil {
+				return err
+			}
+		}
+		return chunked.close(r.Trailer)
+	}
+
+	if !hasBody(r.Body) {
+		return nil
+	}
+
+	_, err := io.Copy(w, r.Body)
+	return err
 }
 
-func parseRequestLine(line string) (string, *url.URL, string, error) {
+func parseRequestLine(line string, config config) (string, *url.URL, string, error) {
 	data := strings.Split(line, " ")
 
 	// RFC 7230, section 3.1.1. prescribes exactly 3 tokens.
@@ -255,7 +482,15 @@ func parseRequestLine(line string) (string, *url.URL, string, error) {
 
 	method := strings.TrimSuffix(data[0], "\n")
 	targetUrl := strings.TrimSuffix(data[1], "\n")
-	protocol := strings.TrimSuffix(data[2], "\n")
+	protocol := strings.TrimSuffix(strings.TrimSuffix(data[2], "\n"), "\r")
+
+	if err := checkRequestLineLimits(method, targetUrl, config); err != nil {
+		return "", nil, "", err
+	}
+
+	if err := checkMethod(method, config); err != nil {
+		return "", nil, "", err
+	}
 
 	parsedUrl, err := url.Parse(targetUrl)
 	if err != nil {
@@ -266,7 +501,8 @@ func parseRequestLine(line string) (string, *url.URL, string, error) {
 }
 
 func parseStatusLine(line string) (string, int, string, error) {
-	data := strings.Split(line, " ")
+	// The reason phrase may contain spaces itself (RFC 7230, section 3.1.2.).
+	data := strings.SplitN(line, " ", 3)
 
 	// RFC 7230, section 3.1.2. prescribes exactly 3 tokens.
 	if len(data) != 3 {
@@ -299,6 +535,69 @@ func parseHeaderField(line string) (string, string, error) {
 	return name, value, nil
 }
 
+// writeMessage writes the header section and the body of a message, which
+// is the part that requests and responses have in common. If chunked is the
+// final transfer coding, the body is framed as chunks followed by the
+// trailer fields, so that the de-chunked body of a parsed message is
+// serialized into a valid message again. The last chunk is written even if
+// there is no body, since the peer would wait for it otherwise.
+func writeMessage(headers, trailer http.Header, body io.Reader, config config, buf *bytes.Buffer) error {
+	var content []byte
+
+	if body != nil {
+		var err error
+		if content, err = ioutil.ReadAll(body); err != nil {
+			return err
+		}
+	}
+
+	if config.requestMethod == http.MethodHead {
+		headers, content = headResponse(headers, content), nil
+	}
+
+	chunked := config.requestMethod != http.MethodHead && isChunked(headers)
+
+	if config.faults != 0 {
+		return writeFaultyMessage(headers, trailer, content, chunked, config.faults, buf)
+	}
+
+	if err := writeHeaderFields(headers, buf); err != nil {
+		return err
+	}
+
+	if chunked {
+		chunked := &chunkedWriter{writer: buf}
+		_, _ = chunked.Write(content)
+		return chunked.close(trailer)
+	}
+
+	buf.Write(content)
+
+	return nil
+}
+
+// hasBody reports whether a body has been set, as opposed to nil or
+// http.NoBody.
+func hasBody(body io.Reader) bool {
+	return body != nil && body != http.NoBody
+}
+
+// headResponse returns the header fields of a response to a HEAD request,
+// which carry the Content-Length of the omitted body.
+func headResponse(headers http.Header, body []byte) http.Header {
+	if len(body) == 0 || headers.Get("Content-Length") != "" || headers.Get("Transfer-Encoding") != "" {
+		return headers
+	}
+
+	headers = headers.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	headers.Set("Content-Length", strconv.Itoa(len(body)))
+
+	return headers
+}
+
 func writeHeaderFields(headers http.Header, w io.Writer) error {
 	for fieldName, values := range headers {
 		var fieldValue string
@@ -325,28 +624,17 @@ func writeHeaderFields(headers http.Header, w io.Writer) error {
 	return nil
 }
 
-func determineBodyLength(headers http.Header, reader *bufio.Reader) (int, error) {
-
-	// If the Transfer-Encoding header is set, the length of the message
-	// chunk is contained within the body (RFC 7230, section 3.3.3.).
-	if transferEncoding := headers.Get("Transfer-Encoding"); transferEncoding != "" {
-		firstBodyLine, err := reader.ReadString('\n')
-		if err != nil {
-			return 0, err
-		}
-
-		// Parse the hex code as int.
-		// Use TrimRightFunc and not strings.TrimSpace to make sure the hex is at the beginning.
-		length, err := strconv.ParseInt(strings.TrimRightFunc(firstBodyLine, unicode.IsSpace), 16, 64)
-		if err != nil {
-			return 0, err
-		}
-
-		return int(length), nil
+// determineBodyLength returns the body length announced by the Content-Length
+// header field, or -1 if the length is unknown. If the Transfer-Encoding
+// header field is set, the length is determined by the transfer codings
+// instead (RFC 7230, section 3.3.3.).
+func determineBodyLength(headers http.Header) (int64, error) {
+	if hasTransferCodings(headers) {
+		return -1, nil
 	}
 
-	if contentLength := headers.Get("Content-Length"); contentLength != "" {
-		return strconv.Atoi(contentLength)
+	if values := headers.Values("Content-Length"); len(values) > 0 {
+		return parseContentLength(values)
 	}
 
 	return -1, nil
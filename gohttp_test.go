@@ -3,6 +3,7 @@ package gohttp
 import (
 	"bufio"
 	"bytes"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -79,6 +80,8 @@ func TestSerializeRequest(t *testing.T) {
 			expected: "GET / HTTP/1.1\r\n" +
 				"Host: example.com\r\n" +
 				"Transfer-Encoding: gzip, chunked\r\n" +
+				"\r\n" +
+				"0\r\n" +
 				"\r\n",
 		},
 	}
@@ -142,8 +145,99 @@ func TestParseResponse(t *testing.T) {
 	}
 }
 
+func TestParseResponse_Bodyless(t *testing.T) {
+	testCases := map[string]struct {
+		source string
+		method string
+		rest   string
+	}{
+		"HEAD request": {
+			source: "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n",
+			method: http.MethodHead,
+		},
+		"HEAD request with chunked coding": {
+			source: "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n",
+			method: http.MethodHead,
+		},
+		"100 Continue": {
+			source: "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 200 OK\r\n",
+			method: http.MethodPost,
+			rest:   "HTTP/1.1 200 OK\r\n",
+		},
+		"204 No Content": {
+			source: "HTTP/1.1 204 No Content\r\nContent-Length: 5\r\n\r\n",
+			method: http.MethodDelete,
+		},
+		"304 Not Modified": {
+			source: "HTTP/1.1 304 Not Modified\r\nContent-Length: 5\r\n\r\n",
+			method: http.MethodGet,
+		},
+	}
+
+	for name, tc := range testCases {
+		reader := bufio.NewReader(strings.NewReader(tc.source))
+
+		response, err := ParseResponse(reader, WithRequestMethod(tc.method))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil || len(body) != 0 {
+			t.Errorf("'%s': expected no body, got %q (%v)", name, string(body), err)
+		}
+
+		rest, _ := ioutil.ReadAll(reader)
+		if string(rest) != tc.rest {
+			t.Errorf("'%s': expected rest %q, got %q", name, tc.rest, string(rest))
+		}
+	}
+}
+
 func TestSerializeResponse(t *testing.T) {}
 
+func TestSerializeResponse_Head(t *testing.T) {
+	testCases := map[string]struct {
+		method   string
+		header   http.Header
+		expected string
+	}{
+		"GET request": {
+			method:   http.MethodGet,
+			header:   http.Header{"Content-Length": {"5"}},
+			expected: "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello",
+		},
+		"HEAD request": {
+			method:   http.MethodHead,
+			header:   http.Header{"Content-Length": {"5"}},
+			expected: "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n",
+		},
+		"HEAD request without Content-Length": {
+			method:   http.MethodHead,
+			header:   http.Header{},
+			expected: "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		response := &http.Response{
+			Proto:  "HTTP/1.1",
+			Status: "200 OK",
+			Header: tc.header,
+			Body:   ioutil.NopCloser(strings.NewReader("hello")),
+		}
+
+		actual, err := SerializeResponse(response, WithRequestMethod(tc.method))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(actual) != tc.expected {
+			t.Errorf("'%s': expected response %q, got %q", name, tc.expected, string(actual))
+		}
+	}
+}
+
 func TestParseRequestLine(t *testing.T) {
 	type requestLine struct {
 		method    string
@@ -166,7 +260,7 @@ func TestParseRequestLine(t *testing.T) {
 	}
 
 	for name, tc := range testCases {
-		actualMethod, actualURL, actualProtocol, err := parseRequestLine(tc.line)
+		actualMethod, actualURL, actualProtocol, err := parseRequestLine(tc.line, config{})
 		if err != nil {
 			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
 		}
@@ -296,13 +390,11 @@ func TestDetermineBodyLength(t *testing.T) {
 	testCases := map[string]struct {
 		transferEncoding string
 		contentLength    string
-		body             string
-		expected         int
+		expected         int64
 	}{
 		"transfer encoding": {
 			transferEncoding: "gzip, chunked",
-			body:             "400\r\n",
-			expected:         1024,
+			expected:         -1,
 		},
 		"content length": {
 			contentLength: "2048",
@@ -311,8 +403,7 @@ func TestDetermineBodyLength(t *testing.T) {
 		"transfer encoding and content length": {
 			transferEncoding: "gzip, chunked",
 			contentLength:    "2048",
-			body:             "400\r\n",
-			expected:         1024,
+			expected:         -1,
 		},
 		"none": {
 			expected: -1,
@@ -329,9 +420,7 @@ func TestDetermineBodyLength(t *testing.T) {
 			headers.Add("Content-Length", tc.contentLength)
 		}
 
-		reader := bufio.NewReader(strings.NewReader(tc.body))
-
-		actual, err := determineBodyLength(headers, reader)
+		actual, err := determineBodyLength(headers)
 		if err != nil {
 			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
 		}
@@ -0,0 +1,310 @@
+// Package graphql detects GraphQL requests among parsed HTTP requests and
+// extracts their operations, so that proxies and traffic inspection tools
+// can route, rate limit, or log them per operation.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxBodySize is the maximum size of a request body inspected by Parse.
+const maxBodySize = 1 << 20
+
+var (
+	// ErrNotGraphQL is returned by Parse if the request isn't a GraphQL
+	// request.
+	ErrNotGraphQL = errors.New("not a GraphQL request")
+	// ErrBodyTooLarge is returned by Parse if the request body exceeds 1 MiB.
+	ErrBodyTooLarge = errors.New("GraphQL request body too large")
+)
+
+// operationTypes are the keywords starting an operation definition.
+var operationTypes = []string{"query", "mutation", "subscription"}
+
+// parsedKey is the context key for the result of parsing a request.
+type parsedKey struct{}
+
+// parsed is the result of parsing a request, stored in its context.
+type parsed struct {
+	operations []Operation
+	err        error
+}
+
+// operationPattern matches the operation type and name of the first operation
+// definition in a document.
+var operationPattern = regexp.MustCompile(`^\s*(query|mutation|subscription)\b\s*([_A-Za-z][_0-9A-Za-z]*)?`)
+
+// Operation is a GraphQL operation sent with a request.
+type Operation struct {
+	// Type is "query", "mutation", or "subscription".
+	Type string
+	// Name is the operation name, or an empty string for anonymous
+	// operations.
+	Name string
+	// Query is the GraphQL document.
+	Query string
+	// Variables are the variables of the operation.
+	Variables map[string]interface{}
+}
+
+// payload is the JSON representation of a GraphQL request.
+type payload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Detect reports whether the request is a GraphQL request: a POST request
+// with the application/graphql media type or with a JSON body containing a
+// query field, or a GET request with a query parameter. The body is
+// restored after inspecting it.
+func Detect(r *http.Request) bool {
+	_, err := Parse(r)
+	return err == nil
+}
+
+// Parse extracts the operations of a GraphQL request. Batched requests with
+// a JSON array as body yield multiple operations. The body of the request is
+// buffered and restored, so that it can still be forwarded. Bodies larger
+// than 1 MiB aren't inspected and yield ErrBodyTooLarge.
+//
+// If the request has been returned by WithOperations, the operations parsed
+// back then are returned without reading the body again.
+func Parse(r *http.Request) ([]Operation, error) {
+	if result, ok := r.Context().Value(parsedKey{}).(parsed); ok {
+		return result.operations, result.err
+	}
+
+	return parse(r)
+}
+
+// WithOperations parses a request and returns a shallow copy of it carrying
+// the result in its context, so that subsequent calls of Parse, Detect, and
+// OperationKey as well as a Router don't read and parse the body again.
+func WithOperations(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(parsedKey{}).(parsed); ok {
+		return r
+	}
+
+	operations, err := parse(r)
+
+	return r.WithContext(context.WithValue(r.Context(), parsedKey{}, parsed{operations: operations, err: err}))
+}
+
+// Middleware returns an http.Handler parsing requests using WithOperations
+// before passing them to next, so that the body is parsed only once for all
+// subsequent handlers.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, WithOperations(r))
+	})
+}
+
+func parse(r *http.Request) ([]Operation, error) {
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		if query.Get("query") == "" {
+			return nil, ErrNotGraphQL
+		}
+
+		p := payload{Query: query.Get("query"), OperationName: query.Get("operationName")}
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &p.Variables); err != nil {
+				return nil, err
+			}
+		}
+
+		return []Operation{newOperation(p)}, nil
+	}
+
+	if r.Method != http.MethodPost || r.Body == nil {
+		return nil, ErrNotGraphQL
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, ErrNotGraphQL
+	}
+
+	if mediaType != "application/graphql" && mediaType != "application/json" {
+		return nil, ErrNotGraphQL
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > maxBodySize {
+		r.Body = prefixedBody{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+		return nil, ErrBodyTooLarge
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if mediaType == "application/graphql" {
+		return []Operation{newOperation(payload{Query: string(body)})}, nil
+	}
+
+	var payloads []payload
+
+	if trimmed := bytes.TrimSpace(body); bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(body, &payloads); err != nil {
+			return nil, ErrNotGraphQL
+		}
+	} else {
+		var p payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, ErrNotGraphQL
+		}
+		payloads = []payload{p}
+	}
+
+	operations := make([]Operation, 0, len(payloads))
+
+	for _, p := range payloads {
+		if p.Query == "" {
+			return nil, ErrNotGraphQL
+		}
+		operations = append(operations, newOperation(p))
+	}
+
+	if len(operations) == 0 {
+		return nil, ErrNotGraphQL
+	}
+
+	return operations, nil
+}
+
+// OperationKey returns a key identifying the operation of a GraphQL request
+// like "query:GetUser", or an empty string for other requests. It can be used
+// as a ratelimit.KeyFunc for per-operation rate limiting.
+func OperationKey(r *http.Request) string {
+	operations, err := Parse(r)
+	if err != nil {
+		return ""
+	}
+
+	return operations[0].Type + ":" + operations[0].Name
+}
+
+// Router is an http.Handler routing GraphQL requests to handlers by the name
+// of their first operation. Other requests and unknown operations are passed
+// to the default handler.
+type Router struct {
+	Operations map[string]http.Handler
+	Default    http.Handler
+}
+
+// ServeHTTP dispatches the request to the handler for its operation. The
+// handlers receive the request as returned by WithOperations.
+func (r Router) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	request = WithOperations(request)
+
+	if operations, err := Parse(request); err == nil {
+		if handler, ok := r.Operations[operations[0].Name]; ok {
+			handler.ServeHTTP(w, request)
+			return
+		}
+	}
+
+	if r.Default == nil {
+		http.NotFound(w, request)
+		return
+	}
+
+	r.Default.ServeHTTP(w, request)
+}
+
+// newOperation determines the operation type and name of a payload. If the
+// operation name is given explicitly, the operation with that name is used.
+func newOperation(p payload) Operation {
+	operation := Operation{
+		Type:      "query",
+		Name:      p.OperationName,
+		Query:     p.Query,
+		Variables: p.Variables,
+	}
+
+	document := stripComments(p.Query)
+
+	if p.OperationName != "" {
+		if operationType, ok := namedOperationType(document, p.OperationName); ok {
+			operation.Type = operationType
+		}
+		return operation
+	}
+
+	if match := operationPattern.FindStringSubmatch(document); match != nil {
+		operation.Type = match[1]
+		operation.Name = match[2]
+	}
+
+	return operation
+}
+
+// namedOperationType returns the type of the operation definition with the
+// given name, i.e. the operation type keyword followed by whitespace and the
+// name as whole words.
+func namedOperationType(document, name string) (string, bool) {
+	for offset := 0; ; {
+		i := strings.Index(document[offset:], name)
+		if i < 0 {
+			return "", false
+		}
+
+		start := offset + i
+		end := start + len(name)
+		offset = start + 1
+
+		if end < len(document) && isNameChar(document[end]) {
+			continue
+		}
+
+		before := strings.TrimRight(document[:start], " \t\n\f\r")
+		if len(before) == start {
+			continue
+		}
+
+		for _, operationType := range operationTypes {
+			keyword := len(before) - len(operationType)
+			if strings.HasSuffix(before, operationType) && (keyword == 0 || !isNameChar(before[keyword-1])) {
+				return operationType, true
+			}
+		}
+	}
+}
+
+// isNameChar reports whether c may be part of a GraphQL name.
+func isNameChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// prefixedBody is a body whose beginning has been read already.
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// stripComments removes comments from a GraphQL document. Strings are not
+// considered, which is sufficient for finding the operation definition.
+func stripComments(document string) string {
+	lines := strings.Split(document, "\n")
+
+	for i, line := range lines {
+		if j := strings.IndexByte(line, '#'); j >= 0 {
+			lines[i] = line[:j]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,168 @@
+package graphql
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		method       string
+		target       string
+		contentType  string
+		body         string
+		expectedType string
+		expectedName string
+		expectedErr  error
+	}{
+		"JSON query": {
+			method:       http.MethodPost,
+			target:       "/graphql",
+			contentType:  "application/json",
+			body:         `{"query": "query GetUser($id: ID!) { user(id: $id) { name } }", "variables": {"id": "1"}}`,
+			expectedType: "query",
+			expectedName: "GetUser",
+		},
+		"JSON mutation with operationName": {
+			method:       http.MethodPost,
+			target:       "/graphql",
+			contentType:  "application/json; charset=utf-8",
+			body:         `{"query": "query A { a } mutation B { b }", "operationName": "B"}`,
+			expectedType: "mutation",
+			expectedName: "B",
+		},
+		"operationName as part of another name": {
+			method:       http.MethodPost,
+			target:       "/graphql",
+			contentType:  "application/json",
+			body:         `{"query": "query AB { a } query BC { b } mutation\n\tB { b }", "operationName": "B"}`,
+			expectedType: "mutation",
+			expectedName: "B",
+		},
+		"application/graphql": {
+			method:       http.MethodPost,
+			target:       "/graphql",
+			contentType:  "application/graphql",
+			body:         "# comment\nsubscription OnEvent { event }",
+			expectedType: "subscription",
+			expectedName: "OnEvent",
+		},
+		"anonymous query": {
+			method:       http.MethodGet,
+			target:       "/graphql?query=%7B%20me%20%7B%20id%20%7D%20%7D",
+			expectedType: "query",
+		},
+		"plain JSON": {
+			method:      http.MethodPost,
+			target:      "/api",
+			contentType: "application/json",
+			body:        `{"name": "gohttp"}`,
+			expectedErr: ErrNotGraphQL,
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			request.Header.Set("Content-Type", tc.contentType)
+		}
+
+		operations, err := Parse(request)
+		if err != tc.expectedErr {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedErr, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if operations[0].Type != tc.expectedType || operations[0].Name != tc.expectedName {
+			t.Errorf("'%s': expected operation %s %s, got %s %s", name, tc.expectedType, tc.expectedName, operations[0].Type, operations[0].Name)
+		}
+
+		if body, _ := ioutil.ReadAll(request.Body); string(body) != tc.body && tc.method == http.MethodPost {
+			t.Errorf("'%s': expected body to be restored, got %s", name, string(body))
+		}
+	}
+}
+
+func TestParse_LargeBody(t *testing.T) {
+	body := `{"query": "` + strings.Repeat(" ", maxBodySize) + `{ me }"}`
+
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+
+	if _, err := Parse(request); err != ErrBodyTooLarge {
+		t.Errorf("expected error %v, got %v", ErrBodyTooLarge, err)
+	}
+
+	if restored, _ := ioutil.ReadAll(request.Body); string(restored) != body {
+		t.Errorf("expected body of size %d to be restored, got %d bytes", len(body), len(restored))
+	}
+}
+
+func TestWithOperations(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query": "query GetUser { user }"}`))
+	request.Header.Set("Content-Type", "application/json")
+
+	request = WithOperations(request)
+
+	// The body is replaced, so that parsing it again would fail.
+	request.Body = ioutil.NopCloser(strings.NewReader("invalid"))
+
+	if key := OperationKey(request); key != "query:GetUser" {
+		t.Errorf("expected operation key %s, got %s", "query:GetUser", key)
+	}
+
+	if WithOperations(request) != request {
+		t.Errorf("expected a parsed request to be returned as it is")
+	}
+}
+
+func TestRouter(t *testing.T) {
+	var routed string
+
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			routed = name
+		})
+	}
+
+	router := Router{
+		Operations: map[string]http.Handler{"GetUser": handler("users")},
+		Default:    handler("default"),
+	}
+
+	testCases := map[string]struct {
+		body     string
+		expected string
+	}{
+		"known operation": {
+			body:     `{"query": "query GetUser { user { name } }"}`,
+			expected: "users",
+		},
+		"unknown operation": {
+			body:     `{"query": "query Other { other }"}`,
+			expected: "default",
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tc.body))
+		request.Header.Set("Content-Type", "application/json")
+
+		router.ServeHTTP(httptest.NewRecorder(), request)
+
+		if routed != tc.expected {
+			t.Errorf("'%s': expected handler %s, got %s", name, tc.expected, routed)
+		}
+
+		if key := OperationKey(request); !strings.HasPrefix(key, "query:") {
+			t.Errorf("'%s': expected a query operation key, got %s", name, key)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package gohttp
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrBareCR is returned for a CR that isn't part of a line ending in the
+	// message head, unless allowed with WithBareCRPolicy.
+	ErrBareCR = errors.New("bare CR in message head")
+	// ErrNULByte is returned for a NUL byte in the message head, unless
+	// allowed with WithNULPolicy.
+	ErrNULByte = errors.New("NUL byte in message head")
+)
+
+// ControlBytePolicy determines how the parser treats a control byte that is
+// interpreted differently by different implementations, making it a vector
+// for parser differential attacks.
+type ControlBytePolicy int
+
+const (
+	// ControlBytesReject fails parsing with ErrBareCR or ErrNULByte.
+	ControlBytesReject ControlBytePolicy = iota
+	// ControlBytesReplace replaces the byte with a space (RFC 7230, section
+	// 3.5. permits this for bare CRs).
+	ControlBytesReplace
+	// ControlBytesAllow keeps the byte as it is.
+	ControlBytesAllow
+)
+
+// WithBareCRPolicy sets the policy for CR bytes in the message head that
+// aren't followed by LF. By default, they are rejected.
+func WithBareCRPolicy(policy ControlBytePolicy) Option {
+	return func(c *config) {
+		c.bareCRPolicy = policy
+	}
+}
+
+// WithNULPolicy sets the policy for NUL bytes in the message head. By
+// default, they are rejected.
+func WithNULPolicy(policy ControlBytePolicy) Option {
+	return func(c *config) {
+		c.nulPolicy = policy
+	}
+}
+
+// checkControlBytes applies the control byte policies to a line of the
+// message head, excluding its line ending.
+func checkControlBytes(line string, config config) (string, error) {
+	if !strings.ContainsAny(line, "\r\x00") {
+		return line, nil
+	}
+
+	content := strings.TrimSuffix(line, "\n")
+	content = strings.TrimSuffix(content, "\r")
+	ending := line[len(content):]
+
+	var err error
+
+	if content, err = applyControlBytePolicy(content, "\r", config.bareCRPolicy, ErrBareCR); err != nil {
+		return "", err
+	}
+
+	if content, err = applyControlBytePolicy(content, "\x00", config.nulPolicy, ErrNULByte); err != nil {
+		return "", err
+	}
+
+	return content + ending, nil
+}
+
+func applyControlBytePolicy(content, b string, policy ControlBytePolicy, err error) (string, error) {
+	if !strings.Contains(content, b) {
+		return content, nil
+	}
+
+	switch policy {
+	case ControlBytesReplace:
+		return strings.ReplaceAll(content, b, " "), nil
+	case ControlBytesAllow:
+		return content, nil
+	default:
+		return "", err
+	}
+}
+
+// readHeadLine reads a line of the message head and applies the control byte
+// policies to it.
+func readHeadLine(source Source, config config) (string, error) {
+	line, err := readLine(source, config)
+	if err != nil {
+		return line, err
+	}
+
+	return checkControlBytes(line, config)
+}
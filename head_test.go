@@ -0,0 +1,63 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestControlBytePolicies(t *testing.T) {
+	testCases := map[string]struct {
+		field         string
+		options       []Option
+		expectedValue string
+		expectedError error
+	}{
+		"bare CR rejected by default": {
+			field:         "X-Test: a\rb",
+			expectedError: ErrBareCR,
+		},
+		"bare CR replaced": {
+			field:         "X-Test: a\rb",
+			options:       []Option{WithBareCRPolicy(ControlBytesReplace)},
+			expectedValue: "a b",
+		},
+		"bare CR allowed": {
+			field:         "X-Test: a\rb",
+			options:       []Option{WithBareCRPolicy(ControlBytesAllow)},
+			expectedValue: "a\rb",
+		},
+		"NUL rejected by default": {
+			field:         "X-Test: a\x00b",
+			expectedError: ErrNULByte,
+		},
+		"NUL replaced": {
+			field:         "X-Test: a\x00b",
+			options:       []Option{WithNULPolicy(ControlBytesReplace)},
+			expectedValue: "a b",
+		},
+		"NUL allowed with bare CR rejected": {
+			field:         "X-Test: a\x00\rb",
+			options:       []Option{WithNULPolicy(ControlBytesAllow)},
+			expectedError: ErrBareCR,
+		},
+		"no control bytes": {
+			field:         "X-Test: ab",
+			expectedValue: "ab",
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "GET / HTTP/1.1\r\n" + tc.field + "\r\n\r\n"
+
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), tc.options...)
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if err == nil && request.Header.Get("X-Test") != tc.expectedValue {
+			t.Errorf("'%s': expected value %q, got %q", name, tc.expectedValue, request.Header.Get("X-Test"))
+		}
+	}
+}
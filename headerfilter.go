@@ -0,0 +1,113 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrHeaderRejected indicates a header field whose name is rejected by the
+// parser.
+var ErrHeaderRejected = errors.New("header field not allowed")
+
+// HeaderRejectedError is returned by the parser for a header field rejected
+// by WithRejectedHeaders. It wraps ErrHeaderRejected.
+type HeaderRejectedError struct {
+	// Name is the name of the rejected header field as received.
+	Name string
+}
+
+func (h *HeaderRejectedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrHeaderRejected.Error(), h.Name)
+}
+
+func (h *HeaderRejectedError) Unwrap() error {
+	return ErrHeaderRejected
+}
+
+// StatusCode returns the status code for responding to a request with a
+// rejected header field, which is 400.
+func (h *HeaderRejectedError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+type headerFilter struct {
+	allowed  []string
+	dropped  []string
+	rejected []string
+}
+
+// WithAllowedHeaders only keeps header fields matching one of the patterns
+// and drops all others during parsing. A pattern is a field name, matched
+// case-insensitively, that may end with "*" to match a prefix.
+//
+// The framing header fields Content-Length and Transfer-Encoding are never
+// dropped, since the body length couldn't be determined without them and
+// the rest of the body would be parsed as the next message.
+func WithAllowedHeaders(patterns ...string) Option {
+	return func(c *config) {
+		c.headerFilter.allowed = append(c.headerFilter.allowed, patterns...)
+	}
+}
+
+// WithDroppedHeaders drops header fields matching one of the patterns during
+// parsing, e.g. "X-Internal-*" for requests from untrusted clients. See
+// WithAllowedHeaders for the pattern syntax.
+func WithDroppedHeaders(patterns ...string) Option {
+	return func(c *config) {
+		c.headerFilter.dropped = append(c.headerFilter.dropped, patterns...)
+	}
+}
+
+// WithRejectedHeaders fails parsing with a HeaderRejectedError if a header
+// field matches one of the patterns. See WithAllowedHeaders for the pattern
+// syntax.
+func WithRejectedHeaders(patterns ...string) Option {
+	return func(c *config) {
+		c.headerFilter.rejected = append(c.headerFilter.rejected, patterns...)
+	}
+}
+
+// filterHeaderField reports whether a parsed header field should be kept.
+// Rejecting takes precedence over dropping and allowing, and framing header
+// fields are always kept unless rejected.
+func filterHeaderField(name string, config config) (bool, error) {
+	filter := config.headerFilter
+
+	if matchesAnyPattern(name, filter.rejected) {
+		return false, &HeaderRejectedError{Name: name}
+	}
+
+	if strings.EqualFold(name, "Content-Length") || strings.EqualFold(name, "Transfer-Encoding") {
+		return true, nil
+	}
+
+	if matchesAnyPattern(name, filter.dropped) {
+		return false, nil
+	}
+
+	if len(filter.allowed) > 0 && !matchesAnyPattern(name, filter.allowed) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func matchesAnyPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "*") {
+			prefix := pattern[:len(pattern)-1]
+			if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+				return true
+			}
+			continue
+		}
+
+		if strings.EqualFold(name, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestHeaderFilters(t *testing.T) {
+	source := "GET / HTTP/1.1\r\nHost: example.com\r\nx-internal-user: admin\r\nAccept: */*\r\n\r\n"
+
+	testCases := map[string]struct {
+		options       []Option
+		expectedNames []string
+		expectedError error
+	}{
+		"no filters": {
+			expectedNames: []string{"Accept", "Host", "X-Internal-User"},
+		},
+		"dropped by prefix": {
+			options:       []Option{WithDroppedHeaders("X-Internal-*")},
+			expectedNames: []string{"Accept", "Host"},
+		},
+		"rejected": {
+			options:       []Option{WithRejectedHeaders("x-internal-user")},
+			expectedError: ErrHeaderRejected,
+		},
+		"allowed": {
+			options:       []Option{WithAllowedHeaders("Host")},
+			expectedNames: []string{"Host"},
+		},
+		"reject takes precedence": {
+			options:       []Option{WithAllowedHeaders("*"), WithDroppedHeaders("X-*"), WithRejectedHeaders("X-*")},
+			expectedError: ErrHeaderRejected,
+		},
+	}
+
+	for name, tc := range testCases {
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), tc.options...)
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		var names []string
+		for fieldName := range request.Header {
+			names = append(names, fieldName)
+		}
+		sort.Strings(names)
+
+		if !reflect.DeepEqual(names, tc.expectedNames) {
+			t.Errorf("'%s': expected header fields %v, got %v", name, tc.expectedNames, names)
+		}
+	}
+}
+
+func TestHeaderFilters_Framing(t *testing.T) {
+	smuggled := "GET /admin HTTP/1.1\r\nHost: a\r\n\r\n"
+
+	testCases := map[string]struct {
+		source  string
+		options []Option
+	}{
+		"content length not allowed": {
+			source:  "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 32\r\n\r\n" + smuggled,
+			options: []Option{WithAllowedHeaders("Host")},
+		},
+		"content length dropped": {
+			source:  "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 32\r\n\r\n" + smuggled,
+			options: []Option{WithDroppedHeaders("Content-*")},
+		},
+		"transfer encoding dropped": {
+			source:  "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n\r\n20\r\n" + smuggled + "\r\n0\r\n\r\n",
+			options: []Option{WithDroppedHeaders("Transfer-Encoding")},
+		},
+	}
+
+	for name, tc := range testCases {
+		reader := bufio.NewReader(strings.NewReader(tc.source + "GET /next HTTP/1.1\r\nHost: a\r\n\r\n"))
+
+		request, err := ParseRequest(reader, tc.options...)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(body) != smuggled {
+			t.Errorf("'%s': expected body %q, got %q", name, smuggled, string(body))
+		}
+
+		next, err := ParseRequest(reader, tc.options...)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if next.URL.Path != "/next" {
+			t.Errorf("'%s': expected next request %s, got %s", name, "/next", next.URL.Path)
+		}
+	}
+}
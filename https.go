@@ -0,0 +1,99 @@
+package gohttp
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPSRedirect creates a 301 Moved Permanently response redirecting a
+// plaintext request to the https scheme. The host and the target of the
+// request are preserved, only a port is removed. Requests without a host
+// can't be redirected and get a 400 Bad Request response instead.
+func HTTPSRedirect(r *http.Request) *http.Response {
+	location, ok := httpsLocation(r)
+	if !ok {
+		return NewResponse(http.StatusBadRequest, nil)
+	}
+
+	response := NewResponse(http.StatusMovedPermanently, nil)
+	response.Header.Set("Location", location)
+
+	return response
+}
+
+// HTTPSRedirectHandler returns an http.Handler answering all requests with a
+// redirect to the https scheme, just like HTTPSRedirect.
+func HTTPSRedirectHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		location, ok := httpsLocation(r)
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusMovedPermanently)
+	})
+}
+
+// httpsLocation returns the https URL of a request. It returns false if the
+// request has no host.
+func httpsLocation(r *http.Request) (string, bool) {
+	host := RequestHost(r)
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+	}
+
+	if host == "" {
+		return "", false
+	}
+
+	return "https://" + host + r.URL.RequestURI(), true
+}
+
+// HSTS represents a Strict-Transport-Security policy (RFC 6797).
+type HSTS struct {
+	// MaxAge is the time the browser should only use HTTPS.
+	MaxAge time.Duration
+	// IncludeSubDomains applies the policy to all subdomains.
+	IncludeSubDomains bool
+	// Preload signals consent to be included in browser preload lists.
+	Preload bool
+}
+
+// String returns the Strict-Transport-Security header field value.
+func (h HSTS) String() string {
+	value := "max-age=" + strconv.FormatInt(int64(h.MaxAge/time.Second), 10)
+
+	if h.IncludeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if h.Preload {
+		value += "; preload"
+	}
+
+	return value
+}
+
+// Apply sets the Strict-Transport-Security header field. Browsers ignore
+// the header field on plaintext responses, so it should only be applied to
+// responses sent over HTTPS.
+func (h HSTS) Apply(header http.Header) {
+	header.Set("Strict-Transport-Security", h.String())
+}
+
+// Middleware returns an http.Handler stamping the Strict-Transport-Security
+// header field on all responses of next.
+func (h HSTS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.Apply(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
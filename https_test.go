@@ -0,0 +1,106 @@
+package gohttp
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	testCases := map[string]struct {
+		source         string
+		expected       string
+		expectedStatus int
+	}{
+		"origin-form": {
+			source:   "GET /path?q=1 HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: "https://example.com/path?q=1",
+		},
+		"missing host": {
+			source:         "GET /path HTTP/1.0\r\n\r\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"empty host": {
+			source:         "GET /path HTTP/1.1\r\nHost: \r\n\r\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"port only": {
+			source:         "GET /path HTTP/1.1\r\nHost: :80\r\n\r\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"host with port": {
+			source:   "GET / HTTP/1.1\r\nHost: example.com:80\r\n\r\n",
+			expected: "https://example.com/",
+		},
+		"IPv6 host with port": {
+			source:   "GET / HTTP/1.1\r\nHost: [::1]:80\r\n\r\n",
+			expected: "https://[::1]/",
+		},
+		"absolute-form": {
+			source:   "GET http://www.example.com/a HTTP/1.1\r\nHost: other.org\r\n\r\n",
+			expected: "https://www.example.com/a",
+		},
+	}
+
+	for name, tc := range testCases {
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(tc.source)))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		expectedStatus := tc.expectedStatus
+		if expectedStatus == 0 {
+			expectedStatus = http.StatusMovedPermanently
+		}
+
+		response := HTTPSRedirect(request)
+
+		if response.StatusCode != expectedStatus {
+			t.Errorf("'%s': expected status code %d, got %d", name, expectedStatus, response.StatusCode)
+		}
+
+		if actual := response.Header.Get("Location"); actual != tc.expected {
+			t.Errorf("'%s': expected location %s, got %s", name, tc.expected, actual)
+		}
+
+		recorder := httptest.NewRecorder()
+		HTTPSRedirectHandler().ServeHTTP(recorder, request)
+
+		if recorder.Code != expectedStatus {
+			t.Errorf("'%s': expected handler status code %d, got %d", name, expectedStatus, recorder.Code)
+		}
+
+		if actual := recorder.Header().Get("Location"); actual != tc.expected {
+			t.Errorf("'%s': expected handler location %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	testCases := map[string]struct {
+		hsts     HSTS
+		expected string
+	}{
+		"max age only": {
+			hsts:     HSTS{MaxAge: time.Hour},
+			expected: "max-age=3600",
+		},
+		"all directives": {
+			hsts:     HSTS{MaxAge: 365 * 24 * time.Hour, IncludeSubDomains: true, Preload: true},
+			expected: "max-age=31536000; includeSubDomains; preload",
+		},
+	}
+
+	for name, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		handler := tc.hsts.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+		if actual := recorder.Header().Get("Strict-Transport-Security"); actual != tc.expected {
+			t.Errorf("'%s': expected header value %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package gohttp
+
+import (
+	"net/http"
+	"net/textproto"
+)
+
+// InternTable is a set of strings that parsed messages share instead of
+// allocating their own copies, e.g. frequent header field names and values.
+// An InternTable is immutable and safe for concurrent use.
+type InternTable struct {
+	strings map[string]string
+}
+
+// NewInternTable creates a new InternTable holding the given strings. Header
+// field names should be given in their canonical form, e.g. "User-Agent".
+func NewInternTable(values ...string) *InternTable {
+	table := &InternTable{
+		strings: make(map[string]string, len(values)),
+	}
+
+	for _, value := range values {
+		table.strings[value] = value
+	}
+
+	return table
+}
+
+// DefaultInternTable holds common header field names and values.
+var DefaultInternTable = NewInternTable(
+	"Accept", "Accept-Encoding", "Accept-Language", "Authorization",
+	"Cache-Control", "Connection", "Content-Encoding", "Content-Length",
+	"Content-Type", "Cookie", "Date", "Etag", "Host", "If-Modified-Since",
+	"If-None-Match", "Last-Modified", "Location", "Origin", "Pragma",
+	"Referer", "Server", "Set-Cookie", "Transfer-Encoding", "Upgrade",
+	"User-Agent", "Vary", "X-Forwarded-For", "X-Forwarded-Proto",
+	"X-Request-Id",
+	"*/*", "chunked", "close", "deflate", "gzip", "gzip, deflate",
+	"gzip, deflate, br", "identity", "keep-alive", "max-age=0", "no-cache",
+	"application/json", "text/html", "text/plain",
+)
+
+// Extend returns a new InternTable holding the strings of t and the given
+// strings, e.g. to add values that are frequent in a specific deployment.
+func (t *InternTable) Extend(values ...string) *InternTable {
+	table := &InternTable{
+		strings: make(map[string]string, len(t.strings)+len(values)),
+	}
+
+	for value := range t.strings {
+		table.strings[value] = value
+	}
+
+	for _, value := range values {
+		table.strings[value] = value
+	}
+
+	return table
+}
+
+// Intern returns the instance of s held by the table, or s itself if the
+// table doesn't hold it.
+func (t *InternTable) Intern(s string) string {
+	if interned, ok := t.strings[s]; ok {
+		return interned
+	}
+	return s
+}
+
+// WithInterning makes parsed messages share the strings of the given table
+// for their header field names and values. Strings that aren't held by the
+// table are allocated as usual.
+func WithInterning(table *InternTable) Option {
+	return func(c *config) {
+		c.internTable = table
+	}
+}
+
+// addHeaderField adds a parsed header field, interning its name and value if
+// an InternTable has been configured.
+func addHeaderField(header http.Header, name, value string, config config) {
+	if config.internTable == nil {
+		header.Add(name, value)
+		return
+	}
+
+	name = config.internTable.Intern(textproto.CanonicalMIMEHeaderKey(name))
+	header[name] = append(header[name], config.internTable.Intern(value))
+}
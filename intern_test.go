@@ -0,0 +1,48 @@
+package gohttp
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestWithInterning(t *testing.T) {
+	table := DefaultInternTable.Extend("example.com")
+	source := "GET / HTTP/1.1\r\nhost: example.com\r\nAccept-Encoding: gzip\r\nX-Custom: value\r\n\r\n"
+
+	request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), WithInterning(table))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	testCases := map[string]struct {
+		value    string
+		interned bool
+	}{
+		"Host":            {value: "example.com", interned: true},
+		"Accept-Encoding": {value: "gzip", interned: true},
+		"X-Custom":        {value: "value", interned: false},
+	}
+
+	for name, tc := range testCases {
+		values := request.Header[name]
+		if len(values) != 1 || values[0] != tc.value {
+			t.Fatalf("'%s': expected value %s, got %v", name, tc.value, values)
+		}
+
+		if tc.interned && !sameString(values[0], table.Intern(tc.value)) {
+			t.Errorf("'%s': expected value to be interned", name)
+		}
+	}
+
+	if _, ok := DefaultInternTable.strings["example.com"]; ok {
+		t.Errorf("expected Extend not to modify the original table")
+	}
+}
+
+// sameString reports whether both strings share the same memory.
+func sameString(a, b string) bool {
+	return (*reflect.StringHeader)(unsafe.Pointer(&a)).Data == (*reflect.StringHeader)(unsafe.Pointer(&b)).Data
+}
@@ -0,0 +1,293 @@
+// Package inventory observes traffic in a learning mode and accumulates a
+// summary per route: the methods, status codes, content types, and header
+// fields seen, and percentiles of the body sizes. The summaries can be
+// exported as JSON, e.g. as an API inventory or as a baseline for anomaly
+// detection.
+package inventory
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// sampleSize is the number of body sizes kept per route for computing the
+// percentiles. Beyond that, sizes are sampled uniformly.
+const sampleSize = 1024
+
+// defaultMaxRoutes is the maximum number of routes of a Learner unless
+// specified otherwise.
+const defaultMaxRoutes = 1000
+
+// OverflowRoute is the route requests are summarized under once a Learner
+// has reached its maximum number of routes.
+const OverflowRoute = "(overflow)"
+
+// RouteFunc maps a request to the route it is summarized under.
+type RouteFunc func(request *http.Request) string
+
+// Path summarizes requests by their path. It is the default RouteFunc. Since
+// the path is chosen by the client, the number of routes is only bounded by
+// the maximum number of routes of the Learner.
+func Path(request *http.Request) string {
+	return request.URL.Path
+}
+
+// Percentiles are percentiles of observed sizes in bytes.
+type Percentiles struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P99 int64 `json:"p99"`
+	Max int64 `json:"max"`
+}
+
+// Summary summarizes the traffic of a route.
+type Summary struct {
+	// Route is the route as returned by the RouteFunc.
+	Route string `json:"route"`
+	// Count is the number of observed requests.
+	Count int64 `json:"count"`
+	// Methods counts the requests by method.
+	Methods map[string]int64 `json:"methods"`
+	// StatusCodes counts the responses by status code.
+	StatusCodes map[string]int64 `json:"status_codes"`
+	// RequestContentTypes and ResponseContentTypes count the messages by
+	// media type, without parameters.
+	RequestContentTypes  map[string]int64 `json:"request_content_types"`
+	ResponseContentTypes map[string]int64 `json:"response_content_types"`
+	// RequestHeaders and ResponseHeaders are the sorted header field names
+	// seen in any request or response.
+	RequestHeaders  []string `json:"request_headers"`
+	ResponseHeaders []string `json:"response_headers"`
+	// RequestBodySize and ResponseBodySize are percentiles of the body
+	// sizes as transmitted.
+	RequestBodySize  Percentiles `json:"request_body_size"`
+	ResponseBodySize Percentiles `json:"response_body_size"`
+}
+
+type route struct {
+	summary         Summary
+	requestHeaders  map[string]bool
+	responseHeaders map[string]bool
+	requestSizes    reservoir
+	responseSizes   reservoir
+}
+
+// reservoir keeps a uniform sample of observed sizes, so that the memory
+// used per route is bounded regardless of the traffic.
+type reservoir struct {
+	samples []int64
+	seen    int64
+	max     int64
+}
+
+func (r *reservoir) add(size int64, random *rand.Rand) {
+	r.seen++
+
+	if size > r.max {
+		r.max = size
+	}
+
+	if len(r.samples) < sampleSize {
+		r.samples = append(r.samples, size)
+		return
+	}
+
+	if i := random.Int63n(r.seen); i < sampleSize {
+		r.samples[i] = size
+	}
+}
+
+func (r *reservoir) percentiles() Percentiles {
+	if len(r.samples) == 0 {
+		return Percentiles{}
+	}
+
+	sorted := append([]int64(nil), r.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p int) int64 {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+
+	return Percentiles{
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+		Max: r.max,
+	}
+}
+
+// Learner accumulates route summaries from observed traffic. It is safe for
+// concurrent use.
+type Learner struct {
+	// MaxRoutes is the maximum number of routes. Requests for other routes
+	// beyond that are summarized under OverflowRoute. If zero, a maximum of
+	// 1000 is used.
+	MaxRoutes int
+
+	mutex     sync.Mutex
+	routeFunc RouteFunc
+	routes    map[string]*route
+	random    *rand.Rand
+}
+
+// New creates a new Learner summarizing requests by the route returned by
+// routeFunc. If routeFunc is nil, Path is used.
+func New(routeFunc RouteFunc) *Learner {
+	if routeFunc == nil {
+		routeFunc = Path
+	}
+
+	return &Learner{
+		routeFunc: routeFunc,
+		routes:    make(map[string]*route),
+		random:    rand.New(rand.NewSource(1)),
+	}
+}
+
+// Observe adds a request and its response to the summary of its route. The
+// response may be nil. The sizes are those measured using gohttp.WithSizes
+// while parsing the messages.
+func (l *Learner) Observe(request *http.Request, response *http.Response, requestSizes, responseSizes gohttp.Sizes) {
+	name := l.routeFunc(request)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.routes[name]; !ok && len(l.routes) >= l.maxRoutes() {
+		name = OverflowRoute
+	}
+
+	r, ok := l.routes[name]
+	if !ok {
+		r = &route{
+			summary: Summary{
+				Route:                name,
+				Methods:              make(map[string]int64),
+				StatusCodes:          make(map[string]int64),
+				RequestContentTypes:  make(map[string]int64),
+				ResponseContentTypes: make(map[string]int64),
+			},
+			requestHeaders:  make(map[string]bool),
+			responseHeaders: make(map[string]bool),
+		}
+		l.routes[name] = r
+	}
+
+	r.summary.Count++
+	r.summary.Methods[request.Method]++
+	countContentType(r.summary.RequestContentTypes, request.Header)
+	addHeaderNames(r.requestHeaders, request.Header)
+	r.requestSizes.add(requestSizes.Body, l.random)
+
+	if response == nil {
+		return
+	}
+
+	r.summary.StatusCodes[strconv.Itoa(response.StatusCode)]++
+	countContentType(r.summary.ResponseContentTypes, response.Header)
+	addHeaderNames(r.responseHeaders, response.Header)
+	r.responseSizes.add(responseSizes.Body, l.random)
+}
+
+// ObserveTransaction parses a serialized transaction and observes it.
+func (l *Learner) ObserveTransaction(transaction gohttp.Transaction) error {
+	request, response, err := transaction.Parse()
+	if err != nil {
+		return err
+	}
+
+	l.Observe(request, response, transaction.RequestSizes, transaction.ResponseSizes)
+
+	return nil
+}
+
+// maxRoutes returns the maximum number of routes, reserving one for the
+// overflow route.
+func (l *Learner) maxRoutes() int {
+	if l.MaxRoutes <= 0 {
+		return defaultMaxRoutes - 1
+	}
+	return l.MaxRoutes - 1
+}
+
+// Summaries returns the summaries of all routes, sorted by route.
+func (l *Learner) Summaries() []Summary {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	summaries := make([]Summary, 0, len(l.routes))
+
+	for _, r := range l.routes {
+		summary := r.summary
+		summary.Methods = copyCounts(r.summary.Methods)
+		summary.StatusCodes = copyCounts(r.summary.StatusCodes)
+		summary.RequestContentTypes = copyCounts(r.summary.RequestContentTypes)
+		summary.ResponseContentTypes = copyCounts(r.summary.ResponseContentTypes)
+		summary.RequestHeaders = sortedNames(r.requestHeaders)
+		summary.ResponseHeaders = sortedNames(r.responseHeaders)
+		summary.RequestBodySize = r.requestSizes.percentiles()
+		summary.ResponseBodySize = r.responseSizes.percentiles()
+
+		summaries = append(summaries, summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Route < summaries[j].Route
+	})
+
+	return summaries
+}
+
+// WriteJSON writes the summaries of all routes as a JSON array.
+func (l *Learner) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(l.Summaries())
+}
+
+func countContentType(counts map[string]int64, header http.Header) {
+	value := header.Get("Content-Type")
+	if value == "" {
+		return
+	}
+
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		mediaType = "invalid"
+	}
+
+	counts[mediaType]++
+}
+
+func addHeaderNames(names map[string]bool, header http.Header) {
+	for name := range header {
+		names[name] = true
+	}
+}
+
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+	copied := make(map[string]int64, len(counts))
+	for key, count := range counts {
+		copied[key] = count
+	}
+	return copied
+}
@@ -0,0 +1,142 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestLearner(t *testing.T) {
+	learner := New(nil)
+
+	transactions := []struct {
+		request  string
+		response string
+	}{
+		{
+			request:  "GET /users HTTP/1.1\r\nHost: example.com\r\nAccept: application/json\r\n\r\n",
+			response: "HTTP/1.1 200 OK\r\nContent-Type: application/json; charset=utf-8\r\nContent-Length: 2\r\n\r\n[]",
+		},
+		{
+			request:  "POST /users HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 16\r\n\r\n{\"name\":\"alice\"}",
+			response: "HTTP/1.1 201 Created\r\nLocation: /users/1\r\nContent-Length: 0\r\n\r\n",
+		},
+		{
+			request: "GET /health HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		},
+	}
+
+	for _, tc := range transactions {
+		transaction := gohttp.Transaction{
+			Request:      []byte(tc.request),
+			RequestSizes: gohttp.MeasureMessage([]byte(tc.request)),
+		}
+		if tc.response != "" {
+			transaction.Response = []byte(tc.response)
+			transaction.ResponseSizes = gohttp.MeasureMessage([]byte(tc.response))
+		}
+
+		if err := learner.ObserveTransaction(transaction); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	expected := []Summary{
+		{
+			Route:                "/health",
+			Count:                1,
+			Methods:              map[string]int64{"GET": 1},
+			StatusCodes:          map[string]int64{},
+			RequestContentTypes:  map[string]int64{},
+			ResponseContentTypes: map[string]int64{},
+			RequestHeaders:       []string{"Host"},
+			ResponseHeaders:      []string{},
+		},
+		{
+			Route:                "/users",
+			Count:                2,
+			Methods:              map[string]int64{"GET": 1, "POST": 1},
+			StatusCodes:          map[string]int64{"200": 1, "201": 1},
+			RequestContentTypes:  map[string]int64{"application/json": 1},
+			ResponseContentTypes: map[string]int64{"application/json": 1},
+			RequestHeaders:       []string{"Accept", "Content-Length", "Content-Type", "Host"},
+			ResponseHeaders:      []string{"Content-Length", "Content-Type", "Location"},
+			RequestBodySize:      Percentiles{P50: 0, P90: 0, P99: 0, Max: 16},
+			ResponseBodySize:     Percentiles{P50: 0, P90: 0, P99: 0, Max: 2},
+		},
+	}
+
+	summaries := learner.Summaries()
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Errorf("expected summaries %+v, got %+v", expected, summaries)
+	}
+
+	var buf bytes.Buffer
+	if err := learner.WriteJSON(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var exported []Summary
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if !reflect.DeepEqual(exported, expected) {
+		t.Errorf("expected exported summaries %+v, got %+v", expected, exported)
+	}
+}
+
+func TestLearner_MaxRoutes(t *testing.T) {
+	learner := New(nil)
+	learner.MaxRoutes = 3
+
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/a"} {
+		request, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+		learner.Observe(request, nil, gohttp.Sizes{}, gohttp.Sizes{})
+	}
+
+	expected := map[string]int64{"/a": 2, "/b": 1, OverflowRoute: 2}
+
+	summaries := learner.Summaries()
+	if len(summaries) != len(expected) {
+		t.Fatalf("expected %d routes, got %d", len(expected), len(summaries))
+	}
+
+	for _, summary := range summaries {
+		if summary.Count != expected[summary.Route] {
+			t.Errorf("expected count %d for route %s, got %d", expected[summary.Route], summary.Route, summary.Count)
+		}
+	}
+}
+
+func TestReservoir_Percentiles(t *testing.T) {
+	learner := New(func(request *http.Request) string { return "all" })
+
+	for i := int64(1); i <= 10000; i++ {
+		request, _ := http.NewRequest("POST", "/", strings.NewReader(""))
+		learner.Observe(request, nil, gohttp.Sizes{Body: i}, gohttp.Sizes{})
+	}
+
+	sizes := learner.Summaries()[0].RequestBodySize
+
+	if sizes.Max != 10000 {
+		t.Errorf("expected max %d, got %d", 10000, sizes.Max)
+	}
+
+	// The percentiles are estimated from a sample, so they are only
+	// expected to be roughly right.
+	for name, tc := range map[string]struct{ actual, expected int64 }{
+		"p50": {actual: sizes.P50, expected: 5000},
+		"p90": {actual: sizes.P90, expected: 9000},
+		"p99": {actual: sizes.P99, expected: 9900},
+	} {
+		if tc.actual < tc.expected*9/10 || tc.actual > tc.expected*11/10 {
+			t.Errorf("'%s': expected about %d, got %d", name, tc.expected, tc.actual)
+		}
+	}
+}
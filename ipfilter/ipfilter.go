@@ -0,0 +1,171 @@
+// Package ipfilter provides connection-level filtering by client IP address.
+// Connections are rejected right after being accepted, before any parsing
+// happens, which saves cycles under abuse.
+package ipfilter
+
+import (
+	"net"
+	"sync/atomic"
+
+	"github.com/dominikbraun/gohttp/metrics"
+)
+
+// LookupFunc is a hook for additional decisions, e.g. based on a GeoIP
+// database. It returns false to reject the connection.
+type LookupFunc func(ip net.IP) bool
+
+// Filter decides whether connections from an IP address are accepted.
+//
+// Deny lists take precedence over allow lists. If the allow list is empty,
+// all addresses not denied are allowed. The lookup hook is consulted last.
+type Filter struct {
+	// The counters are accessed atomically and come first, so that they are
+	// 64-bit aligned on 32-bit platforms.
+	accepted uint64
+	rejected uint64
+	allow    []*net.IPNet
+	deny     []*net.IPNet
+	lookup   LookupFunc
+	metrics  metrics.Metrics
+}
+
+// New creates a new Filter from CIDR allow and deny lists, e.g.
+// "192.168.0.0/16".
+func New(allow, deny []string) (*Filter, error) {
+	allowNets, err := parseCIDRs(allow)
+	if err != nil {
+		return nil, err
+	}
+
+	denyNets, err := parseCIDRs(deny)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filter{
+		allow: allowNets,
+		deny:  denyNets,
+	}, nil
+}
+
+// SetLookup sets the lookup hook. It must be set before the filter is used.
+func (f *Filter) SetLookup(lookup LookupFunc) {
+	f.lookup = lookup
+}
+
+// SetMetrics sets the Metrics implementation that accepted and rejected
+// connections are reported to. It must be set before the filter is used.
+func (f *Filter) SetMetrics(m metrics.Metrics) {
+	f.metrics = m
+}
+
+// Allowed reports whether connections from the IP address are accepted.
+func (f *Filter) Allowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	if contains(f.deny, ip) {
+		return false
+	}
+
+	if len(f.allow) > 0 && !contains(f.allow, ip) {
+		return false
+	}
+
+	if f.lookup != nil {
+		return f.lookup(ip)
+	}
+
+	return true
+}
+
+// AllowedAddr is like Allowed but takes a network address.
+func (f *Filter) AllowedAddr(addr net.Addr) bool {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return f.Allowed(a.IP)
+	case *net.UDPAddr:
+		return f.Allowed(a.IP)
+	}
+
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+
+	return f.Allowed(net.ParseIP(host))
+}
+
+// Accepted returns the number of connections accepted by listeners.
+func (f *Filter) Accepted() uint64 {
+	return atomic.LoadUint64(&f.accepted)
+}
+
+// Rejected returns the number of connections rejected by listeners.
+func (f *Filter) Rejected() uint64 {
+	return atomic.LoadUint64(&f.rejected)
+}
+
+// Listener wraps a net.Listener so that connections that aren't allowed are
+// closed immediately instead of being returned by Accept.
+func (f *Filter) Listener(listener net.Listener) net.Listener {
+	return &filteredListener{
+		Listener: listener,
+		filter:   f,
+	}
+}
+
+type filteredListener struct {
+	net.Listener
+	filter *Filter
+}
+
+func (l *filteredListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if l.filter.AllowedAddr(conn.RemoteAddr()) {
+			l.filter.count(&l.filter.accepted, true)
+			return conn, nil
+		}
+
+		l.filter.count(&l.filter.rejected, false)
+		_ = conn.Close()
+	}
+}
+
+// count increments a counter and reports the connection to the metrics.
+func (f *Filter) count(counter *uint64, accepted bool) {
+	atomic.AddUint64(counter, 1)
+
+	if f.metrics != nil {
+		f.metrics.Connection(accepted)
+	}
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return networks, nil
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
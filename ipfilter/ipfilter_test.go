@@ -0,0 +1,112 @@
+package ipfilter
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/dominikbraun/gohttp/metrics"
+)
+
+func TestFilter_Allowed(t *testing.T) {
+	testCases := map[string]struct {
+		allow    []string
+		deny     []string
+		lookup   LookupFunc
+		ip       string
+		expected bool
+	}{
+		"no lists": {
+			ip:       "203.0.113.7",
+			expected: true,
+		},
+		"allowed": {
+			allow:    []string{"10.0.0.0/8"},
+			ip:       "10.1.2.3",
+			expected: true,
+		},
+		"not in allow list": {
+			allow:    []string{"10.0.0.0/8"},
+			ip:       "203.0.113.7",
+			expected: false,
+		},
+		"deny wins": {
+			allow:    []string{"10.0.0.0/8"},
+			deny:     []string{"10.1.0.0/16"},
+			ip:       "10.1.2.3",
+			expected: false,
+		},
+		"IPv6 denied": {
+			deny:     []string{"2001:db8::/32"},
+			ip:       "2001:db8::1",
+			expected: false,
+		},
+		"lookup rejects": {
+			lookup:   func(ip net.IP) bool { return !ip.Equal(net.ParseIP("198.51.100.1")) },
+			ip:       "198.51.100.1",
+			expected: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		filter, err := New(tc.allow, tc.deny)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+		filter.SetLookup(tc.lookup)
+
+		if actual := filter.Allowed(net.ParseIP(tc.ip)); actual != tc.expected {
+			t.Errorf("'%s': expected result %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestFilter_Listener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	filter, _ := New(nil, []string{"127.0.0.0/8"})
+	prometheus := metrics.NewPrometheus("gohttp", nil)
+	filter.SetMetrics(prometheus)
+	filtered := filter.Listener(listener)
+
+	done := make(chan error)
+	go func() {
+		_, err := filtered.Accept()
+		done <- err
+	}()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	// The server closes the connection, so reading returns an error.
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected the connection to be closed")
+	}
+	_ = conn.Close()
+
+	_ = filtered.Close()
+	<-done
+
+	if filter.Rejected() != 1 || filter.Accepted() != 0 {
+		t.Errorf("expected 1 rejected and 0 accepted connections, got %d and %d", filter.Rejected(), filter.Accepted())
+	}
+
+	var buf bytes.Buffer
+	_ = prometheus.WriteText(&buf)
+
+	if !strings.Contains(buf.String(), `gohttp_connections_total{result="rejected"} 1`) {
+		t.Errorf("expected the rejected connection to be reported, got %s", buf.String())
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0"}, nil); err == nil {
+		t.Errorf("expected an error for an invalid CIDR, got none")
+	}
+}
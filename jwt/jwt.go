@@ -0,0 +1,577 @@
+// Package jwt verifies JSON Web Tokens (RFC 7519) used as bearer tokens. A
+// Verifier implements auth.BearerVerifier, so that invalid tokens are
+// answered with a 401 challenge by the auth package. Its Middleware
+// additionally passes the verified claims to the next handler.
+//
+// Tokens signed with RS256, RS384, RS512, ES256, ES384, ES512, HS256, HS384,
+// and HS512 are supported. Public keys can be fetched from a JWKS endpoint
+// and are cached according to its Cache-Control header field.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // Register SHA-256 for crypto.Hash.
+	_ "crypto/sha512" // Register SHA-384 and SHA-512 for crypto.Hash.
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dominikbraun/gohttp/auth"
+)
+
+var (
+	// ErrMalformed is returned for a token that isn't a well-formed JWS in
+	// compact serialization.
+	ErrMalformed = errors.New("malformed token")
+	// ErrUnsupportedAlgorithm is returned for an unsupported or unsafe
+	// algorithm, including "none".
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	// ErrUnknownKey is returned if no key is known for the key ID of a token.
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrInvalidSignature is returned for a signature that doesn't match.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrExpired is returned for a token past its expiration time.
+	ErrExpired = errors.New("token is expired")
+	// ErrMissingExpiry is returned for a token without an expiration time
+	// if the Verifier requires one.
+	ErrMissingExpiry = errors.New("token has no expiration time")
+	// ErrNotYetValid is returned for a token before its not-before time.
+	ErrNotYetValid = errors.New("token is not valid yet")
+	// ErrInvalidIssuer is returned for a token from an unexpected issuer.
+	ErrInvalidIssuer = errors.New("invalid issuer")
+	// ErrInvalidAudience is returned for a token not intended for the
+	// expected audience.
+	ErrInvalidAudience = errors.New("invalid audience")
+)
+
+// Claims are the registered claims of a verified token. All claims,
+// including private ones, are available in Raw.
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	Raw       map[string]interface{}
+}
+
+// KeySet provides the keys for verifying signatures. Keys are either
+// *rsa.PublicKey, *ecdsa.PublicKey, or []byte for HMAC secrets.
+type KeySet interface {
+	Key(kid string) (interface{}, error)
+}
+
+// StaticKeys is a KeySet holding keys by their key ID. The empty key ID is
+// used for tokens without a kid header parameter.
+type StaticKeys map[string]interface{}
+
+// Key implements KeySet.
+func (s StaticKeys) Key(kid string) (interface{}, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// Verifier verifies tokens and their claims.
+type Verifier struct {
+	// Keys provides the keys for verifying signatures.
+	Keys KeySet
+	// Issuer is the required issuer. It isn't checked if empty.
+	Issuer string
+	// Audience has to be one of the audiences of the token. It isn't checked
+	// if empty.
+	Audience string
+	// Leeway is the tolerated clock skew for the time-based claims.
+	Leeway time.Duration
+	// RequireExpiry rejects tokens without an expiration time, which would
+	// be valid forever otherwise. It is enabled by NewVerifier.
+	RequireExpiry bool
+
+	now func() time.Time
+}
+
+// claimsKey is the context key for the claims of a verified token.
+type claimsKey struct{}
+
+// NewVerifier creates a new Verifier using the given keys that requires
+// tokens to have an expiration time.
+func NewVerifier(keys KeySet) *Verifier {
+	return &Verifier{
+		Keys:          keys,
+		RequireExpiry: true,
+	}
+}
+
+// VerifyBearer implements auth.BearerVerifier.
+func (v *Verifier) VerifyBearer(token string) bool {
+	_, err := v.Verify(token)
+	return err == nil
+}
+
+// Middleware returns an http.Handler authenticating requests just like
+// auth.Middleware with an auth.Bearer authenticator for the given realm. The
+// claims of the verified token are passed to next in the request context and
+// can be retrieved using ClaimsFromContext.
+func (v *Verifier) Middleware(realm string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var claims *Claims
+
+		bearer := auth.Bearer{
+			Realm: realm,
+			Verifier: auth.BearerVerifierFunc(func(token string) bool {
+				var err error
+				claims, err = v.Verify(token)
+				return err == nil
+			}),
+		}
+
+		auth.Middleware(bearer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})).ServeHTTP(w, r)
+	})
+}
+
+// ClaimsFromContext returns the claims stored in the context by the
+// Middleware of a Verifier.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// Verify verifies the signature and the claims of a token and returns the
+// claims.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	key, err := v.Keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+
+	claims, err := parseClaims(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, v.checkClaims(claims)
+}
+
+func (v *Verifier) checkClaims(claims *Claims) error {
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+
+	if claims.ExpiresAt.IsZero() && v.RequireExpiry {
+		return ErrMissingExpiry
+	}
+
+	if !claims.ExpiresAt.IsZero() && !now.Before(claims.ExpiresAt.Add(v.Leeway)) {
+		return ErrExpired
+	}
+
+	if !claims.NotBefore.IsZero() && now.Add(v.Leeway).Before(claims.NotBefore) {
+		return ErrNotYetValid
+	}
+
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if v.Audience != "" && !contains(claims.Audience, v.Audience) {
+		return ErrInvalidAudience
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformed
+	}
+
+	return nil
+}
+
+func parseClaims(raw map[string]interface{}) (*Claims, error) {
+	claims := &Claims{
+		Raw: raw,
+	}
+
+	claims.Issuer, _ = raw["iss"].(string)
+	claims.Subject, _ = raw["sub"].(string)
+
+	switch audience := raw["aud"].(type) {
+	case string:
+		claims.Audience = []string{audience}
+	case []interface{}:
+		for _, a := range audience {
+			if s, ok := a.(string); ok {
+				claims.Audience = append(claims.Audience, s)
+			}
+		}
+	}
+
+	for name, t := range map[string]*time.Time{"exp": &claims.ExpiresAt, "nbf": &claims.NotBefore, "iat": &claims.IssuedAt} {
+		value, ok := raw[name]
+		if !ok {
+			continue
+		}
+
+		seconds, ok := value.(float64)
+		if !ok {
+			return nil, ErrMalformed
+		}
+
+		*t = time.Unix(int64(seconds), 0)
+	}
+
+	return claims, nil
+}
+
+var hashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+func verifySignature(alg string, key interface{}, signingInput string, signature []byte) error {
+	if len(alg) != 5 {
+		return ErrUnsupportedAlgorithm
+	}
+
+	hash, ok := hashes[alg[2:]]
+	if !ok {
+		return ErrUnsupportedAlgorithm
+	}
+
+	h := hash.New()
+	h.Write([]byte(signingInput))
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		if rsa.VerifyPKCS1v15(publicKey, hash, digest, signature) != nil {
+			return ErrInvalidSignature
+		}
+	case "ES":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return ErrInvalidSignature
+		}
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrUnsupportedAlgorithm
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+
+	return nil
+}
+
+const (
+	// defaultJWKSTTL is the cache lifetime of a key set if the response
+	// doesn't specify a max-age.
+	defaultJWKSTTL = 5 * time.Minute
+	// minJWKSRefresh limits how often a key set is refetched, e.g. when
+	// tokens with unknown key IDs are presented.
+	minJWKSRefresh = time.Minute
+	// defaultJWKSTimeout is the timeout of the default client fetching key
+	// sets.
+	defaultJWKSTimeout = 10 * time.Second
+)
+
+// JWKS is a KeySet fetched from a JWKS endpoint (RFC 7517). The keys are
+// cached for the max-age of the response, and refetched earlier if a token
+// references an unknown key ID. It is safe for concurrent use.
+type JWKS struct {
+	url    string
+	client *http.Client
+
+	mutex    sync.Mutex
+	keys     map[string]interface{}
+	fetched  time.Time
+	expires  time.Time
+	inflight *jwksFetch
+	now      func() time.Time
+}
+
+// jwksFetch is a fetch in progress, which concurrent callers wait for
+// instead of fetching the key set themselves.
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKS creates a new JWKS fetching the keys from the given URL. If client
+// is nil, a client with a timeout of 10 seconds is used, so that a hanging
+// endpoint can't block token verification indefinitely.
+func NewJWKS(url string, client *http.Client) *JWKS {
+	if client == nil {
+		client = &http.Client{Timeout: defaultJWKSTimeout}
+	}
+
+	return &JWKS{
+		url:    url,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Key implements KeySet. If the keys can't be refetched, the cached keys
+// are used until a fetch succeeds.
+func (j *JWKS) Key(kid string) (interface{}, error) {
+	now := j.now()
+
+	keys, expired, refetchable := j.state(now)
+
+	if keys == nil || expired {
+		if err := j.refresh(now); err != nil && keys == nil {
+			return nil, err
+		}
+		keys, _, refetchable = j.state(now)
+	}
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+
+	if refetchable {
+		if err := j.refresh(now); err != nil {
+			return nil, err
+		}
+		keys, _, _ = j.state(now)
+		if key, ok := keys[kid]; ok {
+			return key, nil
+		}
+	}
+
+	return nil, ErrUnknownKey
+}
+
+// state returns the cached keys, whether they have expired, and whether
+// they may be refetched for an unknown key ID.
+func (j *JWKS) state(now time.Time) (map[string]interface{}, bool, bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.keys, !now.Before(j.expires), now.Sub(j.fetched) >= minJWKSRefresh
+}
+
+// refresh fetches the key set. The network request is made without holding
+// the mutex, and concurrent callers wait for a fetch in progress.
+func (j *JWKS) refresh(now time.Time) error {
+	j.mutex.Lock()
+
+	if fetch := j.inflight; fetch != nil {
+		j.mutex.Unlock()
+		<-fetch.done
+		return fetch.err
+	}
+
+	fetch := &jwksFetch{done: make(chan struct{})}
+	j.inflight = fetch
+	j.fetched = now
+	j.mutex.Unlock()
+
+	keys, ttl, err := j.fetch()
+
+	j.mutex.Lock()
+	if err == nil {
+		j.keys = keys
+		j.expires = now.Add(ttl)
+	}
+	j.inflight = nil
+	j.mutex.Unlock()
+
+	fetch.err = err
+	close(fetch.done)
+
+	return err
+}
+
+func (j *JWKS) fetch() (map[string]interface{}, time.Duration, error) {
+	response, err := j.client.Get(j.url)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("fetching JWKS: unexpected status %s", response.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&set); err != nil {
+		return nil, 0, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		// Keys of unsupported types are skipped, as required by RFC 7517,
+		// section 5.
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, cacheTTL(response.Header), nil
+}
+
+// cacheTTL returns the lifetime of a key set based on its Cache-Control
+// header field, but at least minJWKSRefresh.
+func cacheTTL(header http.Header) time.Duration {
+	ttl := defaultJWKSTTL
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			ttl = 0
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	if ttl < minJWKSRefresh {
+		ttl = minJWKSRefresh
+	}
+
+	return ttl
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, ErrMalformed
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, ErrMalformed
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, ErrUnsupportedAlgorithm
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, ErrMalformed
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
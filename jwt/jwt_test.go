@@ -0,0 +1,362 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp/auth"
+)
+
+var now = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret := []byte("secret")
+
+	verifier := &Verifier{
+		Keys: StaticKeys{
+			"rsa": &rsaKey.PublicKey,
+			"ec":  &ecKey.PublicKey,
+			"":    secret,
+		},
+		Issuer:        "https://issuer.example.com",
+		Audience:      "api",
+		RequireExpiry: true,
+		now:           func() time.Time { return now },
+	}
+
+	valid := map[string]interface{}{
+		"iss": "https://issuer.example.com",
+		"sub": "alice",
+		"aud": []string{"api", "other"},
+		"exp": now.Add(time.Hour).Unix(),
+	}
+
+	with := func(name string, value interface{}) map[string]interface{} {
+		claims := make(map[string]interface{}, len(valid))
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[name] = value
+		return claims
+	}
+
+	testCases := map[string]struct {
+		token         string
+		expectedError error
+	}{
+		"RS256": {
+			token: sign(t, "RS256", "rsa", rsaKey, valid),
+		},
+		"ES256": {
+			token: sign(t, "ES256", "ec", ecKey, valid),
+		},
+		"HS256": {
+			token: sign(t, "HS256", "", secret, valid),
+		},
+		"none": {
+			token:         encode(t, map[string]string{"alg": "none"}) + "." + encode(t, valid) + ".",
+			expectedError: ErrUnsupportedAlgorithm,
+		},
+		"algorithm confusion": {
+			token:         sign(t, "HS256", "rsa", secret, valid),
+			expectedError: ErrUnsupportedAlgorithm,
+		},
+		"wrong key": {
+			token:         sign(t, "HS256", "", []byte("other"), valid),
+			expectedError: ErrInvalidSignature,
+		},
+		"unknown key": {
+			token:         sign(t, "RS256", "unknown", rsaKey, valid),
+			expectedError: ErrUnknownKey,
+		},
+		"expired": {
+			token:         sign(t, "RS256", "rsa", rsaKey, with("exp", now.Add(-time.Minute).Unix())),
+			expectedError: ErrExpired,
+		},
+		"missing expiry": {
+			token: sign(t, "RS256", "rsa", rsaKey, map[string]interface{}{
+				"iss": "https://issuer.example.com",
+				"sub": "alice",
+				"aud": "api",
+			}),
+			expectedError: ErrMissingExpiry,
+		},
+		"not yet valid": {
+			token:         sign(t, "RS256", "rsa", rsaKey, with("nbf", now.Add(time.Minute).Unix())),
+			expectedError: ErrNotYetValid,
+		},
+		"wrong issuer": {
+			token:         sign(t, "RS256", "rsa", rsaKey, with("iss", "https://evil.example.com")),
+			expectedError: ErrInvalidIssuer,
+		},
+		"wrong audience": {
+			token:         sign(t, "RS256", "rsa", rsaKey, with("aud", "other")),
+			expectedError: ErrInvalidAudience,
+		},
+		"malformed": {
+			token:         "not-a-token",
+			expectedError: ErrMalformed,
+		},
+	}
+
+	for name, tc := range testCases {
+		claims, err := verifier.Verify(tc.token)
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+			continue
+		}
+
+		if err == nil && claims.Subject != "alice" {
+			t.Errorf("'%s': expected subject %s, got %s", name, "alice", claims.Subject)
+		}
+	}
+}
+
+func TestVerifier_Bearer(t *testing.T) {
+	verifier := &Verifier{
+		Keys: StaticKeys{"": []byte("secret")},
+	}
+
+	bearer := auth.Bearer{Realm: "api", Verifier: verifier}
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Authorization", "Bearer "+sign(t, "HS256", "", []byte("other"), map[string]interface{}{}))
+
+	response, ok := bearer.Authenticate(request)
+	if ok {
+		t.Fatalf("expected the request to be rejected")
+	}
+
+	expected := `Bearer realm="api", error="invalid_token"`
+	if challenge := response.Header.Get("WWW-Authenticate"); challenge != expected {
+		t.Errorf("expected challenge %s, got %s", expected, challenge)
+	}
+}
+
+func TestVerifier_Middleware(t *testing.T) {
+	verifier := NewVerifier(StaticKeys{"": []byte("secret")})
+	verifier.now = func() time.Time { return now }
+
+	var subject string
+	handler := verifier.Middleware("api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			t.Errorf("expected claims in the request context")
+			return
+		}
+		subject = claims.Subject
+	}))
+
+	testCases := map[string]struct {
+		claims          map[string]interface{}
+		expectedStatus  int
+		expectedSubject string
+	}{
+		"valid": {
+			claims:          map[string]interface{}{"sub": "alice", "exp": now.Add(time.Hour).Unix()},
+			expectedStatus:  http.StatusOK,
+			expectedSubject: "alice",
+		},
+		"missing expiry": {
+			claims:         map[string]interface{}{"sub": "alice"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for name, tc := range testCases {
+		subject = ""
+
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Header.Set("Authorization", "Bearer "+sign(t, "HS256", "", []byte("secret"), tc.claims))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("'%s': expected status code %d, got %d", name, tc.expectedStatus, recorder.Code)
+		}
+
+		if subject != tc.expectedSubject {
+			t.Errorf("'%s': expected subject %s, got %s", name, tc.expectedSubject, subject)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	fetches := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Cache-Control", "public, max-age=600")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{"kty": "oct", "kid": "ignored"},
+				{
+					"kty": "EC",
+					"kid": "ec",
+					"crv": "P-256",
+					"x":   base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+					"y":   base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes()),
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	clock := now
+	jwks := NewJWKS(server.URL, server.Client())
+	jwks.now = func() time.Time { return clock }
+
+	verifier := &Verifier{Keys: jwks}
+	token := sign(t, "ES256", "ec", ecKey, map[string]interface{}{"sub": "alice"})
+
+	testCases := []struct {
+		name            string
+		advance         time.Duration
+		kid             string
+		expectedError   error
+		expectedFetches int
+	}{
+		{name: "initial fetch", expectedFetches: 1},
+		{name: "unknown key within refresh interval", kid: "unknown", expectedError: ErrUnknownKey, expectedFetches: 1},
+		{name: "cached", advance: 5 * time.Minute, expectedFetches: 1},
+		{name: "unknown key after refresh interval", kid: "unknown", expectedError: ErrUnknownKey, expectedFetches: 2},
+		{name: "expired", advance: 11 * time.Minute, expectedFetches: 3},
+	}
+
+	for _, tc := range testCases {
+		clock = clock.Add(tc.advance)
+
+		var err error
+		if tc.kid == "" {
+			_, err = verifier.Verify(token)
+		} else {
+			_, err = jwks.Key(tc.kid)
+		}
+
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", tc.name, tc.expectedError, err)
+		}
+
+		if fetches != tc.expectedFetches {
+			t.Errorf("'%s': expected %d fetches, got %d", tc.name, tc.expectedFetches, fetches)
+		}
+	}
+}
+
+func sign(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+
+	input := encode(t, header) + "." + encode(t, claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	var err error
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		signature = append(padded(r, 32), padded(s, 32)...)
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	default:
+		err = fmt.Errorf("unsupported key type %T", key)
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encode(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func padded(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func TestJWKS_Timeout(t *testing.T) {
+	if client := NewJWKS("http://example.com", nil).client; client.Timeout <= 0 {
+		t.Errorf("expected the default client to have a timeout")
+	}
+
+	release := make(chan struct{})
+	var fetches int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	jwks := NewJWKS(server.URL, &http.Client{Timeout: 500 * time.Millisecond})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = jwks.Key("ec")
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a hanging JWKS endpoint to time out")
+	}
+
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("caller %d: expected an error", i)
+		}
+	}
+
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("expected concurrent callers to share %d fetch, got %d", 1, n)
+	}
+}
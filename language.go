@@ -0,0 +1,196 @@
+package gohttp
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// languageKey is the context key for the negotiated language.
+type languageKey struct{}
+
+// LanguageRange is a language range of an Accept-Language header field, like
+// "de-CH" or "*", along with its quality value.
+type LanguageRange struct {
+	Tag     string
+	Quality float64
+}
+
+// ParseAcceptLanguage parses all Accept-Language header fields (RFC 7231,
+// section 5.3.5.) and returns the language ranges ordered by descending
+// quality. Ranges with the same quality keep their original order. Invalid
+// ranges are skipped.
+func ParseAcceptLanguage(header http.Header) []LanguageRange {
+	var ranges []LanguageRange
+
+	for _, value := range header.Values("Accept-Language") {
+		for _, element := range strings.Split(value, ",") {
+			params := strings.Split(element, ";")
+
+			languageRange := LanguageRange{
+				Tag:     strings.TrimSpace(params[0]),
+				Quality: 1,
+			}
+
+			if !isLanguageRange(languageRange.Tag) {
+				continue
+			}
+
+			for _, param := range params[1:] {
+				tokens := strings.SplitN(strings.TrimSpace(param), "=", 2)
+				if len(tokens) == 2 && strings.EqualFold(tokens[0], "q") {
+					quality, err := strconv.ParseFloat(tokens[1], 64)
+					if err != nil || quality < 0 || quality > 1 {
+						quality = 0
+					}
+					languageRange.Quality = quality
+				}
+			}
+
+			ranges = append(ranges, languageRange)
+		}
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Quality > ranges[j].Quality
+	})
+
+	return ranges
+}
+
+// NegotiateLanguage returns the available language tag that matches the
+// Accept-Language header fields best, or fallback if none matches.
+//
+// For each language range, the lookup scheme of RFC 4647, section 3.4. is
+// applied first: the range itself is tried, and then the range is truncated
+// from the end, so that "de-CH" falls back to "de". If that fails, a more
+// specific available tag is chosen, so that "en" matches "en-US". Tags are
+// compared case-insensitively, and tags matched by a range with a quality of
+// 0 are never chosen.
+func NegotiateLanguage(header http.Header, available []string, fallback string) string {
+	ranges := ParseAcceptLanguage(header)
+
+	excluded := make(map[string]bool)
+	for _, languageRange := range ranges {
+		if languageRange.Quality == 0 {
+			excluded[strings.ToLower(languageRange.Tag)] = true
+		}
+	}
+
+	isAcceptable := func(tag string) bool {
+		return !excluded[strings.ToLower(tag)]
+	}
+
+	for _, languageRange := range ranges {
+		if languageRange.Quality == 0 {
+			break
+		}
+
+		if languageRange.Tag == "*" {
+			for _, tag := range available {
+				if isAcceptable(tag) {
+					return tag
+				}
+			}
+			continue
+		}
+
+		for prefix := languageRange.Tag; prefix != ""; prefix = truncateLanguageTag(prefix) {
+			for _, tag := range available {
+				if strings.EqualFold(tag, prefix) && isAcceptable(tag) {
+					return tag
+				}
+			}
+		}
+
+		for _, tag := range available {
+			if hasLanguagePrefix(tag, languageRange.Tag) && isAcceptable(tag) {
+				return tag
+			}
+		}
+	}
+
+	return fallback
+}
+
+// ContextWithLanguage returns a copy of ctx carrying the negotiated language.
+func ContextWithLanguage(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, languageKey{}, tag)
+}
+
+// LanguageFromContext returns the negotiated language stored in ctx, or an
+// empty string.
+func LanguageFromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(languageKey{}).(string)
+	return tag
+}
+
+// LanguageMiddleware returns an http.Handler that negotiates the language of
+// each request using NegotiateLanguage and stores it in the request context.
+// The response carries the Content-Language and Vary header fields.
+func LanguageMiddleware(available []string, fallback string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := NegotiateLanguage(r.Header, available, fallback)
+
+		if tag != "" {
+			w.Header().Set("Content-Language", tag)
+		}
+		appendListValue(w.Header(), "Vary", "Accept-Language")
+
+		next.ServeHTTP(w, r.WithContext(ContextWithLanguage(r.Context(), tag)))
+	})
+}
+
+// truncateLanguageTag removes the last subtag of a language tag. Single
+// character subtags like the "x" of private use sequences are removed along
+// with it (RFC 4647, section 3.4.).
+func truncateLanguageTag(tag string) string {
+	i := strings.LastIndexByte(tag, '-')
+	if i < 0 {
+		return ""
+	}
+
+	tag = tag[:i]
+
+	if j := strings.LastIndexByte(tag, '-'); j >= 0 && len(tag)-j == 2 {
+		tag = tag[:j]
+	}
+
+	return tag
+}
+
+// hasLanguagePrefix reports whether the language range matches the tag
+// using basic filtering (RFC 4647, section 3.3.1.).
+func hasLanguagePrefix(tag, languageRange string) bool {
+	return len(tag) > len(languageRange) &&
+		strings.EqualFold(tag[:len(languageRange)], languageRange) &&
+		tag[len(languageRange)] == '-'
+}
+
+// isLanguageRange reports whether s is a valid language range, consisting
+// of alphanumeric subtags of 1 to 8 characters or being "*".
+func isLanguageRange(s string) bool {
+	if s == "*" {
+		return true
+	}
+
+	for i, subtag := range strings.Split(s, "-") {
+		if len(subtag) < 1 || len(subtag) > 8 {
+			return false
+		}
+
+		for j := 0; j < len(subtag); j++ {
+			c := subtag[j]
+			isAlpha := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+			isDigit := c >= '0' && c <= '9'
+
+			if !isAlpha && (i == 0 || !isDigit) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,104 @@
+package gohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	header := http.Header{
+		"Accept-Language": {"da, en-gb;q=0.8, en;q=0.7", "*;q=0.1, invalid_tag, de-CH-1996"},
+	}
+
+	expected := []LanguageRange{
+		{Tag: "da", Quality: 1},
+		{Tag: "de-CH-1996", Quality: 1},
+		{Tag: "en-gb", Quality: 0.8},
+		{Tag: "en", Quality: 0.7},
+		{Tag: "*", Quality: 0.1},
+	}
+
+	if actual := ParseAcceptLanguage(header); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected language ranges %v, got %v", expected, actual)
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	available := []string{"en-US", "de", "fr-CA"}
+
+	testCases := map[string]struct {
+		acceptLanguage string
+		expected       string
+	}{
+		"exact match": {
+			acceptLanguage: "de",
+			expected:       "de",
+		},
+		"truncated range": {
+			acceptLanguage: "de-CH-1996, fr;q=0.5",
+			expected:       "de",
+		},
+		"more specific tag": {
+			acceptLanguage: "en, de;q=0.5",
+			expected:       "en-US",
+		},
+		"case-insensitive": {
+			acceptLanguage: "FR-ca",
+			expected:       "fr-CA",
+		},
+		"quality order": {
+			acceptLanguage: "de;q=0.5, fr-CA",
+			expected:       "fr-CA",
+		},
+		"wildcard with exclusion": {
+			acceptLanguage: "it, *;q=0.5, en-US;q=0",
+			expected:       "de",
+		},
+		"fallback": {
+			acceptLanguage: "it, es",
+			expected:       "en-US",
+		},
+		"none": {
+			expected: "en-US",
+		},
+	}
+
+	for name, tc := range testCases {
+		header := http.Header{}
+		if tc.acceptLanguage != "" {
+			header.Set("Accept-Language", tc.acceptLanguage)
+		}
+
+		if actual := NegotiateLanguage(header, available, "en-US"); actual != tc.expected {
+			t.Errorf("'%s': expected language %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestLanguageMiddleware(t *testing.T) {
+	var negotiated string
+
+	handler := LanguageMiddleware([]string{"en", "de"}, "en", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		negotiated = LanguageFromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept-Language", "de-AT")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if negotiated != "de" {
+		t.Errorf("expected language %s, got %s", "de", negotiated)
+	}
+
+	if language := recorder.Header().Get("Content-Language"); language != "de" {
+		t.Errorf("expected Content-Language %s, got %s", "de", language)
+	}
+
+	if vary := recorder.Header().Get("Vary"); vary != "Accept-Language" {
+		t.Errorf("expected Vary %s, got %s", "Accept-Language", vary)
+	}
+}
@@ -0,0 +1,76 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrURITooLong indicates a request target exceeding the length set with
+	// WithMaxURILength.
+	ErrURITooLong = errors.New("request target too long")
+	// ErrMethodTooLong indicates a method exceeding the length set with
+	// WithMaxMethodLength.
+	ErrMethodTooLong = errors.New("method too long")
+)
+
+// RequestLineLimitError is returned by the parser for a request line element
+// exceeding its length limit. It wraps ErrURITooLong or ErrMethodTooLong.
+type RequestLineLimitError struct {
+	// Length is the length of the offending element.
+	Length int
+	// Limit is the configured maximum length.
+	Limit int
+	// Err is the reason why the request line has been rejected.
+	Err error
+}
+
+func (r *RequestLineLimitError) Error() string {
+	return fmt.Sprintf("%s: %d bytes exceed the limit of %d", r.Err.Error(), r.Length, r.Limit)
+}
+
+func (r *RequestLineLimitError) Unwrap() error {
+	return r.Err
+}
+
+// StatusCode returns the status code for responding to the request: 414 for
+// a request target that is too long (RFC 7231, section 6.5.12.), and 501 for
+// a method that is too long, since no implemented method is that long.
+func (r *RequestLineLimitError) StatusCode() int {
+	if errors.Is(r.Err, ErrURITooLong) {
+		return http.StatusRequestURITooLong
+	}
+	return http.StatusNotImplemented
+}
+
+// WithMaxURILength limits the length of the request target. Longer targets
+// are rejected with a RequestLineLimitError before the URL is parsed. Use
+// WithMaxLineLength to limit how much of the request line is read at all.
+func WithMaxURILength(length int) Option {
+	return func(c *config) {
+		c.maxURILength = length
+	}
+}
+
+// WithMaxMethodLength limits the length of the method token. Longer methods
+// are rejected with a RequestLineLimitError.
+func WithMaxMethodLength(length int) Option {
+	return func(c *config) {
+		c.maxMethodLength = length
+	}
+}
+
+// checkRequestLineLimits checks the method and the request target against
+// the configured limits.
+func checkRequestLineLimits(method, target string, config config) error {
+	if config.maxMethodLength > 0 && len(method) > config.maxMethodLength {
+		return &RequestLineLimitError{Length: len(method), Limit: config.maxMethodLength, Err: ErrMethodTooLong}
+	}
+
+	if config.maxURILength > 0 && len(target) > config.maxURILength {
+		return &RequestLineLimitError{Length: len(target), Limit: config.maxURILength, Err: ErrURITooLong}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRequestLineLimits(t *testing.T) {
+	testCases := map[string]struct {
+		requestLine    string
+		expectedError  error
+		expectedStatus int
+	}{
+		"within limits": {
+			requestLine: "GET /index.html HTTP/1.1",
+		},
+		"target too long": {
+			requestLine:    "GET /" + strings.Repeat("a", 32) + " HTTP/1.1",
+			expectedError:  ErrURITooLong,
+			expectedStatus: 414,
+		},
+		"method too long": {
+			requestLine:    strings.Repeat("G", 9) + " / HTTP/1.1",
+			expectedError:  ErrMethodTooLong,
+			expectedStatus: 501,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := tc.requestLine + "\r\n\r\n"
+
+		_, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), WithMaxURILength(32), WithMaxMethodLength(8))
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		var limitErr *RequestLineLimitError
+		if errors.As(err, &limitErr) && limitErr.StatusCode() != tc.expectedStatus {
+			t.Errorf("'%s': expected status %d, got %d", name, tc.expectedStatus, limitErr.StatusCode())
+		}
+	}
+}
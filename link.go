@@ -0,0 +1,194 @@
+package gohttp
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Link represents a single link of a Link header field (RFC 8288).
+type Link struct {
+	// Target is the target URI as given, i.e. it may be relative.
+	Target string
+	// Rel is the relation type, which may consist of multiple space-separated
+	// relation types, e.g. "next" or "preload prefetch".
+	Rel string
+	// Anchor is the context URI if it differs from the request URI.
+	Anchor string
+	// Params holds all other target attributes like "type" or "title" with
+	// lowercase names.
+	Params map[string]string
+}
+
+// HasRel reports whether the link has the given relation type. Relation
+// types are compared case-insensitively (RFC 8288, section 2.1.).
+func (l Link) HasRel(rel string) bool {
+	for _, r := range strings.Fields(l.Rel) {
+		if strings.EqualFold(r, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the link as a link-value of a Link header field.
+func (l Link) String() string {
+	var builder strings.Builder
+
+	builder.WriteString("<" + l.Target + ">")
+
+	if l.Rel != "" {
+		builder.WriteString("; rel=" + quoteString(l.Rel))
+	}
+
+	if l.Anchor != "" {
+		builder.WriteString("; anchor=" + quoteString(l.Anchor))
+	}
+
+	names := make([]string, 0, len(l.Params))
+	for name := range l.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		builder.WriteString("; " + name)
+		if value := l.Params[name]; value != "" {
+			builder.WriteString("=" + quoteIfNeeded(value))
+		}
+	}
+
+	return builder.String()
+}
+
+// ParseLinks parses all Link header fields.
+func ParseLinks(header http.Header) ([]Link, error) {
+	var links []Link
+
+	for _, value := range header.Values("Link") {
+		elements, err := splitLinkValues(value)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, element := range elements {
+			if strings.TrimSpace(element) == "" {
+				continue
+			}
+
+			link, err := parseLink(element)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// FindLink returns the first link with the given relation type, e.g. the
+// "next" link of a paginated resource.
+func FindLink(links []Link, rel string) (Link, bool) {
+	for _, link := range links {
+		if link.HasRel(rel) {
+			return link, true
+		}
+	}
+	return Link{}, false
+}
+
+// AddLink appends the given link to the Link header field.
+func AddLink(header http.Header, link Link) {
+	appendListValue(header, "Link", link.String())
+}
+
+func parseLink(element string) (Link, error) {
+	element = strings.TrimSpace(element)
+
+	end := strings.IndexByte(element, '>')
+	if !strings.HasPrefix(element, "<") || end < 0 {
+		return Link{}, errors.New("invalid link target syntax")
+	}
+
+	link := Link{
+		Target: element[1:end],
+	}
+
+	params, err := splitQuoted(element[end+1:], ';')
+	if err != nil {
+		return Link{}, err
+	}
+
+	for _, param := range params {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+
+		tokens := strings.SplitN(param, "=", 2)
+		name := strings.ToLower(strings.TrimSpace(tokens[0]))
+
+		var value string
+		if len(tokens) == 2 {
+			if value, err = unquote(strings.TrimSpace(tokens[1])); err != nil {
+				return Link{}, err
+			}
+		}
+
+		// Occurrences after the first rel and anchor parameters are ignored
+		// (RFC 8288, section 3.3.).
+		switch {
+		case name == "rel":
+			if link.Rel == "" {
+				link.Rel = value
+			}
+		case name == "anchor":
+			if link.Anchor == "" {
+				link.Anchor = value
+			}
+		default:
+			if link.Params == nil {
+				link.Params = make(map[string]string)
+			}
+			if _, exists := link.Params[name]; !exists {
+				link.Params[name] = value
+			}
+		}
+	}
+
+	return link, nil
+}
+
+// splitLinkValues splits a Link header field into link-values. Commas are
+// ignored within the target URI and within quoted-strings.
+func splitLinkValues(s string) ([]string, error) {
+	var parts []string
+	var quoted, escaped, inTarget bool
+	var start int
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case !inTarget && s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == '<':
+			inTarget = true
+		case !quoted && s[i] == '>':
+			inTarget = false
+		case !quoted && !inTarget && s[i] == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	if quoted || inTarget {
+		return nil, errors.New("unterminated link-value")
+	}
+
+	return append(parts, s[start:]), nil
+}
@@ -0,0 +1,109 @@
+package gohttp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinks(t *testing.T) {
+	testCases := map[string]struct {
+		values   []string
+		expected []Link
+	}{
+		"pagination": {
+			values: []string{`<https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=9>; rel="last"`},
+			expected: []Link{
+				{Target: "https://api.example.com/items?page=2", Rel: "next"},
+				{Target: "https://api.example.com/items?page=9", Rel: "last"},
+			},
+		},
+		"comma in target": {
+			values: []string{`</a,b>; rel=preload; as=style`},
+			expected: []Link{
+				{Target: "/a,b", Rel: "preload", Params: map[string]string{"as": "style"}},
+			},
+		},
+		"anchor and quoted params": {
+			values: []string{`<terms>; REL="copyright license"; anchor="#foo"; title="a, b"`},
+			expected: []Link{
+				{Target: "terms", Rel: "copyright license", Anchor: "#foo", Params: map[string]string{"title": "a, b"}},
+			},
+		},
+		"multiple fields": {
+			values: []string{`</style.css>; rel=preload`, `</script.js>; rel=preload; nopush`},
+			expected: []Link{
+				{Target: "/style.css", Rel: "preload"},
+				{Target: "/script.js", Rel: "preload", Params: map[string]string{"nopush": ""}},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, err := ParseLinks(http.Header{"Link": tc.values})
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("'%s': expected links %v, got %v", name, tc.expected, actual)
+		}
+	}
+
+	for _, value := range []string{`<unterminated`, `no-target; rel=next`} {
+		if _, err := ParseLinks(http.Header{"Link": {value}}); err == nil {
+			t.Errorf("expected an error for %s, got none", value)
+		}
+	}
+}
+
+func TestLink_String(t *testing.T) {
+	link := Link{
+		Target: "/style.css",
+		Rel:    "preload",
+		Params: map[string]string{"as": "style", "title": "Main style"},
+	}
+
+	expected := `</style.css>; rel="preload"; as=style; title="Main style"`
+
+	if actual := link.String(); actual != expected {
+		t.Errorf("expected link %s, got %s", expected, actual)
+	}
+
+	header := make(http.Header)
+	AddLink(header, link)
+	AddLink(header, Link{Target: "/next", Rel: "next"})
+
+	links, err := ParseLinks(header)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if next, ok := FindLink(links, "NEXT"); !ok || next.Target != "/next" {
+		t.Errorf("expected next link %s, got %v", "/next", next)
+	}
+}
+
+func TestLink_String_Escaping(t *testing.T) {
+	link := Link{
+		Target: "/a",
+		Rel:    `x"; rel="evil`,
+		Anchor: `/b\c`,
+		Params: map[string]string{"title": `say "hi"`},
+	}
+
+	expected := `</a>; rel="x\"; rel=\"evil"; anchor="/b\\c"; title="say \"hi\""`
+
+	if actual := link.String(); actual != expected {
+		t.Errorf("expected link %s, got %s", expected, actual)
+	}
+
+	links, err := ParseLinks(http.Header{"Link": {link.String()}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(links) != 1 || links[0].Rel != link.Rel || links[0].Anchor != link.Anchor || links[0].Params["title"] != link.Params["title"] {
+		t.Errorf("expected link %+v, got %+v", link, links)
+	}
+}
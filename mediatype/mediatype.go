@@ -0,0 +1,188 @@
+// Package mediatype provides a registry of media types with their file
+// extensions, default charsets, and whether they benefit from compression.
+// The registry comes with an embedded set of common types that can be
+// overridden and extended at runtime, independently of the system's MIME
+// database.
+package mediatype
+
+import (
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Type describes a media type.
+type Type struct {
+	// Name is the media type without parameters, e.g. "text/html".
+	Name string
+	// Extensions are the file extensions of the media type including the
+	// leading dot, e.g. ".html". The first extension is the preferred one.
+	Extensions []string
+	// Charset is the default charset, e.g. "utf-8", or an empty string.
+	Charset string
+	// Compressible indicates whether compressing the content is worthwhile.
+	Compressible bool
+}
+
+// ContentType returns the value for a Content-Type header field, including
+// the default charset if there is one.
+func (t Type) ContentType() string {
+	if t.Charset == "" {
+		return t.Name
+	}
+	return t.Name + "; charset=" + t.Charset
+}
+
+// Registry holds media types by name and by extension. It is safe for
+// concurrent use.
+type Registry struct {
+	mutex       sync.RWMutex
+	byName      map[string]Type
+	byExtension map[string]Type
+}
+
+// NewRegistry creates a new Registry holding the embedded media types.
+func NewRegistry() *Registry {
+	registry := &Registry{
+		byName:      make(map[string]Type),
+		byExtension: make(map[string]Type),
+	}
+
+	for _, t := range builtinTypes {
+		registry.Register(t)
+	}
+
+	return registry
+}
+
+// Register adds a media type to the registry. A media type with the same
+// name and types registered for the same extensions are overridden.
+func (r *Registry) Register(t Type) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	t.Name = strings.ToLower(t.Name)
+	r.byName[t.Name] = t
+
+	for _, extension := range t.Extensions {
+		r.byExtension[normalizeExtension(extension)] = t
+	}
+}
+
+// ByName returns the media type with the given name. Parameters like the
+// charset of a Content-Type value are ignored.
+func (r *Registry) ByName(name string) (Type, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	t, ok := r.byName[baseType(name)]
+	return t, ok
+}
+
+// ByExtension returns the media type for the given file extension, with or
+// without the leading dot.
+func (r *Registry) ByExtension(extension string) (Type, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	t, ok := r.byExtension[normalizeExtension(extension)]
+	return t, ok
+}
+
+// IsCompressible reports whether content of the given media type should be
+// compressed. Unregistered text types and types with a +json or +xml suffix
+// are considered compressible.
+func (r *Registry) IsCompressible(name string) bool {
+	if t, ok := r.ByName(name); ok {
+		return t.Compressible
+	}
+
+	name = baseType(name)
+
+	return strings.HasPrefix(name, "text/") ||
+		strings.HasSuffix(name, "+json") ||
+		strings.HasSuffix(name, "+xml")
+}
+
+// Default is the registry used by the package-level functions.
+var Default = NewRegistry()
+
+// Register adds a media type to the Default registry.
+func Register(t Type) {
+	Default.Register(t)
+}
+
+// ByName returns the media type with the given name from the Default
+// registry.
+func ByName(name string) (Type, bool) {
+	return Default.ByName(name)
+}
+
+// ByExtension returns the media type for the given file extension from the
+// Default registry.
+func ByExtension(extension string) (Type, bool) {
+	return Default.ByExtension(extension)
+}
+
+// IsCompressible reports whether content of the given media type should be
+// compressed according to the Default registry.
+func IsCompressible(name string) bool {
+	return Default.IsCompressible(name)
+}
+
+func normalizeExtension(extension string) string {
+	extension = strings.ToLower(extension)
+	if !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	return extension
+}
+
+func baseType(name string) string {
+	if mediaType, _, err := mime.ParseMediaType(name); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(strings.SplitN(name, ";", 2)[0]))
+}
+
+// builtinTypes are the media types every registry starts with.
+var builtinTypes = []Type{
+	{Name: "text/html", Extensions: []string{".html", ".htm"}, Charset: "utf-8", Compressible: true},
+	{Name: "text/css", Extensions: []string{".css"}, Charset: "utf-8", Compressible: true},
+	{Name: "text/plain", Extensions: []string{".txt", ".text", ".log"}, Charset: "utf-8", Compressible: true},
+	{Name: "text/csv", Extensions: []string{".csv"}, Charset: "utf-8", Compressible: true},
+	{Name: "text/markdown", Extensions: []string{".md", ".markdown"}, Charset: "utf-8", Compressible: true},
+	{Name: "text/xml", Extensions: []string{".xml"}, Charset: "utf-8", Compressible: true},
+	{Name: "text/javascript", Extensions: []string{".js", ".mjs"}, Charset: "utf-8", Compressible: true},
+	{Name: "text/calendar", Extensions: []string{".ics"}, Charset: "utf-8", Compressible: true},
+	{Name: "application/json", Extensions: []string{".json", ".map"}, Compressible: true},
+	{Name: "application/ld+json", Extensions: []string{".jsonld"}, Compressible: true},
+	{Name: "application/manifest+json", Extensions: []string{".webmanifest"}, Compressible: true},
+	{Name: "application/xhtml+xml", Extensions: []string{".xhtml"}, Compressible: true},
+	{Name: "application/rss+xml", Extensions: []string{".rss"}, Compressible: true},
+	{Name: "application/atom+xml", Extensions: []string{".atom"}, Compressible: true},
+	{Name: "application/wasm", Extensions: []string{".wasm"}, Compressible: true},
+	{Name: "application/yaml", Extensions: []string{".yaml", ".yml"}, Compressible: true},
+	{Name: "application/toml", Extensions: []string{".toml"}, Compressible: true},
+	{Name: "application/pdf", Extensions: []string{".pdf"}},
+	{Name: "application/zip", Extensions: []string{".zip"}},
+	{Name: "application/gzip", Extensions: []string{".gz"}},
+	{Name: "application/x-tar", Extensions: []string{".tar"}, Compressible: true},
+	{Name: "application/octet-stream", Extensions: []string{".bin", ".exe", ".dll"}},
+	{Name: "image/svg+xml", Extensions: []string{".svg"}, Compressible: true},
+	{Name: "image/png", Extensions: []string{".png"}},
+	{Name: "image/jpeg", Extensions: []string{".jpg", ".jpeg"}},
+	{Name: "image/gif", Extensions: []string{".gif"}},
+	{Name: "image/webp", Extensions: []string{".webp"}},
+	{Name: "image/avif", Extensions: []string{".avif"}},
+	{Name: "image/x-icon", Extensions: []string{".ico"}, Compressible: true},
+	{Name: "font/woff", Extensions: []string{".woff"}},
+	{Name: "font/woff2", Extensions: []string{".woff2"}},
+	{Name: "font/ttf", Extensions: []string{".ttf"}, Compressible: true},
+	{Name: "font/otf", Extensions: []string{".otf"}, Compressible: true},
+	{Name: "audio/mpeg", Extensions: []string{".mp3"}},
+	{Name: "audio/ogg", Extensions: []string{".oga", ".ogg"}},
+	{Name: "audio/wav", Extensions: []string{".wav"}},
+	{Name: "video/mp4", Extensions: []string{".mp4"}},
+	{Name: "video/webm", Extensions: []string{".webm"}},
+}
@@ -0,0 +1,74 @@
+package mediatype
+
+import (
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(Type{Name: "application/vnd.example+json", Extensions: []string{"EXM"}})
+	registry.Register(Type{Name: "text/x-markdown", Extensions: []string{".md"}, Charset: "utf-8", Compressible: true})
+
+	testCases := map[string]struct {
+		extension           string
+		expectedContentType string
+		expectedOK          bool
+	}{
+		"builtin type with charset": {
+			extension:           ".html",
+			expectedContentType: "text/html; charset=utf-8",
+			expectedOK:          true,
+		},
+		"builtin type without dot": {
+			extension:           "PNG",
+			expectedContentType: "image/png",
+			expectedOK:          true,
+		},
+		"custom type": {
+			extension:           ".exm",
+			expectedContentType: "application/vnd.example+json",
+			expectedOK:          true,
+		},
+		"overridden extension": {
+			extension:           ".md",
+			expectedContentType: "text/x-markdown; charset=utf-8",
+			expectedOK:          true,
+		},
+		"unknown extension": {
+			extension: ".unknown",
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, ok := registry.ByExtension(tc.extension)
+		if ok != tc.expectedOK {
+			t.Errorf("'%s': expected ok %v, got %v", name, tc.expectedOK, ok)
+			continue
+		}
+
+		if ok && actual.ContentType() != tc.expectedContentType {
+			t.Errorf("'%s': expected content type %s, got %s", name, tc.expectedContentType, actual.ContentType())
+		}
+	}
+
+	if _, ok := registry.ByName("Text/HTML; charset=iso-8859-1"); !ok {
+		t.Errorf("expected text/html to be found by a Content-Type value")
+	}
+}
+
+func TestIsCompressible(t *testing.T) {
+	testCases := map[string]bool{
+		"text/html; charset=utf-8":     true,
+		"image/png":                    false,
+		"image/svg+xml":                true,
+		"text/x-unregistered":          true,
+		"application/vnd.example+json": true,
+		"application/x-unregistered":   false,
+	}
+
+	for name, expected := range testCases {
+		if actual := IsCompressible(name); actual != expected {
+			t.Errorf("'%s': expected compressible %v, got %v", name, expected, actual)
+		}
+	}
+}
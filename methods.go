@@ -0,0 +1,244 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrUnknownMethod is returned by the parser for a method that isn't
+	// registered if the policy MethodsRejectUnknown is set.
+	ErrUnknownMethod = errors.New("unknown method")
+	// ErrMethodNotAllowed is returned by the parser for a method that isn't
+	// allowed by WithAllowedMethods.
+	ErrMethodNotAllowed = errors.New("method not allowed")
+)
+
+// MethodError is returned by the parser for a request method rejected by the
+// method policy. It wraps ErrUnknownMethod or ErrMethodNotAllowed.
+type MethodError struct {
+	// Method is the rejected request method.
+	Method string
+	// Err is the reason why the method has been rejected.
+	Err error
+}
+
+func (m *MethodError) Error() string {
+	return fmt.Sprintf("%s: %s", m.Err.Error(), m.Method)
+}
+
+func (m *MethodError) Unwrap() error {
+	return m.Err
+}
+
+// StatusCode returns the status code for responding to the request: 501 for
+// an unknown method, and 405 for a known method that isn't allowed (RFC 7231,
+// section 4.1.).
+func (m *MethodError) StatusCode() int {
+	if errors.Is(m.Err, ErrUnknownMethod) {
+		return http.StatusNotImplemented
+	}
+	return http.StatusMethodNotAllowed
+}
+
+// MethodProperties describes the semantics of a method.
+type MethodProperties struct {
+	// Safe methods are essentially read-only (RFC 7231, section 4.2.1.).
+	Safe bool
+	// Idempotent methods can be repeated with the same effect, e.g. when
+	// retrying after a connection failure (RFC 7231, section 4.2.2.).
+	Idempotent bool
+	// AllowsBody indicates that a request body has defined semantics.
+	AllowsBody bool
+}
+
+var (
+	methodsMutex sync.RWMutex
+	// knownMethods are the methods defined by RFC 7231, RFC 5789, and RFC
+	// 4918 (WebDAV), plus the ones added with RegisterMethod.
+	knownMethods = map[string]MethodProperties{
+		http.MethodGet:     {Safe: true, Idempotent: true},
+		http.MethodHead:    {Safe: true, Idempotent: true},
+		http.MethodPost:    {AllowsBody: true},
+		http.MethodPut:     {Idempotent: true, AllowsBody: true},
+		http.MethodPatch:   {AllowsBody: true},
+		http.MethodDelete:  {Idempotent: true},
+		http.MethodConnect: {},
+		http.MethodOptions: {Safe: true, Idempotent: true, AllowsBody: true},
+		http.MethodTrace:   {Safe: true, Idempotent: true},
+		"PROPFIND":         {Safe: true, Idempotent: true, AllowsBody: true},
+		"PROPPATCH":        {Idempotent: true, AllowsBody: true},
+		"MKCOL":            {Idempotent: true, AllowsBody: true},
+		"COPY":             {Idempotent: true},
+		"MOVE":             {Idempotent: true},
+		"LOCK":             {AllowsBody: true},
+		"UNLOCK":           {Idempotent: true},
+	}
+)
+
+// RegisterMethod registers an extension method with its properties, or
+// overrides the properties of a known method.
+func RegisterMethod(method string, properties MethodProperties) {
+	methodsMutex.Lock()
+	defer methodsMutex.Unlock()
+
+	knownMethods[method] = properties
+}
+
+// LookupMethod returns the properties of a method and whether the method is
+// known.
+func LookupMethod(method string) (MethodProperties, bool) {
+	methodsMutex.RLock()
+	defer methodsMutex.RUnlock()
+
+	properties, ok := knownMethods[method]
+	return properties, ok
+}
+
+// IsKnownMethod reports whether the method is known.
+func IsKnownMethod(method string) bool {
+	_, ok := LookupMethod(method)
+	return ok
+}
+
+// IsSafe reports whether the method is known to be safe.
+func IsSafe(method string) bool {
+	properties, _ := LookupMethod(method)
+	return properties.Safe
+}
+
+// IsIdempotent reports whether the method is known to be idempotent. Unknown
+// methods are considered non-idempotent, so they must not be retried.
+func IsIdempotent(method string) bool {
+	properties, _ := LookupMethod(method)
+	return properties.Idempotent
+}
+
+// AllowsBody reports whether a request body has defined semantics for the
+// method. Unknown methods are assumed to allow a body.
+func AllowsBody(method string) bool {
+	properties, ok := LookupMethod(method)
+	return !ok || properties.AllowsBody
+}
+
+// MethodPolicy determines how the parser treats request methods that aren't
+// known.
+type MethodPolicy int
+
+const (
+	// MethodsAllowUnknown accepts all syntactically valid methods.
+	MethodsAllowUnknown MethodPolicy = iota
+	// MethodsRejectUnknown fails parsing with a MethodError for methods
+	// that aren't known.
+	MethodsRejectUnknown
+)
+
+// WithMethodPolicy sets the policy for unknown request methods.
+func WithMethodPolicy(policy MethodPolicy) Option {
+	return func(c *config) {
+		c.methodPolicy = policy
+	}
+}
+
+// WithAllowedMethods restricts requests to the given methods. Other methods
+// fail parsing with a MethodError.
+func WithAllowedMethods(methods ...string) Option {
+	return func(c *config) {
+		c.allowedMethods = append(c.allowedMethods, methods...)
+	}
+}
+
+// checkMethod applies the method policy to a request method.
+func checkMethod(method string, config config) error {
+	known := IsKnownMethod(method)
+
+	if !known && config.methodPolicy == MethodsRejectUnknown {
+		return &MethodError{Method: method, Err: ErrUnknownMethod}
+	}
+
+	if len(config.allowedMethods) > 0 && !containsMethod(config.allowedMethods, method) {
+		if !known {
+			return &MethodError{Method: method, Err: ErrUnknownMethod}
+		}
+		return &MethodError{Method: method, Err: ErrMethodNotAllowed}
+	}
+
+	return nil
+}
+
+// MethodNotAllowed creates a response for a request whose method isn't
+// supported by its target. The response is 405 Method Not Allowed with an
+// Allow header field listing the given methods, or 501 Not Implemented if
+// the request method isn't known at all (RFC 7231, section 4.1.).
+func MethodNotAllowed(r *http.Request, methods ...string) *http.Response {
+	if !IsKnownMethod(r.Method) && !containsMethod(methods, r.Method) {
+		return NewResponse(http.StatusNotImplemented, nil)
+	}
+
+	response := NewResponse(http.StatusMethodNotAllowed, nil)
+	response.Header.Set("Allow", allowValue(methods))
+
+	return response
+}
+
+// MethodMux is an http.Handler that dispatches requests to handlers by
+// their method. Requests with other methods are answered just like with
+// MethodNotAllowed. A MethodMux can be registered in a VHostMux to restrict
+// the methods of a virtual host.
+type MethodMux map[string]http.Handler
+
+// Methods returns the supported methods in sorted order.
+func (m MethodMux) Methods() []string {
+	methods := make([]string, 0, len(m))
+	for method := range m {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	return methods
+}
+
+// ServeHTTP dispatches the request to the handler registered for its method.
+func (m MethodMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handler, ok := m[r.Method]; ok {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	if !IsKnownMethod(r.Method) {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Allow", allowValue(m.Methods()))
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+// allowValue returns the value of an Allow header field for the given
+// methods without duplicates.
+func allowValue(methods []string) string {
+	seen := make(map[string]bool, len(methods))
+	var unique []string
+
+	for _, method := range methods {
+		if !seen[method] {
+			seen[method] = true
+			unique = append(unique, method)
+		}
+	}
+
+	return strings.Join(unique, ", ")
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
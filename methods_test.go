@@ -0,0 +1,165 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	testCases := map[string]struct {
+		method         string
+		methods        []string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		"known method": {
+			method:         http.MethodDelete,
+			methods:        []string{http.MethodGet, http.MethodHead, http.MethodGet},
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedAllow:  "GET, HEAD",
+		},
+		"unknown method": {
+			method:         "BREW",
+			methods:        []string{http.MethodGet},
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest(tc.method, "/", nil)
+		response := MethodNotAllowed(request, tc.methods...)
+
+		if response.StatusCode != tc.expectedStatus {
+			t.Errorf("'%s': expected status code %d, got %d", name, tc.expectedStatus, response.StatusCode)
+		}
+
+		if allow := response.Header.Get("Allow"); allow != tc.expectedAllow {
+			t.Errorf("'%s': expected Allow %s, got %s", name, tc.expectedAllow, allow)
+		}
+	}
+}
+
+func TestMethodMux(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mux := NewVHostMux()
+	mux.Handle("example.com", MethodMux{
+		http.MethodPost: ok,
+		http.MethodGet:  ok,
+	})
+
+	testCases := map[string]struct {
+		method         string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		"supported method": {
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+		},
+		"unsupported method": {
+			method:         http.MethodPut,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedAllow:  "GET, POST",
+		},
+		"unknown method": {
+			method:         "BREW",
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for name, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(tc.method, "http://example.com/", nil))
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("'%s': expected status code %d, got %d", name, tc.expectedStatus, recorder.Code)
+		}
+
+		if allow := recorder.Header().Get("Allow"); allow != tc.expectedAllow {
+			t.Errorf("'%s': expected Allow %s, got %s", name, tc.expectedAllow, allow)
+		}
+	}
+}
+
+func TestMethodProperties(t *testing.T) {
+	testCases := map[string]struct {
+		method             string
+		expectedSafe       bool
+		expectedIdempotent bool
+		expectedAllowsBody bool
+	}{
+		"GET":      {method: http.MethodGet, expectedSafe: true, expectedIdempotent: true},
+		"POST":     {method: http.MethodPost, expectedAllowsBody: true},
+		"PUT":      {method: http.MethodPut, expectedIdempotent: true, expectedAllowsBody: true},
+		"PROPFIND": {method: "PROPFIND", expectedSafe: true, expectedIdempotent: true, expectedAllowsBody: true},
+		"unknown":  {method: "BREW", expectedAllowsBody: true},
+	}
+
+	for name, tc := range testCases {
+		if IsSafe(tc.method) != tc.expectedSafe {
+			t.Errorf("'%s': expected safe %v, got %v", name, tc.expectedSafe, IsSafe(tc.method))
+		}
+
+		if IsIdempotent(tc.method) != tc.expectedIdempotent {
+			t.Errorf("'%s': expected idempotent %v, got %v", name, tc.expectedIdempotent, IsIdempotent(tc.method))
+		}
+
+		if AllowsBody(tc.method) != tc.expectedAllowsBody {
+			t.Errorf("'%s': expected allows body %v, got %v", name, tc.expectedAllowsBody, AllowsBody(tc.method))
+		}
+	}
+}
+
+func TestMethodPolicy(t *testing.T) {
+	testCases := map[string]struct {
+		method         string
+		options        []Option
+		expectedError  error
+		expectedStatus int
+	}{
+		"unknown allowed by default": {
+			method: "BREW",
+		},
+		"unknown rejected": {
+			method:         "BREW",
+			options:        []Option{WithMethodPolicy(MethodsRejectUnknown)},
+			expectedError:  ErrUnknownMethod,
+			expectedStatus: http.StatusNotImplemented,
+		},
+		"WebDAV method known": {
+			method:  "PROPFIND",
+			options: []Option{WithMethodPolicy(MethodsRejectUnknown)},
+		},
+		"restricted": {
+			method:         http.MethodDelete,
+			options:        []Option{WithAllowedMethods(http.MethodGet, http.MethodHead)},
+			expectedError:  ErrMethodNotAllowed,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+		"allowed but not registered": {
+			method:         "BREW",
+			options:        []Option{WithMethodPolicy(MethodsRejectUnknown), WithAllowedMethods("BREW")},
+			expectedError:  ErrUnknownMethod,
+			expectedStatus: http.StatusNotImplemented,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := tc.method + " / HTTP/1.1\r\n\r\n"
+
+		_, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), tc.options...)
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		var methodErr *MethodError
+		if errors.As(err, &methodErr) && methodErr.StatusCode() != tc.expectedStatus {
+			t.Errorf("'%s': expected status %d, got %d", name, tc.expectedStatus, methodErr.StatusCode())
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package metrics collects metrics about parsed traffic, like transaction
+// rates, status classes, message sizes, latencies, and parse errors. The
+// collected observations are passed to a Metrics implementation, e.g. the
+// Prometheus exporter or the statsd emitter of this package, so that the
+// monitoring system can be chosen without changing the collection.
+package metrics
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// otherMethod is the method label of requests with an unknown method.
+const otherMethod = "OTHER"
+
+// Labels are the attributes of a transaction that metrics are broken down
+// by.
+type Labels struct {
+	// Host is the host as returned by the HostFunc of the Collector.
+	Host string
+	// Route is the route as returned by the RouteFunc of the Collector.
+	Route string
+	// Method is the request method, or "OTHER" for a method that isn't
+	// known to gohttp.IsKnownMethod.
+	Method string
+	// StatusClass is the class of the response status code, e.g. "2xx". It
+	// is empty for a transaction without a response.
+	StatusClass string
+}
+
+// Observation is a completed transaction.
+type Observation struct {
+	Labels Labels
+	// RequestBytes and ResponseBytes are the total sizes of the messages as
+	// transmitted.
+	RequestBytes  int64
+	ResponseBytes int64
+	// Latency is the time from the first byte of the request to the end of
+	// the response. It is zero if the timings haven't been recorded.
+	Latency time.Duration
+}
+
+// Metrics receives the observations of a Collector and of other components,
+// e.g. an ipfilter.Filter. Implementations must be safe for concurrent use.
+type Metrics interface {
+	// Transaction records a completed transaction.
+	Transaction(observation Observation)
+	// ParseError records a message that couldn't be parsed, along with the
+	// status code a server would respond with.
+	ParseError(statusCode int)
+	// Connection records a connection that has been accepted or rejected by
+	// a connection-level filter.
+	Connection(accepted bool)
+}
+
+// RouteFunc maps a request to the route its metrics are recorded under.
+type RouteFunc func(request *http.Request) string
+
+// HostFunc maps a request to the host its metrics are recorded under.
+type HostFunc func(request *http.Request) string
+
+// Collector derives observations from parsed messages and their sizes and
+// timings as measured by gohttp.WithSizes and gohttp.WithTiming, and passes
+// them to a Metrics implementation. It is safe for concurrent use if the
+// Metrics implementation is.
+type Collector struct {
+	metrics   Metrics
+	routeFunc RouteFunc
+	// HostFunc maps requests to their host label. If nil, the host label is
+	// left empty, since the Host header field is chosen by the client and
+	// may create an unbounded number of series. Host can be used if the
+	// hosts are known to be bounded, e.g. behind a virtual host router.
+	HostFunc HostFunc
+}
+
+// NewCollector creates a new Collector passing its observations to metrics.
+// If routeFunc is nil, the route label is left empty, since using the raw
+// path as a label may create an unbounded number of series.
+func NewCollector(metrics Metrics, routeFunc RouteFunc) *Collector {
+	return &Collector{
+		metrics:   metrics,
+		routeFunc: routeFunc,
+	}
+}
+
+// Observe records a request and its response. The response may be nil. The
+// sizes and timings are those measured while parsing the messages, and the
+// latency is left zero if the timings are zero.
+func (c *Collector) Observe(request *http.Request, response *http.Response, requestSizes, responseSizes gohttp.Sizes, requestTiming, responseTiming gohttp.MessageTiming) {
+	observation := Observation{
+		Labels: Labels{
+			Method: method(request.Method),
+		},
+		RequestBytes: requestSizes.Total(),
+	}
+
+	if c.HostFunc != nil {
+		observation.Labels.Host = c.HostFunc(request)
+	}
+
+	if c.routeFunc != nil {
+		observation.Labels.Route = c.routeFunc(request)
+	}
+
+	if response != nil {
+		observation.Labels.StatusClass = statusClass(response.StatusCode)
+		observation.ResponseBytes = responseSizes.Total()
+	}
+
+	end := responseTiming.BodyComplete
+	if end.IsZero() {
+		end = responseTiming.HeadersComplete
+	}
+
+	if !requestTiming.FirstByte.IsZero() && !end.IsZero() {
+		observation.Latency = end.Sub(requestTiming.FirstByte)
+	}
+
+	c.metrics.Transaction(observation)
+}
+
+// ObserveTransaction parses a serialized transaction and observes it. A
+// message that can't be parsed is recorded as a parse error.
+func (c *Collector) ObserveTransaction(transaction gohttp.Transaction) error {
+	request, response, err := transaction.Parse()
+	if err != nil {
+		c.ObserveParseError(err)
+		return err
+	}
+
+	c.Observe(request, response, transaction.RequestSizes, transaction.ResponseSizes,
+		transaction.RequestTiming, transaction.ResponseTiming)
+
+	return nil
+}
+
+// ObserveParseError records an error returned by the parser. The status
+// code is taken from the error if it provides one, e.g. 414 for a
+// RequestLineLimitError, and is 400 otherwise.
+func (c *Collector) ObserveParseError(err error) {
+	statusCode := http.StatusBadRequest
+
+	var withStatus interface{ StatusCode() int }
+	if errors.As(err, &withStatus) {
+		statusCode = withStatus.StatusCode()
+	}
+
+	c.metrics.ParseError(statusCode)
+}
+
+// Host returns the host of a request without the port. It is a HostFunc.
+func Host(request *http.Request) string {
+	value := request.Header.Get("Host")
+	if value == "" {
+		value = request.Host
+	}
+	if value == "" && request.URL != nil {
+		value = request.URL.Host
+	}
+
+	if h, _, err := net.SplitHostPort(value); err == nil {
+		return h
+	}
+
+	if len(value) > 1 && value[0] == '[' && value[len(value)-1] == ']' {
+		value = value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+// method returns the label of a request method, folding unknown methods into
+// a single label.
+func method(method string) string {
+	if gohttp.IsKnownMethod(method) {
+		return method
+	}
+	return otherMethod
+}
+
+// statusClass returns the class of a status code, e.g. "2xx".
+func statusClass(statusCode int) string {
+	if statusCode < 100 || statusCode > 999 {
+		return "invalid"
+	}
+	return strconv.Itoa(statusCode/100) + "xx"
+}
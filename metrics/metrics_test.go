@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// recorder is a Metrics implementation recording all observations.
+type recorder struct {
+	mutex        sync.Mutex
+	observations []Observation
+	parseErrors  []int
+	connections  []bool
+}
+
+func (r *recorder) Transaction(observation Observation) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.observations = append(r.observations, observation)
+}
+
+func (r *recorder) ParseError(statusCode int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.parseErrors = append(r.parseErrors, statusCode)
+}
+
+func (r *recorder) Connection(accepted bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.connections = append(r.connections, accepted)
+}
+
+func TestCollector_ObserveTransaction(t *testing.T) {
+	start := time.Unix(0, 0)
+
+	testCases := map[string]struct {
+		request             string
+		response            string
+		responseTiming      gohttp.MessageTiming
+		expected            Observation
+		expectedParseErrors []int
+	}{
+		"with response": {
+			request:  "GET /users/1 HTTP/1.1\r\nHost: example.com:8080\r\n\r\n",
+			response: "HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n",
+			responseTiming: gohttp.MessageTiming{
+				HeadersComplete: start.Add(20 * time.Millisecond),
+				BodyComplete:    start.Add(30 * time.Millisecond),
+			},
+			expected: Observation{
+				Labels: Labels{
+					Host:        "example.com",
+					Route:       "/users/:id",
+					Method:      "GET",
+					StatusClass: "4xx",
+				},
+				RequestBytes:  49,
+				ResponseBytes: 45,
+				Latency:       30 * time.Millisecond,
+			},
+		},
+		"without response": {
+			request: "POST /login HTTP/1.1\r\nHost: [::1]\r\nContent-Length: 0\r\n\r\n",
+			expected: Observation{
+				Labels: Labels{
+					Host:   "::1",
+					Route:  "/login",
+					Method: "POST",
+				},
+				RequestBytes: 56,
+			},
+		},
+		"unknown method": {
+			request: "FROB /login HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: Observation{
+				Labels: Labels{
+					Host:   "example.com",
+					Route:  "/login",
+					Method: "OTHER",
+				},
+				RequestBytes: 43,
+			},
+		},
+		"invalid request": {
+			request:             "GET /" + string(make([]byte, 10)) + " HTTP/1.1\r\n\r\n",
+			expectedParseErrors: []int{http.StatusBadRequest},
+		},
+	}
+
+	for name, tc := range testCases {
+		metrics := &recorder{}
+		collector := NewCollector(metrics, func(request *http.Request) string {
+			if request.URL.Path == "/users/1" {
+				return "/users/:id"
+			}
+			return request.URL.Path
+		})
+		collector.HostFunc = Host
+
+		transaction := gohttp.Transaction{
+			Request:        []byte(tc.request),
+			RequestSizes:   gohttp.MeasureMessage([]byte(tc.request)),
+			RequestTiming:  gohttp.MessageTiming{FirstByte: start},
+			ResponseTiming: tc.responseTiming,
+		}
+		if tc.response != "" {
+			transaction.Response = []byte(tc.response)
+			transaction.ResponseSizes = gohttp.MeasureMessage([]byte(tc.response))
+		}
+
+		err := collector.ObserveTransaction(transaction)
+
+		if (err != nil) != (len(tc.expectedParseErrors) > 0) {
+			t.Errorf("'%s': unexpected error %v", name, err)
+		}
+
+		if len(metrics.parseErrors) != len(tc.expectedParseErrors) {
+			t.Fatalf("'%s': expected parse errors %v, got %v", name, tc.expectedParseErrors, metrics.parseErrors)
+		}
+
+		for i, statusCode := range tc.expectedParseErrors {
+			if metrics.parseErrors[i] != statusCode {
+				t.Errorf("'%s': expected parse error %d, got %d", name, statusCode, metrics.parseErrors[i])
+			}
+		}
+
+		if len(tc.expectedParseErrors) > 0 {
+			continue
+		}
+
+		if len(metrics.observations) != 1 {
+			t.Fatalf("'%s': expected 1 observation, got %d", name, len(metrics.observations))
+		}
+
+		if metrics.observations[0] != tc.expected {
+			t.Errorf("'%s': expected observation %+v, got %+v", name, tc.expected, metrics.observations[0])
+		}
+	}
+}
+
+func TestCollector_Observe_Host(t *testing.T) {
+	request, _ := http.NewRequest("GET", "http://example.com/", nil)
+
+	metrics := &recorder{}
+	NewCollector(metrics, nil).Observe(request, nil, gohttp.Sizes{}, gohttp.Sizes{}, gohttp.MessageTiming{}, gohttp.MessageTiming{})
+
+	if host := metrics.observations[0].Labels.Host; host != "" {
+		t.Errorf("expected no host label without a HostFunc, got %s", host)
+	}
+}
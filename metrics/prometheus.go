@@ -0,0 +1,250 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the upper bounds of the latency histogram
+// buckets in seconds.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// defaultMaxSeries is the maximum number of series unless specified
+// otherwise.
+const defaultMaxSeries = 1000
+
+// overflowLabels are the labels of the series that transactions are recorded
+// in once the maximum number of series has been reached.
+var overflowLabels = Labels{Host: "overflow", Route: "overflow", Method: "overflow", StatusClass: "overflow"}
+
+// series holds the metrics of a single set of labels.
+type series struct {
+	labels        Labels
+	transactions  int64
+	requestBytes  int64
+	responseBytes int64
+	buckets       []int64
+	latencySum    float64
+	latencyCount  int64
+}
+
+// Prometheus is a Metrics implementation exposing the metrics in the
+// Prometheus text exposition format, so that no client library is required.
+// It is an http.Handler serving the metrics for scraping. It is safe for
+// concurrent use.
+type Prometheus struct {
+	// MaxSeries is the maximum number of label sets. Transactions with new
+	// labels beyond that are recorded in a single series with all labels set
+	// to "overflow". If zero, a maximum of 1000 is used.
+	MaxSeries int
+
+	mutex       sync.Mutex
+	namespace   string
+	buckets     []float64
+	series      map[Labels]*series
+	parseErrors map[int]int64
+	connections map[bool]int64
+}
+
+// NewPrometheus creates a new Prometheus exporter. The metric names are
+// prefixed with the namespace, e.g. "gohttp". If buckets is nil,
+// DefaultLatencyBuckets are used.
+func NewPrometheus(namespace string, buckets []float64) *Prometheus {
+	if buckets == nil {
+		buckets = DefaultLatencyBuckets
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &Prometheus{
+		namespace:   namespace,
+		buckets:     sorted,
+		series:      make(map[Labels]*series),
+		parseErrors: make(map[int]int64),
+		connections: make(map[bool]int64),
+	}
+}
+
+// Transaction records a completed transaction. Transactions without a
+// latency aren't counted in the latency histogram.
+func (p *Prometheus) Transaction(observation Observation) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	labels := observation.Labels
+
+	if _, ok := p.series[labels]; !ok && len(p.series) >= p.maxSeries() {
+		labels = overflowLabels
+	}
+
+	s, ok := p.series[labels]
+	if !ok {
+		s = &series{
+			labels:  labels,
+			buckets: make([]int64, len(p.buckets)),
+		}
+		p.series[labels] = s
+	}
+
+	s.transactions++
+	s.requestBytes += observation.RequestBytes
+	s.responseBytes += observation.ResponseBytes
+
+	if observation.Latency <= 0 {
+		return
+	}
+
+	seconds := observation.Latency.Seconds()
+
+	for i, bound := range p.buckets {
+		if seconds <= bound {
+			s.buckets[i]++
+		}
+	}
+
+	s.latencySum += seconds
+	s.latencyCount++
+}
+
+// ParseError records a message that couldn't be parsed.
+func (p *Prometheus) ParseError(statusCode int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.parseErrors[statusCode]++
+}
+
+// Connection records a connection accepted or rejected by a filter.
+func (p *Prometheus) Connection(accepted bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.connections[accepted]++
+}
+
+// ServeHTTP serves the metrics in the text exposition format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = p.WriteText(w)
+}
+
+// WriteText writes the metrics in the text exposition format. The series
+// are sorted by their labels, so that the output is stable.
+func (p *Prometheus) WriteText(w io.Writer) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	all := make([]*series, 0, len(p.series))
+	for _, s := range p.series {
+		all = append(all, s)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return labelString(all[i].labels) < labelString(all[j].labels)
+	})
+
+	b := bufio.NewWriter(w)
+
+	p.writeCounter(b, "transactions_total", "Completed transactions.", all, func(s *series) int64 { return s.transactions })
+	p.writeCounter(b, "request_bytes_total", "Bytes of requests as transmitted.", all, func(s *series) int64 { return s.requestBytes })
+	p.writeCounter(b, "response_bytes_total", "Bytes of responses as transmitted.", all, func(s *series) int64 { return s.responseBytes })
+
+	name := p.name("latency_seconds")
+	fmt.Fprintf(b, "# HELP %s Time from the first byte of the request to the end of the response.\n", name)
+	fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+
+	for _, s := range all {
+		labels := labelString(s.labels)
+
+		for i, bound := range p.buckets {
+			fmt.Fprintf(b, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), s.buckets[i])
+		}
+
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, s.latencyCount)
+		fmt.Fprintf(b, "%s_sum{%s} %s\n", name, labels, formatFloat(s.latencySum))
+		fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, s.latencyCount)
+	}
+
+	codes := make([]int, 0, len(p.parseErrors))
+	for code := range p.parseErrors {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	name = p.name("parse_errors_total")
+	fmt.Fprintf(b, "# HELP %s Messages that couldn't be parsed.\n", name)
+	fmt.Fprintf(b, "# TYPE %s counter\n", name)
+
+	for _, code := range codes {
+		fmt.Fprintf(b, "%s{status_code=\"%d\"} %d\n", name, code, p.parseErrors[code])
+	}
+
+	name = p.name("connections_total")
+	fmt.Fprintf(b, "# HELP %s Connections accepted or rejected by a filter.\n", name)
+	fmt.Fprintf(b, "# TYPE %s counter\n", name)
+
+	for _, accepted := range []bool{true, false} {
+		if count, ok := p.connections[accepted]; ok {
+			fmt.Fprintf(b, "%s{result=\"%s\"} %d\n", name, connectionResult(accepted), count)
+		}
+	}
+
+	return b.Flush()
+}
+
+func (p *Prometheus) writeCounter(w io.Writer, name, help string, all []*series, value func(s *series) int64) {
+	name = p.name(name)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s counter\n", name)
+
+	for _, s := range all {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, labelString(s.labels), value(s))
+	}
+}
+
+// maxSeries returns the maximum number of series, reserving one for the
+// overflow series.
+func (p *Prometheus) maxSeries() int {
+	if p.MaxSeries <= 0 {
+		return defaultMaxSeries - 1
+	}
+	return p.MaxSeries - 1
+}
+
+func (p *Prometheus) name(name string) string {
+	if p.namespace == "" {
+		return name
+	}
+	return p.namespace + "_" + name
+}
+
+// connectionResult returns the label value for a connection result.
+func connectionResult(accepted bool) string {
+	if accepted {
+		return "accepted"
+	}
+	return "rejected"
+}
+
+// labelString formats labels as a comma-separated list of label pairs.
+func labelString(labels Labels) string {
+	return fmt.Sprintf(`host="%s",route="%s",method="%s",status_class="%s"`,
+		escapeLabel(labels.Host), escapeLabel(labels.Route), escapeLabel(labels.Method), escapeLabel(labels.StatusClass))
+}
+
+// escapeLabel escapes a label value for the text exposition format.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheus(t *testing.T) {
+	prometheus := NewPrometheus("gohttp", []float64{0.1, 0.01})
+
+	labels := Labels{Host: "example.com", Route: `/"quoted"`, Method: "GET", StatusClass: "2xx"}
+
+	prometheus.Transaction(Observation{Labels: labels, RequestBytes: 40, ResponseBytes: 100, Latency: 50 * time.Millisecond})
+	prometheus.Transaction(Observation{Labels: labels, RequestBytes: 40, ResponseBytes: 100, Latency: 5 * time.Millisecond})
+	prometheus.Transaction(Observation{Labels: labels, RequestBytes: 40})
+	prometheus.ParseError(http.StatusBadRequest)
+	prometheus.Connection(false)
+	prometheus.Connection(false)
+
+	l := `host="example.com",route="/\"quoted\"",method="GET",status_class="2xx"`
+
+	expected := "# HELP gohttp_transactions_total Completed transactions.\n" +
+		"# TYPE gohttp_transactions_total counter\n" +
+		"gohttp_transactions_total{" + l + "} 3\n" +
+		"# HELP gohttp_request_bytes_total Bytes of requests as transmitted.\n" +
+		"# TYPE gohttp_request_bytes_total counter\n" +
+		"gohttp_request_bytes_total{" + l + "} 120\n" +
+		"# HELP gohttp_response_bytes_total Bytes of responses as transmitted.\n" +
+		"# TYPE gohttp_response_bytes_total counter\n" +
+		"gohttp_response_bytes_total{" + l + "} 200\n" +
+		"# HELP gohttp_latency_seconds Time from the first byte of the request to the end of the response.\n" +
+		"# TYPE gohttp_latency_seconds histogram\n" +
+		"gohttp_latency_seconds_bucket{" + l + ",le=\"0.01\"} 1\n" +
+		"gohttp_latency_seconds_bucket{" + l + ",le=\"0.1\"} 2\n" +
+		"gohttp_latency_seconds_bucket{" + l + ",le=\"+Inf\"} 2\n" +
+		"gohttp_latency_seconds_sum{" + l + "} 0.055\n" +
+		"gohttp_latency_seconds_count{" + l + "} 2\n" +
+		"# HELP gohttp_parse_errors_total Messages that couldn't be parsed.\n" +
+		"# TYPE gohttp_parse_errors_total counter\n" +
+		"gohttp_parse_errors_total{status_code=\"400\"} 1\n" +
+		"# HELP gohttp_connections_total Connections accepted or rejected by a filter.\n" +
+		"# TYPE gohttp_connections_total counter\n" +
+		"gohttp_connections_total{result=\"rejected\"} 2\n"
+
+	recorder := httptest.NewRecorder()
+	prometheus.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("expected text exposition format, got %s", recorder.Header().Get("Content-Type"))
+	}
+
+	if recorder.Body.String() != expected {
+		t.Errorf("expected metrics\n%s\ngot\n%s", expected, recorder.Body.String())
+	}
+}
+
+func TestPrometheus_MaxSeries(t *testing.T) {
+	prometheus := NewPrometheus("", nil)
+	prometheus.MaxSeries = 3
+
+	for _, route := range []string{"/a", "/b", "/c", "/d", "/a"} {
+		prometheus.Transaction(Observation{Labels: Labels{Route: route, Method: "GET"}})
+	}
+
+	var buf strings.Builder
+	if err := prometheus.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := map[string]bool{
+		`transactions_total{host="",route="/a",method="GET",status_class=""} 2`:                            true,
+		`transactions_total{host="",route="/b",method="GET",status_class=""} 1`:                            true,
+		`transactions_total{host="overflow",route="overflow",method="overflow",status_class="overflow"} 2`: true,
+	}
+
+	var actual int
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasPrefix(line, "transactions_total") {
+			continue
+		}
+		actual++
+		if !expected[line] {
+			t.Errorf("unexpected series %s", line)
+		}
+	}
+
+	if actual != len(expected) {
+		t.Errorf("expected %d series, got %d", len(expected), actual)
+	}
+}
@@ -0,0 +1,224 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxPacketSize is the maximum size of a single write, so that a packet
+	// sent over UDP isn't fragmented on a typical network.
+	maxPacketSize = 1432
+	// maxBufferedTimings is the number of latency samples buffered between
+	// two flushes. Beyond that, samples are dropped.
+	maxBufferedTimings = 10000
+)
+
+// TagFunc maps the labels of a transaction to statsd tags. Returning an
+// empty tag value omits the tag.
+type TagFunc func(labels Labels) map[string]string
+
+// DefaultTags maps the route, method, and status class to tags of the same
+// name. The host is omitted even if the Collector has a HostFunc, since every
+// tag value creates a new series on the statsd server, and the host is
+// chosen by the client. A custom TagFunc can add it if the hosts are known to
+// be bounded.
+func DefaultTags(labels Labels) map[string]string {
+	return map[string]string{
+		"route":        labels.Route,
+		"method":       labels.Method,
+		"status_class": labels.StatusClass,
+	}
+}
+
+// counterKey identifies a counter by its name and tag suffix.
+type counterKey struct {
+	name string
+	tags string
+}
+
+// Statsd is a Metrics implementation for environments without scraping. It
+// aggregates counters and buffers latency samples, and pushes them to a
+// statsd server in the DogStatsD format, which supports tags, once per
+// flush interval. It is safe for concurrent use.
+type Statsd struct {
+	mutex    sync.Mutex
+	flushing sync.Mutex
+	writer   io.Writer
+	prefix   string
+	tags     TagFunc
+	counters map[counterKey]int64
+	timings  []string
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewStatsd creates a new Statsd emitter writing to writer, typically a UDP
+// connection to the statsd server. The metric names are prefixed with
+// prefix, e.g. "gohttp". If tags is nil, DefaultTags is used. If interval is
+// positive, the metrics are flushed periodically until Close is called.
+func NewStatsd(writer io.Writer, prefix string, interval time.Duration, tags TagFunc) *Statsd {
+	if tags == nil {
+		tags = DefaultTags
+	}
+
+	s := &Statsd{
+		writer:   writer,
+		prefix:   prefix,
+		tags:     tags,
+		counters: make(map[counterKey]int64),
+		done:     make(chan struct{}),
+	}
+
+	if interval > 0 {
+		s.wg.Add(1)
+		go s.run(interval)
+	}
+
+	return s
+}
+
+// Transaction records a completed transaction. Transactions without a
+// latency don't produce a latency sample.
+func (s *Statsd) Transaction(observation Observation) {
+	tags := s.tagString(observation.Labels)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters[counterKey{s.name("transactions"), tags}]++
+	s.counters[counterKey{s.name("request_bytes"), tags}] += observation.RequestBytes
+	s.counters[counterKey{s.name("response_bytes"), tags}] += observation.ResponseBytes
+
+	if observation.Latency > 0 && len(s.timings) < maxBufferedTimings {
+		ms := strconv.FormatFloat(observation.Latency.Seconds()*1000, 'f', -1, 64)
+		s.timings = append(s.timings, s.name("latency")+":"+ms+"|ms"+tags)
+	}
+}
+
+// ParseError records a message that couldn't be parsed.
+func (s *Statsd) ParseError(statusCode int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters[counterKey{s.name("parse_errors"), "|#status_code:" + strconv.Itoa(statusCode)}]++
+}
+
+// Connection records a connection accepted or rejected by a filter.
+func (s *Statsd) Connection(accepted bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counters[counterKey{s.name("connections"), "|#result:" + connectionResult(accepted)}]++
+}
+
+// Flush writes the metrics recorded since the last flush, split into
+// packets of at most 1432 bytes.
+func (s *Statsd) Flush() error {
+	s.flushing.Lock()
+	defer s.flushing.Unlock()
+
+	s.mutex.Lock()
+	lines := make([]string, 0, len(s.counters)+len(s.timings))
+	for key, value := range s.counters {
+		lines = append(lines, key.name+":"+strconv.FormatInt(value, 10)+"|c"+key.tags)
+	}
+	sort.Strings(lines)
+	lines = append(lines, s.timings...)
+
+	s.counters = make(map[counterKey]int64)
+	s.timings = nil
+	s.mutex.Unlock()
+
+	var packet bytes.Buffer
+
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxPacketSize {
+			if _, err := s.writer.Write(packet.Bytes()); err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	if packet.Len() == 0 {
+		return nil
+	}
+
+	_, err := s.writer.Write(packet.Bytes())
+	return err
+}
+
+// Close stops the periodic flushing and flushes the remaining metrics. It
+// must only be called once.
+func (s *Statsd) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	return s.Flush()
+}
+
+func (s *Statsd) run(interval time.Duration) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Statsd) name(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "." + name
+}
+
+// tagString formats the tags of a transaction as a DogStatsD tag suffix,
+// sorted by tag name.
+func (s *Statsd) tagString(labels Labels) string {
+	tags := s.tags(labels)
+
+	names := make([]string, 0, len(tags))
+	for name, value := range tags {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = strings.Replace(sanitizeTag(name), ":", "_", -1) + ":" + sanitizeTag(tags[name])
+	}
+
+	return "|#" + strings.Join(pairs, ",")
+}
+
+// sanitizeTag replaces the characters that delimit metrics, values, and tags
+// in the statsd line format.
+func sanitizeTag(s string) string {
+	return strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_").Replace(s)
+}
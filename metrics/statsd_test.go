@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// packetWriter records each write as a separate packet.
+type packetWriter struct {
+	packets []string
+}
+
+func (p *packetWriter) Write(b []byte) (int, error) {
+	p.packets = append(p.packets, string(b))
+	return len(b), nil
+}
+
+func TestStatsd(t *testing.T) {
+	testCases := map[string]struct {
+		tags     TagFunc
+		expected []string
+	}{
+		"default tags": {
+			expected: []string{
+				"gohttp.connections:1|c|#result:accepted",
+				"gohttp.parse_errors:1|c|#status_code:400",
+				"gohttp.request_bytes:80|c|#method:GET,route:/a_b,status_class:2xx",
+				"gohttp.response_bytes:100|c|#method:GET,route:/a_b,status_class:2xx",
+				"gohttp.transactions:2|c|#method:GET,route:/a_b,status_class:2xx",
+				"gohttp.latency:12.5|ms|#method:GET,route:/a_b,status_class:2xx",
+			},
+		},
+		"custom tags": {
+			tags: func(labels Labels) map[string]string {
+				return map[string]string{"class": labels.StatusClass, "empty": ""}
+			},
+			expected: []string{
+				"gohttp.connections:1|c|#result:accepted",
+				"gohttp.parse_errors:1|c|#status_code:400",
+				"gohttp.request_bytes:80|c|#class:2xx",
+				"gohttp.response_bytes:100|c|#class:2xx",
+				"gohttp.transactions:2|c|#class:2xx",
+				"gohttp.latency:12.5|ms|#class:2xx",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		writer := &packetWriter{}
+		statsd := NewStatsd(writer, "gohttp", 0, tc.tags)
+
+		labels := Labels{Host: "example.com", Route: "/a,b", Method: "GET", StatusClass: "2xx"}
+
+		statsd.Transaction(Observation{Labels: labels, RequestBytes: 40, ResponseBytes: 100, Latency: 12500 * time.Microsecond})
+		statsd.Transaction(Observation{Labels: labels, RequestBytes: 40})
+		statsd.ParseError(http.StatusBadRequest)
+		statsd.Connection(true)
+
+		if err := statsd.Close(); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		expected := strings.Join(tc.expected, "\n")
+
+		if len(writer.packets) != 1 || writer.packets[0] != expected {
+			t.Errorf("'%s': expected packet\n%s\ngot %q", name, expected, writer.packets)
+		}
+	}
+}
+
+func TestStatsd_Flush(t *testing.T) {
+	writer := &packetWriter{}
+	statsd := NewStatsd(writer, "gohttp", 0, nil)
+
+	for i := 0; i < 100; i++ {
+		statsd.Transaction(Observation{
+			Labels:  Labels{Method: "GET"},
+			Latency: time.Duration(i+1) * time.Millisecond,
+		})
+	}
+
+	if err := statsd.Flush(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var lines int
+
+	for _, packet := range writer.packets {
+		if len(packet) > maxPacketSize {
+			t.Errorf("expected packets of at most %d bytes, got %d", maxPacketSize, len(packet))
+		}
+		lines += strings.Count(packet, "\n") + 1
+	}
+
+	if len(writer.packets) < 2 || lines != 103 {
+		t.Errorf("expected 103 lines in multiple packets, got %d in %d", lines, len(writer.packets))
+	}
+
+	packets := len(writer.packets)
+
+	if err := statsd.Flush(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(writer.packets) != packets {
+		t.Errorf("expected no packets for an empty flush, got %d", len(writer.packets)-packets)
+	}
+}
@@ -0,0 +1,168 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrTooSlow indicates a peer that transfers data slower than the minimum
+// throughput.
+var ErrTooSlow = errors.New("throughput below minimum")
+
+// ThroughputError is returned by a MinThroughputConn for a peer that falls
+// below the minimum throughput.
+type ThroughputError struct {
+	// Op is the operation that was too slow, either "read" or "write".
+	Op string
+	// Bytes is the number of bytes transferred since the measurement
+	// started.
+	Bytes int64
+	// Elapsed is the time since the measurement started.
+	Elapsed time.Duration
+}
+
+func (t *ThroughputError) Error() string {
+	return fmt.Sprintf("%s: %s %d bytes in %s", ErrTooSlow.Error(), t.Op, t.Bytes, t.Elapsed)
+}
+
+func (t *ThroughputError) Unwrap() error {
+	return ErrTooSlow
+}
+
+// StatusCode returns 408 Request Timeout (RFC 7231, section 6.5.7.). For a
+// write that has been too slow, the connection should be closed instead.
+func (t *ThroughputError) StatusCode() int {
+	return http.StatusRequestTimeout
+}
+
+// MinThroughputConn is a net.Conn enforcing a minimum throughput for reads
+// and writes, mitigating slow-loris style attacks on the body phase.
+//
+// While the measurement is running, every read and write has a deadline by
+// which the peer has to have transferred enough bytes to keep up with the
+// minimum throughput, after an initial grace period. Bytes transferred
+// early count towards later ones. The measurement is started with Start,
+// typically after the header section has been parsed, and stopped with
+// Stop, e.g. while waiting for the next request on a persistent connection.
+// A MinThroughputConn manages the read and write deadlines of the
+// underlying connection while the measurement is running.
+type MinThroughputConn struct {
+	net.Conn
+	bytesPerSecond int
+	grace          time.Duration
+	mutex          sync.Mutex
+	running        bool
+	start          time.Time
+	read           int64
+	written        int64
+	now            func() time.Time
+}
+
+// NewMinThroughputConn creates a new MinThroughputConn requiring at least
+// bytesPerSecond in each direction after the given grace period.
+func NewMinThroughputConn(conn net.Conn, bytesPerSecond int, grace time.Duration) *MinThroughputConn {
+	return &MinThroughputConn{
+		Conn:           conn,
+		bytesPerSecond: bytesPerSecond,
+		grace:          grace,
+		now:            time.Now,
+	}
+}
+
+// Start starts measuring the throughput from now on.
+func (m *MinThroughputConn) Start() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.running = true
+	m.start = m.now()
+	m.read, m.written = 0, 0
+}
+
+// Stop stops measuring the throughput and clears the deadlines.
+func (m *MinThroughputConn) Stop() error {
+	m.mutex.Lock()
+	m.running = false
+	m.mutex.Unlock()
+
+	return m.Conn.SetDeadline(time.Time{})
+}
+
+// Read reads from the connection, failing with a ThroughputError if the
+// peer doesn't send enough data in time.
+func (m *MinThroughputConn) Read(p []byte) (int, error) {
+	deadline, running := m.deadline(&m.read, 1)
+	if running {
+		if err := m.Conn.SetReadDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := m.Conn.Read(p)
+
+	return n, m.account(&m.read, n, err, running, "read")
+}
+
+// Write writes to the connection, failing with a ThroughputError if the
+// peer doesn't receive the data in time. The deadline covers all of p, so
+// that a large write isn't aborted by a peer keeping up with the minimum
+// throughput.
+func (m *MinThroughputConn) Write(p []byte) (int, error) {
+	deadline, running := m.deadline(&m.written, int64(len(p)))
+	if running {
+		if err := m.Conn.SetWriteDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := m.Conn.Write(p)
+
+	return n, m.account(&m.written, n, err, running, "write")
+}
+
+// deadline returns the time by which the next size bytes have to be
+// transferred.
+func (m *MinThroughputConn) deadline(transferred *int64, size int64) (time.Time, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.running || m.bytesPerSecond <= 0 {
+		return time.Time{}, false
+	}
+
+	return m.start.Add(m.grace + credit(*transferred+size, int64(m.bytesPerSecond))), true
+}
+
+// credit returns the time it takes to transfer n bytes at bytesPerSecond. It
+// is computed in whole seconds first, so that large transfers don't overflow
+// time.Duration, and saturates at the maximum duration.
+func credit(n, bytesPerSecond int64) time.Duration {
+	seconds := n / bytesPerSecond
+	if seconds >= math.MaxInt64/int64(time.Second)-1 {
+		return math.MaxInt64 / 2
+	}
+
+	return time.Duration(seconds)*time.Second + time.Duration(n%bytesPerSecond)*time.Second/time.Duration(bytesPerSecond)
+}
+
+func (m *MinThroughputConn) account(transferred *int64, n int, err error, running bool, op string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	*transferred += int64(n)
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() && running && m.running {
+		return &ThroughputError{
+			Op:      op,
+			Bytes:   *transferred,
+			Elapsed: m.now().Sub(m.start),
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,230 @@
+package gohttp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// timeoutError is the net.Error returned by slowConn for an exceeded
+// deadline.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// slowConn is a net.Conn whose peer sends a chunk of 10 bytes per interval
+// and receives bytesPerSecond, as measured by a fake clock. A read or write
+// fails with a timeout error if it would complete after the deadline.
+type slowConn struct {
+	net.Conn
+	clock          *fakeClock
+	chunks         int
+	interval       time.Duration
+	bytesPerSecond int
+	deadline       time.Time
+}
+
+func (s *slowConn) Read(p []byte) (int, error) {
+	if s.chunks == 0 {
+		return 0, io.EOF
+	}
+
+	arrival := s.clock.current.Add(s.interval)
+
+	if !s.deadline.IsZero() && arrival.After(s.deadline) {
+		s.clock.current = s.deadline
+		return 0, timeoutError{}
+	}
+
+	s.clock.current = arrival
+	s.chunks--
+
+	if len(p) > 10 {
+		p = p[:10]
+	}
+
+	return len(p), nil
+}
+
+func (s *slowConn) Write(p []byte) (int, error) {
+	done := s.clock.current.Add(time.Duration(len(p)) * time.Second / time.Duration(s.bytesPerSecond))
+
+	if !s.deadline.IsZero() && done.After(s.deadline) {
+		s.clock.current = s.deadline
+		return 0, timeoutError{}
+	}
+
+	s.clock.current = done
+
+	return len(p), nil
+}
+
+func (s *slowConn) SetWriteDeadline(t time.Time) error {
+	s.deadline = t
+	return nil
+}
+
+func (s *slowConn) SetDeadline(t time.Time) error {
+	s.deadline = t
+	return nil
+}
+
+func (s *slowConn) SetReadDeadline(t time.Time) error {
+	s.deadline = t
+	return nil
+}
+
+func TestMinThroughputConn(t *testing.T) {
+	testCases := map[string]struct {
+		started         bool
+		chunks          int
+		interval        time.Duration
+		expectedError   error
+		expectedElapsed time.Duration
+	}{
+		"fast enough": {
+			started:  true,
+			chunks:   5,
+			interval: 10 * time.Millisecond,
+		},
+		"too slow": {
+			started:         true,
+			chunks:          5,
+			interval:        200 * time.Millisecond,
+			expectedError:   ErrTooSlow,
+			expectedElapsed: 60 * time.Millisecond,
+		},
+		"slow but not started": {
+			chunks:   2,
+			interval: 200 * time.Millisecond,
+		},
+	}
+
+	for name, tc := range testCases {
+		clock := &fakeClock{current: time.Unix(0, 0)}
+
+		conn := NewMinThroughputConn(&slowConn{
+			clock:    clock,
+			chunks:   tc.chunks,
+			interval: tc.interval,
+		}, 100, 50*time.Millisecond)
+		conn.now = func() time.Time { return clock.current }
+
+		if tc.started {
+			conn.Start()
+		}
+
+		_, err := ioutil.ReadAll(conn)
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		var throughputErr *ThroughputError
+		if errors.As(err, &throughputErr) {
+			if throughputErr.StatusCode() != http.StatusRequestTimeout {
+				t.Errorf("'%s': expected status code %d, got %d", name, http.StatusRequestTimeout, throughputErr.StatusCode())
+			}
+
+			if throughputErr.Elapsed != tc.expectedElapsed {
+				t.Errorf("'%s': expected elapsed time %v, got %v", name, tc.expectedElapsed, throughputErr.Elapsed)
+			}
+		}
+	}
+}
+
+func TestMinThroughputConn_Stop(t *testing.T) {
+	clock := &fakeClock{current: time.Unix(0, 0)}
+
+	conn := NewMinThroughputConn(&slowConn{
+		clock:    clock,
+		chunks:   1,
+		interval: time.Second,
+	}, 1000, 10*time.Millisecond)
+	conn.now = func() time.Time { return clock.current }
+
+	conn.Start()
+
+	if err := conn.Stop(); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Errorf("expected no error after stopping, got %v", err)
+	}
+}
+
+func TestMinThroughputConn_Write(t *testing.T) {
+	testCases := map[string]struct {
+		size           int
+		bytesPerSecond int
+		expectedError  error
+	}{
+		"large write at sufficient throughput": {
+			size:           64000,
+			bytesPerSecond: 64000,
+		},
+		"large write below minimum throughput": {
+			size:           64000,
+			bytesPerSecond: 500,
+			expectedError:  ErrTooSlow,
+		},
+	}
+
+	for name, tc := range testCases {
+		clock := &fakeClock{current: time.Unix(0, 0)}
+
+		conn := NewMinThroughputConn(&slowConn{
+			clock:          clock,
+			bytesPerSecond: tc.bytesPerSecond,
+		}, 1000, 100*time.Millisecond)
+		conn.now = func() time.Time { return clock.current }
+		conn.Start()
+
+		n, err := conn.Write(make([]byte, tc.size))
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if err == nil && n != tc.size {
+			t.Errorf("'%s': expected %d bytes written, got %d", name, tc.size, n)
+		}
+	}
+}
+
+func TestCredit(t *testing.T) {
+	testCases := map[string]struct {
+		n              int64
+		bytesPerSecond int64
+		expected       time.Duration
+	}{
+		"fraction of a second": {
+			n:              250,
+			bytesPerSecond: 1000,
+			expected:       250 * time.Millisecond,
+		},
+		"seconds and fraction": {
+			n:              64000,
+			bytesPerSecond: 1000,
+			expected:       64 * time.Second,
+		},
+		"large transfer": {
+			n:              1 << 40,
+			bytesPerSecond: 1,
+			expected:       math.MaxInt64 / 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		if actual := credit(tc.n, tc.bytesPerSecond); actual != tc.expected {
+			t.Errorf("'%s': expected credit %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package mock provides a wire-level mock server. Expected requests are
+// declared using matchers, and each expectation answers with a canned raw
+// response. Incoming requests are parsed using the gohttp package.
+package mock
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// notFound is the raw response sent for unexpected requests.
+const notFound = "HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n"
+
+// Matcher reports whether a parsed request and its body match a condition.
+type Matcher func(request *http.Request, body []byte) bool
+
+// Method matches requests with the given method.
+func Method(method string) Matcher {
+	return func(request *http.Request, _ []byte) bool {
+		return request.Method == method
+	}
+}
+
+// Path matches requests whose target has the given path.
+func Path(path string) Matcher {
+	return func(request *http.Request, _ []byte) bool {
+		return request.URL.Path == path
+	}
+}
+
+// Header matches requests having a header field with the given value.
+func Header(name, value string) Matcher {
+	return func(request *http.Request, _ []byte) bool {
+		for _, v := range request.Header.Values(name) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Body matches requests whose body equals the given body.
+func Body(body string) Matcher {
+	return func(_ *http.Request, b []byte) bool {
+		return string(b) == body
+	}
+}
+
+// BodyContains matches requests whose body contains the given substring.
+func BodyContains(substr string) Matcher {
+	return func(_ *http.Request, b []byte) bool {
+		return strings.Contains(string(b), substr)
+	}
+}
+
+// Expectation is an expected request along with the raw response it is
+// answered with. An expectation is expected to be met exactly once unless
+// specified otherwise using Times. It may be modified while the server is
+// handling requests.
+type Expectation struct {
+	// mutex is the mutex of the server, guarding the fields below.
+	mutex    *sync.Mutex
+	matchers []Matcher
+	response []byte
+	times    int
+	calls    int
+}
+
+// Respond sets the raw response that matching requests are answered with.
+func (e *Expectation) Respond(response []byte) *Expectation {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.response = response
+	return e
+}
+
+// Times sets the number of times the expectation is expected to be met.
+func (e *Expectation) Times(n int) *Expectation {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.times = n
+	return e
+}
+
+func (e *Expectation) matches(request *http.Request, body []byte) bool {
+	for _, matcher := range e.matchers {
+		if !matcher(request, body) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a human-readable representation of the expectation.
+func (e *Expectation) String() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.string()
+}
+
+// string works like String, but requires the mutex to be held.
+func (e *Expectation) string() string {
+	return fmt.Sprintf("expectation with %d matchers (%d/%d calls)", len(e.matchers), e.calls, e.times)
+}
+
+// Server is a mock server listening on a local TCP port.
+type Server struct {
+	listener     net.Listener
+	mutex        sync.Mutex
+	expectations []*Expectation
+	unexpected   []*http.Request
+	conns        map[net.Conn]struct{}
+	closed       bool
+	wg           sync.WaitGroup
+}
+
+// NewServer creates a new Server listening on a random local port and starts
+// accepting connections.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &Server{
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	server.wg.Add(1)
+	go server.serve()
+
+	return server, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Expect declares a new expected request matching all given matchers. By
+// default, the request is answered with an empty 200 response.
+func (s *Server) Expect(matchers ...Matcher) *Expectation {
+	expectation := &Expectation{
+		mutex:    &s.mutex,
+		matchers: matchers,
+		response: []byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"),
+		times:    1,
+	}
+
+	s.mutex.Lock()
+	s.expectations = append(s.expectations, expectation)
+	s.mutex.Unlock()
+
+	return expectation
+}
+
+// Unmet returns all expectations that have been met less often than
+// expected.
+func (s *Server) Unmet() []*Expectation {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var unmet []*Expectation
+
+	for _, expectation := range s.expectations {
+		if expectation.calls < expectation.times {
+			unmet = append(unmet, expectation)
+		}
+	}
+
+	return unmet
+}
+
+// Unexpected returns all requests that didn't match any expectation.
+func (s *Server) Unexpected() []*http.Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]*http.Request(nil), s.unexpected...)
+}
+
+// Verify returns an error if there are unmet expectations or unexpected
+// requests. Both are determined at the same time, so that a request handled
+// concurrently can't be missed.
+func (s *Server) Verify() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var messages []string
+
+	for _, expectation := range s.expectations {
+		if expectation.calls < expectation.times {
+			messages = append(messages, "unmet "+expectation.string())
+		}
+	}
+
+	for _, request := range s.unexpected {
+		messages = append(messages, fmt.Sprintf("unexpected request %s %s", request.Method, request.URL.String()))
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// Close stops the server from accepting new connections, closes all open
+// connections, and waits for the accept loop and all connection handlers to
+// finish.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mutex.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return
+		}
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+// track registers an accepted connection, so that it can be closed by Close.
+// It returns false if the server has already been closed.
+func (s *Server) track(conn net.Conn) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.conns[conn] = struct{}{}
+
+	return true
+}
+
+// handle parses requests from the connection until it is closed by the
+// client or a request can't be parsed.
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.conns, conn)
+		s.mutex.Unlock()
+
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		request, err := gohttp.ParseRequest(reader)
+		if err != nil {
+			return
+		}
+
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			return
+		}
+		request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if _, err := conn.Write(s.respond(request, body)); err != nil {
+			return
+		}
+
+		if closeRequested(request.Header) {
+			return
+		}
+	}
+}
+
+// closeRequested reports whether the Connection header field contains the
+// close option. Connection options are case-insensitive (RFC 7230, section
+// 6.1.).
+func closeRequested(header http.Header) bool {
+	for _, value := range header.Values("Connection") {
+		for _, option := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(option), "close") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// respond records the request and returns the response of the first
+// matching expectation that hasn't been met yet.
+func (s *Server) respond(request *http.Request, body []byte) []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, expectation := range s.expectations {
+		if expectation.calls < expectation.times && expectation.matches(request, body) {
+			expectation.calls++
+			return expectation.response
+		}
+	}
+
+	s.unexpected = append(s.unexpected, request)
+
+	return []byte(notFound)
+}
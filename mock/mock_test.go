@@ -0,0 +1,227 @@
+package mock
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestServer(t *testing.T) {
+	testCases := map[string]struct {
+		requests       []string
+		expectedStatus []int
+		expectedUnmet  int
+		expectedUnexp  int
+	}{
+		"all expectations met": {
+			requests: []string{
+				"GET /users HTTP/1.1\r\nHost: example.com\r\n\r\n",
+				"POST /users HTTP/1.1\r\nContent-Length: 5\r\n\r\nalice",
+			},
+			expectedStatus: []int{200, 201},
+		},
+		"unmet expectation": {
+			requests: []string{
+				"GET /users HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			},
+			expectedStatus: []int{200},
+			expectedUnmet:  1,
+		},
+		"unexpected request": {
+			requests: []string{
+				"GET /users HTTP/1.1\r\nHost: example.com\r\n\r\n",
+				"POST /users HTTP/1.1\r\nContent-Length: 3\r\n\r\nbob",
+				"DELETE /users HTTP/1.1\r\n\r\n",
+			},
+			expectedStatus: []int{200, 404, 404},
+			expectedUnmet:  1,
+			expectedUnexp:  2,
+		},
+	}
+
+	for name, tc := range testCases {
+		server, err := NewServer()
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		server.Expect(Method("GET"), Path("/users"), Header("Host", "example.com"))
+		server.Expect(Method("POST"), BodyContains("alice")).
+			Respond([]byte("HTTP/1.1 201 Created\r\nContent-Length: 0\r\n\r\n"))
+
+		conn, err := net.Dial("tcp", server.Addr())
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		reader := bufio.NewReader(conn)
+
+		for i, request := range tc.requests {
+			if _, err := conn.Write([]byte(request)); err != nil {
+				t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+			}
+
+			statusCode := readStatusCode(t, reader)
+
+			if statusCode != tc.expectedStatus[i] {
+				t.Errorf("'%s': expected status code %d, got %d", name, tc.expectedStatus[i], statusCode)
+			}
+		}
+
+		_ = conn.Close()
+		_ = server.Close()
+
+		if len(server.Unmet()) != tc.expectedUnmet {
+			t.Errorf("'%s': expected %d unmet expectations, got %d", name, tc.expectedUnmet, len(server.Unmet()))
+		}
+
+		if len(server.Unexpected()) != tc.expectedUnexp {
+			t.Errorf("'%s': expected %d unexpected requests, got %d", name, tc.expectedUnexp, len(server.Unexpected()))
+		}
+
+		if (server.Verify() == nil) != (tc.expectedUnmet == 0 && tc.expectedUnexp == 0) {
+			t.Errorf("'%s': unexpected verification result %v", name, server.Verify())
+		}
+	}
+}
+
+func TestServer_Concurrent(t *testing.T) {
+	server, err := NewServer()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer server.Close()
+
+	expectation := server.Expect(Method("GET")).Times(100)
+
+	conn, err := net.Dial("tcp", server.Addr())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+
+	// The expectation is modified and inspected while requests are served,
+	// which the race detector reports if it isn't synchronized.
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			expectation.Respond([]byte("HTTP/1.1 204 No Content\r\n\r\n")).Times(100)
+			_ = expectation.String()
+			_ = server.Verify()
+		}
+	}()
+
+	reader := bufio.NewReader(conn)
+
+	for i := 0; i < 10; i++ {
+		if _, err := conn.Write([]byte("GET / HTTP/1.1\r\n\r\n")); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		readStatusCode(t, reader)
+	}
+
+	<-done
+}
+
+func readStatusCode(t *testing.T, reader *bufio.Reader) int {
+	response, err := gohttp.ParseResponse(reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	return response.StatusCode
+}
+
+func TestMatchers(t *testing.T) {
+	request, _ := http.NewRequest("PUT", "http://example.com/items/1", strings.NewReader(""))
+	request.Header.Set("Content-Type", "application/json")
+	body := []byte(`{"name":"item"}`)
+
+	testCases := map[string]struct {
+		matcher  Matcher
+		expected bool
+	}{
+		"method":               {matcher: Method("PUT"), expected: true},
+		"other method":         {matcher: Method("GET"), expected: false},
+		"path":                 {matcher: Path("/items/1"), expected: true},
+		"header":               {matcher: Header("Content-Type", "application/json"), expected: true},
+		"missing header":       {matcher: Header("Accept", "application/json"), expected: false},
+		"body":                 {matcher: Body(`{"name":"item"}`), expected: true},
+		"body contains":        {matcher: BodyContains(`"item"`), expected: true},
+		"body doesn't contain": {matcher: BodyContains("other"), expected: false},
+	}
+
+	for name, tc := range testCases {
+		if actual := tc.matcher(request, body); actual != tc.expected {
+			t.Errorf("'%s': expected result %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestServer_Close(t *testing.T) {
+	testCases := map[string]struct {
+		request  string
+		closeNow bool
+	}{
+		"persistent connection": {
+			request:  "GET /users HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			closeNow: true,
+		},
+		"connection close": {
+			request: "GET /users HTTP/1.1\r\nHost: example.com\r\nConnection: keep-alive, Close\r\n\r\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		server, err := NewServer()
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		server.Expect(Method("GET"), Path("/users"))
+
+		conn, err := net.Dial("tcp", server.Addr())
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		reader := bufio.NewReader(conn)
+
+		if _, err := conn.Write([]byte(tc.request)); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if statusCode := readStatusCode(t, reader); statusCode != http.StatusOK {
+			t.Errorf("'%s': expected status code %d, got %d", name, http.StatusOK, statusCode)
+		}
+
+		if tc.closeNow {
+			closed := make(chan error, 1)
+			go func() {
+				closed <- server.Close()
+			}()
+
+			select {
+			case <-closed:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("'%s': Close didn't return while a connection was open", name)
+			}
+		}
+
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		if _, err := reader.ReadByte(); err != io.EOF {
+			t.Errorf("'%s': expected connection to be closed, got %v", name, err)
+		}
+
+		_ = conn.Close()
+		_ = server.Close()
+	}
+}
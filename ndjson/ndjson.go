@@ -0,0 +1,188 @@
+// Package ndjson exports transactions as newline-delimited JSON, one line per
+// transaction. Compared to WARC, the output is lossy but lightweight, and it
+// can be piped directly into tools like jq or Elasticsearch.
+package ndjson
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// Entry is the JSON representation of a transaction.
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Request  *Message  `json:"request"`
+	Response *Message  `json:"response,omitempty"`
+}
+
+// Message is the JSON representation of a request or a response.
+type Message struct {
+	// Method and Target are only set for requests.
+	Method string `json:"method,omitempty"`
+	Target string `json:"target,omitempty"`
+	// Status and Reason are only set for responses.
+	Status int    `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+
+	Proto   string      `json:"proto"`
+	Headers http.Header `json:"headers"`
+	// Size is the size of the serialized message in bytes.
+	Size int64 `json:"size"`
+	// BodySize is the size of the decoded body in bytes.
+	BodySize int `json:"body_size"`
+	// BodySHA256 is the hex-encoded SHA-256 digest of the decoded body.
+	BodySHA256 string `json:"body_sha256,omitempty"`
+	// Body is the beginning of a textual body, up to the configured maximum
+	// size. Binary bodies are omitted.
+	Body          string `json:"body,omitempty"`
+	BodyTruncated bool   `json:"body_truncated,omitempty"`
+	// Timing is the timing of receiving the message, if it has been
+	// recorded.
+	Timing *Timing `json:"timing,omitempty"`
+}
+
+// Timing is the JSON representation of a gohttp.MessageTiming. Points in time
+// that haven't been recorded are omitted.
+type Timing struct {
+	FirstByte       *time.Time `json:"first_byte,omitempty"`
+	HeadersComplete *time.Time `json:"headers_complete,omitempty"`
+	BodyComplete    *time.Time `json:"body_complete,omitempty"`
+}
+
+// Writer writes transactions as NDJSON lines.
+type Writer struct {
+	encoder *json.Encoder
+	// MaxBodySize is the number of body bytes included in each entry. Bodies
+	// are represented only by their size and digest if it is 0.
+	MaxBodySize int
+}
+
+// NewWriter creates a new Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	return &Writer{
+		encoder: encoder,
+	}
+}
+
+// WriteTransaction writes the transaction as a single line.
+func (w *Writer) WriteTransaction(transaction gohttp.Transaction) error {
+	entry, err := w.entry(transaction)
+	if err != nil {
+		return err
+	}
+
+	return w.encoder.Encode(entry)
+}
+
+func (w *Writer) entry(transaction gohttp.Transaction) (*Entry, error) {
+	request, response, err := transaction.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{
+		Time: transaction.Time,
+		Request: &Message{
+			Method:  request.Method,
+			Target:  request.URL.String(),
+			Proto:   request.Proto,
+			Headers: request.Header,
+			Size:    size(transaction.Request, transaction.RequestSizes),
+			Timing:  timing(transaction.RequestTiming),
+		},
+	}
+
+	if err := w.setBody(entry.Request, request.Body); err != nil {
+		return nil, err
+	}
+
+	if response == nil {
+		return entry, nil
+	}
+
+	entry.Response = &Message{
+		Status:  response.StatusCode,
+		Reason:  strings.TrimPrefix(response.Status, strconv.Itoa(response.StatusCode)+" "),
+		Proto:   response.Proto,
+		Headers: response.Header,
+		Size:    size(transaction.Response, transaction.ResponseSizes),
+		Timing:  timing(transaction.ResponseTiming),
+	}
+
+	if err := w.setBody(entry.Response, response.Body); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// size returns the total size of a message as recorded in the transaction.
+// Transactions assembled without recording the sizes are measured instead.
+func size(message []byte, sizes gohttp.Sizes) int64 {
+	if sizes == (gohttp.Sizes{}) {
+		sizes = gohttp.MeasureMessage(message)
+	}
+	return sizes.Total()
+}
+
+// timing converts a gohttp.MessageTiming, returning nil if it is zero.
+func timing(t gohttp.MessageTiming) *Timing {
+	if t == (gohttp.MessageTiming{}) {
+		return nil
+	}
+
+	return &Timing{
+		FirstByte:       timeOrNil(t.FirstByte),
+		HeadersComplete: timeOrNil(t.HeadersComplete),
+		BodyComplete:    timeOrNil(t.BodyComplete),
+	}
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (w *Writer) setBody(message *Message, body io.Reader) error {
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	message.BodySize = len(content)
+
+	if len(content) == 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256(content)
+	message.BodySHA256 = hex.EncodeToString(sum[:])
+
+	if w.MaxBodySize <= 0 || !utf8.Valid(content) {
+		return nil
+	}
+
+	if len(content) > w.MaxBodySize {
+		content = content[:w.MaxBodySize]
+		message.BodyTruncated = true
+	}
+
+	message.Body = strings.ToValidUTF8(string(content), "")
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package ndjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestWriter_WriteTransaction(t *testing.T) {
+	transactions := []gohttp.Transaction{
+		{
+			Time:     time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC),
+			Request:  []byte("POST /api HTTP/1.1\r\nHost: example.com\r\nContent-Length: 11\r\n\r\nhello world"),
+			Response: []byte("HTTP/1.1 201 Created\r\nContent-Length: 2\r\n\r\nok"),
+		},
+		{
+			Time:    time.Date(2020, 6, 1, 12, 0, 1, 0, time.UTC),
+			Request: []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		},
+	}
+
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	writer.MaxBodySize = 5
+
+	for _, transaction := range transactions {
+		if err := writer.WriteTransaction(transaction); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(transactions) {
+		t.Fatalf("expected %d lines, got %d", len(transactions), len(lines))
+	}
+
+	var entry Entry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if entry.Request.Method != "POST" || entry.Request.Target != "/api" {
+		t.Errorf("expected request POST /api, got %s %s", entry.Request.Method, entry.Request.Target)
+	}
+
+	if entry.Request.Body != "hello" || !entry.Request.BodyTruncated || entry.Request.BodySize != 11 {
+		t.Errorf("expected truncated body %s of size %d, got %s of size %d", "hello", 11, entry.Request.Body, entry.Request.BodySize)
+	}
+
+	expectedDigest := "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9"
+	if entry.Request.BodySHA256 != expectedDigest {
+		t.Errorf("expected body digest %s, got %s", expectedDigest, entry.Request.BodySHA256)
+	}
+
+	if entry.Response.Status != 201 || entry.Response.Reason != "Created" || entry.Response.Body != "ok" {
+		t.Errorf("unexpected response %+v", entry.Response)
+	}
+
+	if host := entry.Request.Headers.Get("Host"); host != "example.com" {
+		t.Errorf("expected host %s, got %s", "example.com", host)
+	}
+
+	entry = Entry{}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if entry.Response != nil {
+		t.Errorf("expected no response, got %+v", entry.Response)
+	}
+}
+
+func TestWriter_WriteTransaction_Head(t *testing.T) {
+	firstByte := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	transaction := gohttp.Transaction{
+		Request:       []byte("HEAD / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		Response:      []byte("HTTP/1.1 200 OK\r\nContent-Length: 42\r\n\r\n"),
+		ResponseSizes: gohttp.Sizes{StartLine: 17, Header: 22},
+		ResponseTiming: gohttp.MessageTiming{
+			FirstByte:       firstByte,
+			HeadersComplete: firstByte.Add(time.Millisecond),
+		},
+	}
+
+	var buf bytes.Buffer
+
+	if err := NewWriter(&buf).WriteTransaction(transaction); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if entry.Response.BodySize != 0 {
+		t.Errorf("expected body size %d, got %d", 0, entry.Response.BodySize)
+	}
+
+	if entry.Request.Size != int64(len(transaction.Request)) {
+		t.Errorf("expected request size %d, got %d", len(transaction.Request), entry.Request.Size)
+	}
+
+	if entry.Response.Size != transaction.ResponseSizes.Total() {
+		t.Errorf("expected response size %d, got %d", transaction.ResponseSizes.Total(), entry.Response.Size)
+	}
+
+	if entry.Request.Timing != nil {
+		t.Errorf("expected no request timing, got %+v", entry.Request.Timing)
+	}
+
+	if timing := entry.Response.Timing; timing == nil || !timing.FirstByte.Equal(firstByte) || timing.BodyComplete != nil {
+		t.Errorf("expected response timing starting at %v, got %+v", firstByte, timing)
+	}
+}
@@ -0,0 +1,372 @@
+// Package oauth2 acquires access tokens using the OAuth 2.0 client
+// credentials grant (RFC 6749, section 4.4.) and injects them into outbound
+// requests, e.g. requests forwarded by a gateway to a protected upstream.
+// Token requests are serialized and their responses parsed with gohttp.
+package oauth2
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// expiryMargin is the time before its expiry at which a token is renewed,
+// so that it doesn't expire while a request is in flight.
+const expiryMargin = 10 * time.Second
+
+// maxResponseSize is the maximum size of a token response body.
+const maxResponseSize = 1 << 20
+
+// defaultTimeout is the timeout of a token request unless specified
+// otherwise.
+const defaultTimeout = 30 * time.Second
+
+// ErrUnsupportedScheme is returned for a token URL that is neither http nor
+// https.
+var ErrUnsupportedScheme = errors.New("unsupported token URL scheme")
+
+// ErrInvalidTokenResponse is returned for a token response without an
+// access token.
+var ErrInvalidTokenResponse = errors.New("invalid token response")
+
+// TokenError is an error response of the token endpoint (RFC 6749, section
+// 5.2.).
+type TokenError struct {
+	// StatusCode is the status code of the response.
+	StatusCode int
+	// Code is the error code, e.g. "invalid_client".
+	Code string
+	// Description is the optional human-readable description.
+	Description string
+}
+
+func (t *TokenError) Error() string {
+	if t.Description != "" {
+		return fmt.Sprintf("token request failed with %s (%d): %s", t.Code, t.StatusCode, t.Description)
+	}
+	return fmt.Sprintf("token request failed with %s (%d)", t.Code, t.StatusCode)
+}
+
+// Token is an access token.
+type Token struct {
+	AccessToken string
+	TokenType   string
+	// Expiry is the time the token expires. It is zero if the token
+	// response didn't specify a lifetime.
+	Expiry time.Time
+}
+
+// ClientCredentials acquires tokens using the client credentials grant and
+// caches them until they are about to expire. It is safe for concurrent use.
+type ClientCredentials struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	// TLSConfig is used for https token endpoints. If nil, the default
+	// configuration is used.
+	TLSConfig *tls.Config
+	// Dialer connects to the token endpoint, e.g. a dialer returned by an
+	// egress policy. If nil, a zero net.Dialer is used.
+	Dialer *net.Dialer
+	// Timeout limits the duration of a token request in addition to the
+	// deadline of the context. If zero, a timeout of 30 seconds is used, so
+	// that a hanging endpoint can't block outbound requests indefinitely.
+	Timeout time.Duration
+
+	mutex    sync.Mutex
+	token    *Token
+	inflight *tokenFetch
+	now      func() time.Time
+}
+
+// tokenFetch is a token request in progress, which concurrent callers wait
+// for instead of requesting a token themselves.
+type tokenFetch struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// NewClientCredentials creates a new ClientCredentials requesting tokens for
+// the given scopes from the token endpoint.
+func NewClientCredentials(tokenURL, clientID, clientSecret string, scopes ...string) *ClientCredentials {
+	return &ClientCredentials{
+		tokenURL:     tokenURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		scopes:       scopes,
+		now:          time.Now,
+	}
+}
+
+// Token returns a cached token, or acquires a new one if there is none or
+// the cached one is about to expire. The token request is made without
+// holding the mutex, and concurrent callers wait for a request in progress
+// until their context is done.
+func (c *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	c.mutex.Lock()
+
+	now := c.now()
+
+	if token := c.token; token != nil && (token.Expiry.IsZero() || now.Add(expiryMargin).Before(token.Expiry)) {
+		c.mutex.Unlock()
+		return token, nil
+	}
+
+	if fetch := c.inflight; fetch != nil {
+		c.mutex.Unlock()
+
+		select {
+		case <-fetch.done:
+			return fetch.token, fetch.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	fetch := &tokenFetch{done: make(chan struct{})}
+	c.inflight = fetch
+	c.mutex.Unlock()
+
+	fetch.token, fetch.err = c.fetch(ctx, now)
+
+	c.mutex.Lock()
+	if fetch.err == nil {
+		c.token = fetch.token
+	}
+	c.inflight = nil
+	c.mutex.Unlock()
+
+	close(fetch.done)
+
+	return fetch.token, fetch.err
+}
+
+// Authorize sets the Authorization header field of an outbound request to
+// the current token, replacing the credentials of the client that sent the
+// request to the gateway.
+func (c *ClientCredentials) Authorize(ctx context.Context, r *http.Request) error {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	return nil
+}
+
+// Invalidate discards the cached token, e.g. after an upstream rejected it.
+func (c *ClientCredentials) Invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.token = nil
+}
+
+// fetch requests a token within the timeout. Errors caused by the context
+// being done are reported as the context's error.
+func (c *ClientCredentials) fetch(ctx context.Context, now time.Time) (*Token, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	token, err := c.requestToken(ctx, now)
+	if err == nil {
+		return token, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// The connection deadline may expire just before the context does.
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return nil, context.DeadlineExceeded
+	}
+
+	return nil, err
+}
+
+func (c *ClientCredentials) requestToken(ctx context.Context, now time.Time) (*Token, error) {
+	endpoint, err := url.Parse(c.tokenURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, ErrUnsupportedScheme
+	}
+
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+	}
+	if len(c.scopes) > 0 {
+		form.Set("scope", strings.Join(c.scopes, " "))
+	}
+
+	content := form.Encode()
+
+	request := &http.Request{
+		Method: http.MethodPost,
+		URL:    &url.URL{Path: endpoint.EscapedPath(), RawQuery: endpoint.RawQuery},
+		Proto:  "HTTP/1.1",
+		Header: http.Header{
+			"Host":           {endpoint.Host},
+			"Content-Type":   {"application/x-www-form-urlencoded"},
+			"Content-Length": {strconv.Itoa(len(content))},
+			"Accept":         {"application/json"},
+			"Connection":     {"close"},
+		},
+		Body: ioutil.NopCloser(strings.NewReader(content)),
+	}
+
+	if request.URL.Path == "" {
+		request.URL.Path = "/"
+	}
+
+	// The client credentials are form-encoded before being used for Basic
+	// authentication (RFC 6749, section 2.3.1.).
+	request.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+
+	message, err := gohttp.SerializeRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	conn, err := c.dial(ctx, endpoint, stop)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(message); err != nil {
+		return nil, err
+	}
+
+	response, err := gohttp.ParseResponse(bufio.NewReader(conn))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	if err := json.Unmarshal(body, &parsed); err != nil && response.StatusCode == http.StatusOK {
+		return nil, ErrInvalidTokenResponse
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, &TokenError{
+			StatusCode:  response.StatusCode,
+			Code:        parsed.Error,
+			Description: parsed.ErrorDescription,
+		}
+	}
+
+	if parsed.AccessToken == "" {
+		return nil, ErrInvalidTokenResponse
+	}
+
+	token := &Token{
+		AccessToken: parsed.AccessToken,
+		TokenType:   parsed.TokenType,
+	}
+
+	if parsed.ExpiresIn > 0 {
+		token.Expiry = now.Add(time.Duration(parsed.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+// dial connects to the token endpoint, using TLS for https URLs. The
+// connection has the deadline of the context, and is closed once the context
+// is done before stop is closed.
+func (c *ClientCredentials) dial(ctx context.Context, endpoint *url.URL, stop <-chan struct{}) (net.Conn, error) {
+	address := endpoint.Host
+	if endpoint.Port() == "" {
+		port := "80"
+		if endpoint.Scheme == "https" {
+			port = "443"
+		}
+		address = net.JoinHostPort(endpoint.Hostname(), port)
+	}
+
+	dialer := c.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	// The deadline is set and the connection is watched before the TLS
+	// handshake, so that neither the handshake nor the token request can
+	// outlive the context.
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-stop:
+		}
+	}()
+
+	if endpoint.Scheme != "https" {
+		return conn, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if c.TLSConfig != nil {
+		tlsConfig = c.TLSConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = endpoint.Hostname()
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
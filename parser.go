@@ -0,0 +1,67 @@
+package gohttp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// defaultReadBufferSize is the default read buffer size of a Parser.
+const defaultReadBufferSize = 4096
+
+// Parser parses consecutive messages using a fixed set of options. It can be
+// reset to read from another connection, so that a worker can reuse a single
+// Parser and its read buffer for all connections it handles.
+//
+// A Parser is not safe for concurrent use.
+type Parser struct {
+	config config
+	source bufferedSource
+}
+
+// NewParser creates a new Parser with the given options. It has to be reset
+// to a reader before parsing.
+func NewParser(options ...Option) *Parser {
+	config := newConfig(options...)
+
+	size := config.readBufferSize
+	if size <= 0 {
+		size = defaultReadBufferSize
+	}
+
+	return &Parser{
+		config: config,
+		source: bufferedSource{
+			Reader: bufio.NewReaderSize(nil, size),
+		},
+	}
+}
+
+// Reset discards any buffered data and makes the parser read from r, reusing
+// the read buffer. If r is a net.Conn, its read deadline is used by the
+// Source returned by Source.
+func (p *Parser) Reset(r io.Reader) {
+	p.source.Reader.Reset(r)
+	p.source.setReadDeadline = nil
+
+	if conn, ok := r.(net.Conn); ok {
+		p.source.setReadDeadline = conn.SetReadDeadline
+	}
+}
+
+// Source returns the Source the parser reads from, e.g. to set a deadline
+// for the next message.
+func (p *Parser) Source() Source {
+	return &p.source
+}
+
+// ParseRequest parses the next request like ParseRequestSource.
+func (p *Parser) ParseRequest() (*http.Request, error) {
+	return parseRequest(&p.source, p.config)
+}
+
+// ParseResponse parses the next response like ParseResponseSource.
+func (p *Parser) ParseResponse() (*http.Response, error) {
+	return parseResponse(&p.source, p.config)
+}
@@ -0,0 +1,64 @@
+package gohttp
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParser_Reset(t *testing.T) {
+	parser := NewParser(WithLFLineEndings(true))
+
+	testCases := map[string]struct {
+		source        string
+		expectedPaths []string
+	}{
+		"single request": {
+			source:        "GET /a HTTP/1.1\nHost: a\n\n",
+			expectedPaths: []string{"/a"},
+		},
+		"pipelined requests": {
+			source:        "POST /b HTTP/1.1\r\nContent-Length: 2\r\n\r\nhiGET /c HTTP/1.1\r\n\r\n",
+			expectedPaths: []string{"/b", "/c"},
+		},
+	}
+
+	for name, tc := range testCases {
+		parser.Reset(strings.NewReader(tc.source))
+
+		for _, expectedPath := range tc.expectedPaths {
+			request, err := parser.ParseRequest()
+			if err != nil {
+				t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+			}
+
+			if request.URL.Path != expectedPath {
+				t.Errorf("'%s': expected path %s, got %s", name, expectedPath, request.URL.Path)
+			}
+
+			_, _ = ioutil.ReadAll(request.Body)
+		}
+	}
+}
+
+func TestParser_Source(t *testing.T) {
+	parser := NewParser()
+	parser.Reset(strings.NewReader(""))
+
+	if err := parser.Source().SetReadDeadline(time.Time{}); !errors.Is(err, ErrDeadlineUnsupported) {
+		t.Errorf("expected error %v, got %v", ErrDeadlineUnsupported, err)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	parser.Reset(server)
+
+	if err := parser.Source().SetReadDeadline(time.Time{}); err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+	}
+}
@@ -0,0 +1,142 @@
+// Package pipeline provides a Processor interface and a registry for
+// request and response processors, e.g. for authentication, rewriting, or
+// scoring. Processors are registered by name and assembled into ordered
+// pipelines, so that third parties can ship processors without modifying
+// the code that runs them.
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Processor processes requests and their responses.
+type Processor interface {
+	// Name returns the unique name the processor is registered with.
+	Name() string
+	// ProcessRequest processes a request. Returning a non-nil response
+	// short-circuits the pipeline, and the response is sent instead of
+	// passing the request on.
+	ProcessRequest(request *http.Request) (*http.Response, error)
+	// ProcessResponse processes the response to a request.
+	ProcessResponse(request *http.Request, response *http.Response) error
+}
+
+// Registry holds processors by name. It is safe for concurrent use.
+type Registry struct {
+	mutex      sync.RWMutex
+	processors map[string]Processor
+}
+
+// NewRegistry creates a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		processors: make(map[string]Processor),
+	}
+}
+
+// Register adds a processor to the registry. It returns an error if another
+// processor with the same name has been registered.
+func (r *Registry) Register(processor Processor) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.processors[processor.Name()]; exists {
+		return fmt.Errorf("processor %s has already been registered", processor.Name())
+	}
+
+	r.processors[processor.Name()] = processor
+
+	return nil
+}
+
+// Lookup returns the processor with the given name.
+func (r *Registry) Lookup(name string) (Processor, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	processor, ok := r.processors[name]
+	return processor, ok
+}
+
+// Names returns the names of all registered processors in sorted order.
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.processors))
+	for name := range r.processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Pipeline assembles the processors with the given names into a Pipeline,
+// in the given order.
+func (r *Registry) Pipeline(names ...string) (*Pipeline, error) {
+	pipeline := &Pipeline{}
+
+	for _, name := range names {
+		processor, ok := r.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("processor %s has not been registered", name)
+		}
+		pipeline.processors = append(pipeline.processors, processor)
+	}
+
+	return pipeline, nil
+}
+
+// DefaultRegistry is the registry used by the package-level functions.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a processor to the DefaultRegistry. It is typically called
+// in the init function of a package providing a processor.
+func Register(processor Processor) error {
+	return DefaultRegistry.Register(processor)
+}
+
+// New assembles processors of the DefaultRegistry into a Pipeline.
+func New(names ...string) (*Pipeline, error) {
+	return DefaultRegistry.Pipeline(names...)
+}
+
+// Pipeline runs processors in a fixed order.
+type Pipeline struct {
+	processors []Processor
+}
+
+// ProcessRequest runs the request through all processors in order. If a
+// processor returns a response, the remaining processors are skipped and
+// the response is returned.
+func (p *Pipeline) ProcessRequest(request *http.Request) (*http.Response, error) {
+	for _, processor := range p.processors {
+		response, err := processor.ProcessRequest(request)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", processor.Name(), err)
+		}
+		if response != nil {
+			return response, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ProcessResponse runs the response through all processors in reverse
+// order, so that the first processor sees the request first and the
+// response last, just like nested middleware.
+func (p *Pipeline) ProcessResponse(request *http.Request, response *http.Response) error {
+	for i := len(p.processors) - 1; i >= 0; i-- {
+		processor := p.processors[i]
+		if err := processor.ProcessResponse(request, response); err != nil {
+			return fmt.Errorf("%s: %w", processor.Name(), err)
+		}
+	}
+
+	return nil
+}
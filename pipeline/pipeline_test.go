@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+type testProcessor struct {
+	name  string
+	block bool
+	fail  bool
+	calls *[]string
+}
+
+func (t testProcessor) Name() string {
+	return t.name
+}
+
+func (t testProcessor) ProcessRequest(request *http.Request) (*http.Response, error) {
+	*t.calls = append(*t.calls, t.name+":request")
+
+	if t.fail {
+		return nil, errors.New("failed")
+	}
+	if t.block {
+		return gohttp.NewResponse(http.StatusForbidden, nil), nil
+	}
+	return nil, nil
+}
+
+func (t testProcessor) ProcessResponse(request *http.Request, response *http.Response) error {
+	*t.calls = append(*t.calls, t.name+":response")
+	return nil
+}
+
+func TestPipeline(t *testing.T) {
+	testCases := map[string]struct {
+		processors    []testProcessor
+		expectedCalls []string
+		expectedCode  int
+		expectedError bool
+	}{
+		"all processors": {
+			processors: []testProcessor{{name: "auth"}, {name: "rewrite"}},
+			expectedCalls: []string{
+				"auth:request", "rewrite:request",
+				"rewrite:response", "auth:response",
+			},
+		},
+		"short-circuit": {
+			processors:    []testProcessor{{name: "auth", block: true}, {name: "rewrite"}},
+			expectedCalls: []string{"auth:request"},
+			expectedCode:  http.StatusForbidden,
+		},
+		"error": {
+			processors:    []testProcessor{{name: "auth", fail: true}, {name: "rewrite"}},
+			expectedCalls: []string{"auth:request"},
+			expectedError: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		var calls []string
+		registry := NewRegistry()
+		var names []string
+
+		for _, processor := range tc.processors {
+			processor.calls = &calls
+			if err := registry.Register(processor); err != nil {
+				t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+			}
+			names = append(names, processor.name)
+		}
+
+		pipeline, err := registry.Pipeline(names...)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		request := httptest.NewRequest("GET", "/", nil)
+
+		response, err := pipeline.ProcessRequest(request)
+		if (err != nil) != tc.expectedError {
+			t.Fatalf("'%s': unexpected error: %v", name, err)
+		}
+
+		if response == nil && err == nil {
+			if err := pipeline.ProcessResponse(request, gohttp.NewResponse(http.StatusOK, nil)); err != nil {
+				t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+			}
+		}
+
+		if response != nil && response.StatusCode != tc.expectedCode {
+			t.Errorf("'%s': expected status code %d, got %d", name, tc.expectedCode, response.StatusCode)
+		}
+
+		if !reflect.DeepEqual(calls, tc.expectedCalls) {
+			t.Errorf("'%s': expected calls %v, got %v", name, tc.expectedCalls, calls)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	var calls []string
+	registry := NewRegistry()
+
+	if err := registry.Register(testProcessor{name: "b", calls: &calls}); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if err := registry.Register(testProcessor{name: "a", calls: &calls}); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if err := registry.Register(testProcessor{name: "a", calls: &calls}); err == nil {
+		t.Errorf("expected an error for a duplicate processor")
+	}
+
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("expected names %v, got %v", []string{"a", "b"}, names)
+	}
+
+	if _, err := registry.Pipeline("a", "missing"); err == nil {
+		t.Errorf("expected an error for a missing processor")
+	}
+}
@@ -0,0 +1,155 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unixTimeThreshold is the value above which X-RateLimit-Reset values are
+// interpreted as Unix timestamps rather than as delta-seconds.
+const unixTimeThreshold = 1000000000
+
+// RetryAfter returns the point in time announced by the Retry-After header
+// field of a response (RFC 7231, section 7.1.3.). The value may either be a
+// number of seconds relative to now or an HTTP-date.
+func RetryAfter(header http.Header, now time.Time) (time.Time, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return date, true
+	}
+
+	return time.Time{}, false
+}
+
+// Quota is the rate limit quota announced by a server.
+type Quota struct {
+	// Limit is the number of requests allowed within the current window, or
+	// -1 if unknown.
+	Limit int64
+	// Remaining is the number of requests left within the current window, or
+	// -1 if unknown.
+	Remaining int64
+	// Reset is the point in time the quota is reset, or the zero time if
+	// unknown.
+	Reset time.Time
+}
+
+// ParseQuota parses the rate limit header fields of a response. Supported
+// are the RateLimit header field, the RateLimit-Limit, RateLimit-Remaining,
+// and RateLimit-Reset header fields, as well as their widespread X-RateLimit-*
+// counterparts. It returns false if the response carries none of them.
+func ParseQuota(header http.Header, now time.Time) (Quota, bool) {
+	quota := Quota{
+		Limit:     -1,
+		Remaining: -1,
+	}
+
+	var found bool
+
+	if value := header.Get("RateLimit"); value != "" {
+		for _, param := range strings.Split(value, ",") {
+			tokens := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(tokens) != 2 {
+				continue
+			}
+			found = quota.set(strings.ToLower(tokens[0]), tokens[1], now, false) || found
+		}
+	}
+
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		for _, name := range []string{"limit", "remaining", "reset"} {
+			if value := header.Get(prefix + name); value != "" {
+				found = quota.set(name, value, now, prefix == "X-RateLimit-") || found
+			}
+		}
+	}
+
+	return quota, found
+}
+
+// Delay returns how long to wait before sending the next request, which is
+// the time until the reset if the quota has been exhausted and 0 otherwise.
+func (q Quota) Delay(now time.Time) time.Duration {
+	if q.Remaining != 0 || q.Reset.IsZero() || !q.Reset.After(now) {
+		return 0
+	}
+	return q.Reset.Sub(now)
+}
+
+// Interval returns the interval for spreading the remaining requests evenly
+// until the reset, e.g. for pacing a load generator. It returns 0 if the
+// quota is unknown.
+func (q Quota) Interval(now time.Time) time.Duration {
+	if q.Remaining < 0 || q.Reset.IsZero() || !q.Reset.After(now) {
+		return 0
+	}
+
+	if q.Remaining == 0 {
+		return q.Reset.Sub(now)
+	}
+
+	return q.Reset.Sub(now) / time.Duration(q.Remaining)
+}
+
+// RetryDelay returns how long a client should wait before retrying after
+// the given response, considering both Retry-After and the rate limit quota.
+func RetryDelay(header http.Header, now time.Time) time.Duration {
+	var delay time.Duration
+
+	if retryAfter, ok := RetryAfter(header, now); ok && retryAfter.After(now) {
+		delay = retryAfter.Sub(now)
+	}
+
+	if quota, ok := ParseQuota(header, now); ok {
+		if quotaDelay := quota.Delay(now); quotaDelay > delay {
+			delay = quotaDelay
+		}
+	}
+
+	return delay
+}
+
+// set sets the quota field with the given name if it isn't set yet. Values
+// may be followed by parameters like ";w=60", which are ignored.
+func (q *Quota) set(name, value string, now time.Time, allowUnixTime bool) bool {
+	value = strings.TrimSpace(strings.SplitN(strings.SplitN(value, ",", 2)[0], ";", 2)[0])
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return false
+	}
+
+	switch name {
+	case "limit":
+		if q.Limit < 0 {
+			q.Limit = n
+		}
+	case "remaining":
+		if q.Remaining < 0 {
+			q.Remaining = n
+		}
+	case "reset":
+		if !q.Reset.IsZero() {
+			break
+		}
+		if allowUnixTime && n >= unixTimeThreshold {
+			q.Reset = time.Unix(n, 0)
+		} else {
+			q.Reset = now.Add(time.Duration(n) * time.Second)
+		}
+	default:
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		value    string
+		expected time.Time
+		ok       bool
+	}{
+		"delta-seconds": {
+			value:    "120",
+			expected: now.Add(2 * time.Minute),
+			ok:       true,
+		},
+		"HTTP-date": {
+			value:    "Mon, 01 Jun 2020 12:05:00 GMT",
+			expected: now.Add(5 * time.Minute),
+			ok:       true,
+		},
+		"invalid": {
+			value: "soon",
+		},
+		"negative": {
+			value: "-5",
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, ok := RetryAfter(http.Header{"Retry-After": {tc.value}}, now)
+		if ok != tc.ok {
+			t.Errorf("'%s': expected ok %v, got %v", name, tc.ok, ok)
+		}
+
+		if !actual.Equal(tc.expected) {
+			t.Errorf("'%s': expected time %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	now := time.Unix(1590000000, 0)
+
+	testCases := map[string]struct {
+		header           http.Header
+		expected         Quota
+		expectedDelay    time.Duration
+		expectedInterval time.Duration
+	}{
+		"RateLimit": {
+			header:           http.Header{"Ratelimit": {"limit=100, remaining=50, reset=50"}},
+			expected:         Quota{Limit: 100, Remaining: 50, Reset: now.Add(50 * time.Second)},
+			expectedInterval: time.Second,
+		},
+		"RateLimit-*": {
+			header: http.Header{
+				"Ratelimit-Limit":     {"100, 100;w=60"},
+				"Ratelimit-Remaining": {"0"},
+				"Ratelimit-Reset":     {"30"},
+			},
+			expected:         Quota{Limit: 100, Remaining: 0, Reset: now.Add(30 * time.Second)},
+			expectedDelay:    30 * time.Second,
+			expectedInterval: 30 * time.Second,
+		},
+		"X-RateLimit-* with Unix time": {
+			header: http.Header{
+				"X-Ratelimit-Limit":     {"5000"},
+				"X-Ratelimit-Remaining": {"10"},
+				"X-Ratelimit-Reset":     {"1590000100"},
+			},
+			expected:         Quota{Limit: 5000, Remaining: 10, Reset: now.Add(100 * time.Second)},
+			expectedInterval: 10 * time.Second,
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, ok := ParseQuota(tc.header, now)
+		if !ok {
+			t.Fatalf("'%s': expected a quota, got none", name)
+		}
+
+		if actual.Limit != tc.expected.Limit || actual.Remaining != tc.expected.Remaining || !actual.Reset.Equal(tc.expected.Reset) {
+			t.Errorf("'%s': expected quota %v, got %v", name, tc.expected, actual)
+		}
+
+		if delay := actual.Delay(now); delay != tc.expectedDelay {
+			t.Errorf("'%s': expected delay %s, got %s", name, tc.expectedDelay, delay)
+		}
+
+		if interval := actual.Interval(now); interval != tc.expectedInterval {
+			t.Errorf("'%s': expected interval %s, got %s", name, tc.expectedInterval, interval)
+		}
+	}
+
+	if _, ok := ParseQuota(http.Header{}, now); ok {
+		t.Errorf("expected no quota for empty header")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	now := time.Unix(1590000000, 0)
+
+	header := http.Header{
+		"Retry-After":         {"10"},
+		"Ratelimit-Remaining": {"0"},
+		"Ratelimit-Reset":     {"20"},
+	}
+
+	if delay := RetryDelay(header, now); delay != 20*time.Second {
+		t.Errorf("expected delay %s, got %s", 20*time.Second, delay)
+	}
+}
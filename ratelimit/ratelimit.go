@@ -0,0 +1,162 @@
+// Package ratelimit provides token bucket rate limiting keyed by attributes
+// of parsed requests. It can be used as a standalone decision API, e.g. by
+// proxies embedding the parser, or as an http.Handler middleware. For clients,
+// it interprets the Retry-After and rate limit header fields of responses.
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// pruneInterval is the number of decisions after which buckets that have
+// been refilled completely are removed.
+const pruneInterval = 1024
+
+// KeyFunc returns the key a request is rate limited by. Requests with the
+// same key share a token bucket.
+type KeyFunc func(r *http.Request) string
+
+// ByClientIP limits requests by the IP address of the client.
+func ByClientIP() KeyFunc {
+	return func(r *http.Request) string {
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	}
+}
+
+// ByHost limits requests by the requested host.
+func ByHost() KeyFunc {
+	return gohttp.RequestHost
+}
+
+// ByPath limits requests by the path of the request target.
+func ByPath() KeyFunc {
+	return func(r *http.Request) string {
+		return r.URL.Path
+	}
+}
+
+// ByHeader limits requests by the value of the given header field.
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket rate limiter. Each key gets its own bucket that
+// holds up to burst tokens and is refilled with rate tokens per second. It
+// is safe for concurrent use.
+type Limiter struct {
+	rate    float64
+	burst   float64
+	key     KeyFunc
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+// New creates a new Limiter allowing rate requests per second with bursts
+// of up to burst requests per key.
+func New(rate float64, burst int, key KeyFunc) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		key:     key,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether the request may pass. If it may not, Allow returns
+// the duration after which the next request with the same key is allowed.
+func (l *Limiter) Allow(r *http.Request) (bool, time.Duration) {
+	return l.AllowKey(l.key(r))
+}
+
+// AllowKey is like Allow but takes the key directly.
+func (l *Limiter) AllowKey(key string) (bool, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+
+	if l.calls++; l.calls%pruneInterval == 0 {
+		l.prune(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	if l.rate <= 0 {
+		return false, time.Duration(math.MaxInt64)
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+
+	return false, wait
+}
+
+// prune removes all buckets that are full again, since they behave exactly
+// like new ones.
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware returns an http.Handler that answers rate limited requests with
+// 429 Too Many Requests and passes all other requests to next.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, retryAfter := l.Allow(r)
+		if !allowed {
+			w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TooManyRequests creates a 429 Too Many Requests response with a
+// Retry-After header field, ready to be serialized.
+func TooManyRequests(retryAfter time.Duration) *http.Response {
+	response := gohttp.NewResponse(http.StatusTooManyRequests, nil)
+	response.Header.Set("Retry-After", retryAfterSeconds(retryAfter))
+
+	return response
+}
+
+// retryAfterSeconds formats a duration as delta-seconds, rounded up.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	type step struct {
+		advance    time.Duration
+		remoteAddr string
+		allowed    bool
+		retryAfter time.Duration
+	}
+
+	testCases := map[string]struct {
+		rate  float64
+		burst int
+		steps []step
+	}{
+		"burst exhausted": {
+			rate:  1,
+			burst: 2,
+			steps: []step{
+				{remoteAddr: "10.0.0.1:1000", allowed: true},
+				{remoteAddr: "10.0.0.1:1001", allowed: true},
+				{remoteAddr: "10.0.0.1:1002", allowed: false, retryAfter: time.Second},
+			},
+		},
+		"refill": {
+			rate:  2,
+			burst: 1,
+			steps: []step{
+				{remoteAddr: "10.0.0.1:1000", allowed: true},
+				{remoteAddr: "10.0.0.1:1000", allowed: false, retryAfter: 500 * time.Millisecond},
+				{advance: 500 * time.Millisecond, remoteAddr: "10.0.0.1:1000", allowed: true},
+			},
+		},
+		"separate keys": {
+			rate:  1,
+			burst: 1,
+			steps: []step{
+				{remoteAddr: "10.0.0.1:1000", allowed: true},
+				{remoteAddr: "10.0.0.2:1000", allowed: true},
+				{remoteAddr: "10.0.0.1:1000", allowed: false, retryAfter: time.Second},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		current := time.Unix(0, 0)
+
+		limiter := New(tc.rate, tc.burst, ByClientIP())
+		limiter.now = func() time.Time { return current }
+
+		for i, s := range tc.steps {
+			current = current.Add(s.advance)
+
+			allowed, retryAfter := limiter.Allow(&http.Request{RemoteAddr: s.remoteAddr})
+
+			if allowed != s.allowed {
+				t.Errorf("'%s': step %d: expected allowed %v, got %v", name, i, s.allowed, allowed)
+			}
+
+			if retryAfter != s.retryAfter {
+				t.Errorf("'%s': step %d: expected retry after %v, got %v", name, i, s.retryAfter, retryAfter)
+			}
+		}
+	}
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	limiter := New(1, 1, ByPath())
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := httptest.NewRequest("GET", "/limited", nil)
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, request)
+
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, request)
+
+	if first.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, first.Code)
+	}
+
+	if second.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status code %d, got %d", http.StatusTooManyRequests, second.Code)
+	}
+
+	if second.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After %s, got %s", "1", second.Header().Get("Retry-After"))
+	}
+}
+
+func TestTooManyRequests(t *testing.T) {
+	expected := "HTTP/1.1 429 Too Many Requests\r\n"
+
+	actual, err := gohttp.SerializeResponse(TooManyRequests(1500 * time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if string(actual[:len(expected)]) != expected {
+		t.Errorf("expected status line %q, got %q", expected, string(actual))
+	}
+
+	response := TooManyRequests(1500 * time.Millisecond)
+
+	if response.Header.Get("Retry-After") != "2" {
+		t.Errorf("expected Retry-After %s, got %s", "2", response.Header.Get("Retry-After"))
+	}
+}
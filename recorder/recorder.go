@@ -0,0 +1,145 @@
+// Package recorder provides an in-memory flight recorder keeping the most
+// recent transactions, like a black box for investigating incidents.
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+	"github.com/dominikbraun/gohttp/blobstore"
+)
+
+// Recorder stores the last N transactions in a ring buffer. It is safe for
+// concurrent use.
+type Recorder struct {
+	mutex        sync.Mutex
+	transactions []gohttp.Transaction
+	next         int
+	full         bool
+	filter       func(request *http.Request, response *http.Response) bool
+}
+
+// New creates a new Recorder keeping the given number of transactions.
+func New(size int) *Recorder {
+	if size < 1 {
+		size = 1
+	}
+
+	return &Recorder{
+		transactions: make([]gohttp.Transaction, size),
+	}
+}
+
+// Record stores a transaction, evicting the oldest one if the recorder is
+// full. A zero Time is set to the current time.
+func (r *Recorder) Record(transaction gohttp.Transaction) {
+	if transaction.Time.IsZero() {
+		transaction.Time = time.Now()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.transactions[r.next] = transaction
+	r.next = (r.next + 1) % len(r.transactions)
+
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// SetFilter sets a function deciding which messages passed to RecordMessages
+// are recorded, e.g. a capture.Filter. A nil filter records all messages.
+func (r *Recorder) SetFilter(filter func(request *http.Request, response *http.Response) bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.filter = filter
+}
+
+// RecordMessages serializes and stores a request and its response unless
+// they are rejected by the filter. The bodies of both messages remain
+// readable.
+func (r *Recorder) RecordMessages(request *http.Request, response *http.Response) error {
+	r.mutex.Lock()
+	filter := r.filter
+	r.mutex.Unlock()
+
+	if filter != nil && !filter(request, response) {
+		return nil
+	}
+
+	transaction, err := gohttp.NewTransaction(request, response)
+	if err != nil {
+		return err
+	}
+
+	r.Record(transaction)
+
+	return nil
+}
+
+// Transactions returns the recorded transactions, oldest first.
+func (r *Recorder) Transactions() []gohttp.Transaction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.full {
+		return append([]gohttp.Transaction(nil), r.transactions[:r.next]...)
+	}
+
+	transactions := make([]gohttp.Transaction, 0, len(r.transactions))
+	transactions = append(transactions, r.transactions[r.next:]...)
+	transactions = append(transactions, r.transactions[:r.next]...)
+
+	return transactions
+}
+
+// Dump writes all recorded transactions to w in a human-readable format,
+// oldest first. Each transaction is introduced by a line containing its
+// timestamp, followed by the raw request and response.
+func (r *Recorder) Dump(w io.Writer) error {
+	for i, transaction := range r.Transactions() {
+		if _, err := fmt.Fprintf(w, "=== #%d %s\n", i+1, transaction.Time.Format(time.RFC3339Nano)); err != nil {
+			return err
+		}
+
+		for _, message := range [][]byte{transaction.Request, transaction.Response} {
+			if _, err := w.Write(message); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Save writes the dump of all recorded transactions to the given store under
+// the given key, e.g. to preserve the traffic preceding an incident.
+func (r *Recorder) Save(ctx context.Context, store blobstore.Store, key string) error {
+	var buf bytes.Buffer
+
+	if err := r.Dump(&buf); err != nil {
+		return err
+	}
+
+	return store.Put(ctx, key, &buf)
+}
+
+// Handler returns an http.Handler serving the dump of all recorded
+// transactions, intended for debug endpoints.
+func (r *Recorder) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = r.Dump(w)
+	})
+}
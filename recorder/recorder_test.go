@@ -0,0 +1,167 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+	"github.com/dominikbraun/gohttp/blobstore"
+)
+
+func TestRecorder_Transactions(t *testing.T) {
+	testCases := map[string]struct {
+		size     int
+		records  int
+		expected []string
+	}{
+		"not full": {
+			size:     3,
+			records:  2,
+			expected: []string{"0", "1"},
+		},
+		"full": {
+			size:     3,
+			records:  3,
+			expected: []string{"0", "1", "2"},
+		},
+		"wrapped": {
+			size:     3,
+			records:  5,
+			expected: []string{"2", "3", "4"},
+		},
+	}
+
+	for name, tc := range testCases {
+		recorder := New(tc.size)
+
+		for i := 0; i < tc.records; i++ {
+			recorder.Record(gohttp.Transaction{Request: []byte{byte('0' + i)}})
+		}
+
+		actual := recorder.Transactions()
+
+		if len(actual) != len(tc.expected) {
+			t.Fatalf("'%s': expected %d transactions, got %d", name, len(tc.expected), len(actual))
+		}
+
+		for i, transaction := range actual {
+			if string(transaction.Request) != tc.expected[i] {
+				t.Errorf("'%s': expected transaction %s at %d, got %s", name, tc.expected[i], i, string(transaction.Request))
+			}
+			if transaction.Time.IsZero() {
+				t.Errorf("'%s': expected a timestamp", name)
+			}
+		}
+	}
+}
+
+func TestRecorder_Dump(t *testing.T) {
+	recorder := New(2)
+	recorder.Record(gohttp.Transaction{
+		Time:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Request:  []byte("GET / HTTP/1.1\r\n\r\n"),
+		Response: []byte("HTTP/1.1 204 No Content\r\n\r\n"),
+	})
+
+	var buf bytes.Buffer
+	if err := recorder.Dump(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := "=== #1 2020-01-02T03:04:05Z\n" +
+		"GET / HTTP/1.1\r\n\r\n\n" +
+		"HTTP/1.1 204 No Content\r\n\r\n\n"
+
+	if buf.String() != expected {
+		t.Errorf("expected dump %q, got %q", expected, buf.String())
+	}
+
+	recorder2 := httptest.NewRecorder()
+	recorder.Handler().ServeHTTP(recorder2, httptest.NewRequest("GET", "/debug/transactions", nil))
+
+	if recorder2.Body.String() != expected {
+		t.Errorf("expected handler output %q, got %q", expected, recorder2.Body.String())
+	}
+}
+
+func TestRecorder_RecordMessages(t *testing.T) {
+	recorder := New(1)
+
+	request := httptest.NewRequest("POST", "/", strings.NewReader("hi"))
+	request.Proto = "HTTP/1.1"
+
+	if err := recorder.RecordMessages(request, gohttp.NewResponse(http.StatusOK, nil)); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	transactions := recorder.Transactions()
+
+	if len(transactions) != 1 || !strings.HasPrefix(string(transactions[0].Response), "HTTP/1.1 200 OK") {
+		t.Errorf("unexpected transactions %v", transactions)
+	}
+}
+
+func TestRecorder_SetFilter(t *testing.T) {
+	recorder := New(2)
+	recorder.SetFilter(func(request *http.Request, response *http.Response) bool {
+		return response.StatusCode >= 500
+	})
+
+	for _, statusCode := range []int{http.StatusOK, http.StatusBadGateway} {
+		request := httptest.NewRequest("GET", "/", nil)
+
+		if err := recorder.RecordMessages(request, gohttp.NewResponse(statusCode, nil)); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	transactions := recorder.Transactions()
+
+	if len(transactions) != 1 || !strings.HasPrefix(string(transactions[0].Response), "HTTP/1.1 502") {
+		t.Errorf("expected only the 502 response to be recorded, got %v", transactions)
+	}
+}
+
+func TestRecorder_Save(t *testing.T) {
+	root, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	store, err := blobstore.NewFileStore(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	recorder := New(1)
+	recorder.Record(gohttp.Transaction{
+		Time:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Request:  []byte("GET / HTTP/1.1\r\n\r\n"),
+		Response: []byte("HTTP/1.1 204 No Content\r\n\r\n"),
+	})
+
+	if err := recorder.Save(context.Background(), store, "incidents/1.txt"); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	reader, err := store.Get(context.Background(), "incidents/1.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	defer reader.Close()
+
+	var expected bytes.Buffer
+	_ = recorder.Dump(&expected)
+
+	if actual, _ := ioutil.ReadAll(reader); string(actual) != expected.String() {
+		t.Errorf("expected saved dump %q, got %q", expected.String(), string(actual))
+	}
+}
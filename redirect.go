@@ -0,0 +1,60 @@
+package gohttp
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+)
+
+// Redirect creates a redirect response with the given status code, e.g. 302
+// Found. A relative location is resolved against the request target. For
+// GET requests, the response has a minimal HTML body linking to the new
+// location, for the sake of clients that don't follow redirects.
+func Redirect(r *http.Request, statusCode int, location string) (*http.Response, error) {
+	target, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.URL != nil {
+		target = r.URL.ResolveReference(target)
+	}
+
+	location = target.String()
+
+	var body []byte
+	if r.Method == http.MethodGet {
+		body = []byte(fmt.Sprintf("<a href=\"%s\">%s</a>.\n", html.EscapeString(location), http.StatusText(statusCode)))
+	}
+
+	response := NewResponse(statusCode, body)
+	response.Header.Set("Location", location)
+
+	if len(body) > 0 {
+		response.Header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	return response, nil
+}
+
+// ResponseLocation returns the URL of the Location header field of a parsed
+// response, resolved against the URL of the request it answers. It returns
+// http.ErrNoLocation if the response doesn't have a Location header field.
+func ResponseLocation(response *http.Response, requestURL *url.URL) (*url.URL, error) {
+	location := response.Header.Get("Location")
+	if location == "" {
+		return nil, http.ErrNoLocation
+	}
+
+	target, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	if requestURL == nil {
+		return target, nil
+	}
+
+	return requestURL.ResolveReference(target), nil
+}
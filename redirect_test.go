@@ -0,0 +1,96 @@
+package gohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRedirect(t *testing.T) {
+	testCases := map[string]struct {
+		method           string
+		target           string
+		location         string
+		expectedLocation string
+		expectedBody     bool
+	}{
+		"relative path": {
+			method:           http.MethodGet,
+			target:           "/a/b/c",
+			location:         "../d",
+			expectedLocation: "/a/d",
+			expectedBody:     true,
+		},
+		"absolute path": {
+			method:           http.MethodPost,
+			target:           "/a/b",
+			location:         "/login?next=%2Fa",
+			expectedLocation: "/login?next=%2Fa",
+		},
+		"absolute URL": {
+			method:           http.MethodGet,
+			target:           "/",
+			location:         "https://example.com/x",
+			expectedLocation: "https://example.com/x",
+			expectedBody:     true,
+		},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest(tc.method, tc.target, nil)
+
+		response, err := Redirect(request, http.StatusFound, tc.location)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if response.StatusCode != http.StatusFound {
+			t.Errorf("'%s': expected status code %d, got %d", name, http.StatusFound, response.StatusCode)
+		}
+
+		if location := response.Header.Get("Location"); location != tc.expectedLocation {
+			t.Errorf("'%s': expected location %s, got %s", name, tc.expectedLocation, location)
+		}
+
+		if (response.ContentLength > 0) != tc.expectedBody {
+			t.Errorf("'%s': expected body %v, got content length %d", name, tc.expectedBody, response.ContentLength)
+		}
+	}
+}
+
+func TestResponseLocation(t *testing.T) {
+	requestURL, _ := url.Parse("https://example.com/a/b")
+
+	testCases := map[string]struct {
+		location string
+		expected string
+	}{
+		"relative": {
+			location: "c",
+			expected: "https://example.com/a/c",
+		},
+		"absolute": {
+			location: "http://other.org/",
+			expected: "http://other.org/",
+		},
+	}
+
+	for name, tc := range testCases {
+		response := NewResponse(http.StatusFound, nil)
+		response.Header.Set("Location", tc.location)
+
+		actual, err := ResponseLocation(response, requestURL)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if actual.String() != tc.expected {
+			t.Errorf("'%s': expected location %s, got %s", name, tc.expected, actual.String())
+		}
+	}
+
+	if _, err := ResponseLocation(NewResponse(http.StatusOK, nil), requestURL); err != http.ErrNoLocation {
+		t.Errorf("expected error %v, got %v", http.ErrNoLocation, err)
+	}
+}
@@ -0,0 +1,125 @@
+package gohttp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// RequestIDHeader is the header field carrying the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the maximum length of a request ID accepted from a
+// client.
+const maxRequestIDLength = 128
+
+// requestIDKey is the context key for the request ID.
+type requestIDKey struct{}
+
+// EnsureRequestID returns the ID of a request and makes sure that it is set
+// in the X-Request-ID header field. If there is no valid X-Request-ID, the
+// trace ID of a W3C traceparent header field is used, and if there is none
+// either, a new random ID is generated. A client-supplied X-Request-ID is only
+// valid if it is at most 128 characters long and consists of letters, digits,
+// and the characters "-._:+=/", so that it can be safely logged and
+// propagated.
+func EnsureRequestID(r *http.Request) string {
+	id := r.Header.Get(RequestIDHeader)
+
+	if !validRequestID(id) {
+		id = ""
+	}
+
+	if id == "" {
+		id = traceID(r.Header.Get("Traceparent"))
+	}
+
+	if id == "" {
+		id = newRequestID()
+	}
+
+	r.Header.Set(RequestIDHeader, id)
+
+	return id
+}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty
+// string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// PropagateRequestID copies the request ID of a received request to an
+// upstream request, along with the traceparent header field if present.
+func PropagateRequestID(from, to *http.Request) {
+	to.Header.Set(RequestIDHeader, EnsureRequestID(from))
+
+	if traceparent := from.Header.Get("Traceparent"); traceparent != "" {
+		to.Header.Set("Traceparent", traceparent)
+	}
+}
+
+// RequestIDMiddleware returns an http.Handler that ensures each request has
+// an ID, stores it in the request context, and stamps it on the response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := EnsureRequestID(r)
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), id)))
+	})
+}
+
+// traceID extracts the trace ID from a traceparent header field value, which
+// looks like "00-<trace-id>-<parent-id>-<flags>".
+func traceID(traceparent string) string {
+	tokens := strings.Split(traceparent, "-")
+	if len(tokens) < 4 || len(tokens[1]) != 32 {
+		return ""
+	}
+
+	if _, err := hex.DecodeString(tokens[1]); err != nil || tokens[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+
+	return tokens[1]
+}
+
+// validRequestID reports whether a client-supplied request ID is non-empty,
+// bounded, and consists of safe characters only.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("-._:+=/", c) >= 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	var id [16]byte
+
+	if _, err := rand.Read(id[:]); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(id[:])
+}
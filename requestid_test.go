@@ -0,0 +1,98 @@
+package gohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnsureRequestID(t *testing.T) {
+	testCases := map[string]struct {
+		header   http.Header
+		expected string
+	}{
+		"existing request ID": {
+			header:   http.Header{"X-Request-Id": {"abc"}},
+			expected: "abc",
+		},
+		"invalid request ID": {
+			header: http.Header{"X-Request-Id": {"abc\ndef"}},
+		},
+		"too long request ID": {
+			header: http.Header{"X-Request-Id": {strings.Repeat("a", 129)}},
+		},
+		"invalid request ID with traceparent": {
+			header: http.Header{
+				"X-Request-Id": {"<script>"},
+				"Traceparent":  {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			},
+			expected: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		"traceparent": {
+			header:   http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}},
+			expected: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		"invalid traceparent": {
+			header: http.Header{"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"}},
+		},
+		"none": {
+			header: http.Header{},
+		},
+	}
+
+	for name, tc := range testCases {
+		request := &http.Request{Header: tc.header}
+		actual := EnsureRequestID(request)
+
+		if tc.expected != "" && actual != tc.expected {
+			t.Errorf("'%s': expected request ID %s, got %s", name, tc.expected, actual)
+		}
+
+		if tc.expected == "" && len(actual) != 32 {
+			t.Errorf("'%s': expected generated request ID, got %s", name, actual)
+		}
+
+		if request.Header.Get(RequestIDHeader) != actual {
+			t.Errorf("'%s': expected header %s, got %s", name, actual, request.Header.Get(RequestIDHeader))
+		}
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var fromContext string
+
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = RequestIDFromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set(RequestIDHeader, "abc")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if fromContext != "abc" {
+		t.Errorf("expected request ID %s in context, got %s", "abc", fromContext)
+	}
+
+	if recorder.Header().Get(RequestIDHeader) != "abc" {
+		t.Errorf("expected request ID %s in response, got %s", "abc", recorder.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	from := httptest.NewRequest("GET", "/", nil)
+	from.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	to := httptest.NewRequest("GET", "http://upstream/", nil)
+
+	PropagateRequestID(from, to)
+
+	if to.Header.Get(RequestIDHeader) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected propagated request ID, got %s", to.Header.Get(RequestIDHeader))
+	}
+
+	if to.Header.Get("Traceparent") != from.Header.Get("Traceparent") {
+		t.Errorf("expected propagated traceparent, got %s", to.Header.Get("Traceparent"))
+	}
+}
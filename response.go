@@ -0,0 +1,53 @@
+package gohttp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// NewResponse creates an HTTP/1.1 response with the given status code and
+// body, ready to be serialized using SerializeResponse. The Content-Length
+// header field is set according to the body.
+func NewResponse(statusCode int, body []byte) *http.Response {
+	response := &http.Response{
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		StatusCode: statusCode,
+		Status:     statusText(statusCode),
+		Header:     make(http.Header),
+		Body:       http.NoBody,
+	}
+
+	if bodyAllowedForStatus(statusCode) {
+		response.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		response.ContentLength = int64(len(body))
+	}
+
+	if len(body) > 0 {
+		response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	return response
+}
+
+// statusText returns the status code and the reason phrase as used in the
+// status line, e.g. "200 OK".
+func statusText(statusCode int) string {
+	return fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode))
+}
+
+// bodyAllowedForStatus reports whether a response with the given status code
+// may have a body (RFC 7230, section 3.3.).
+func bodyAllowedForStatus(statusCode int) bool {
+	switch {
+	case statusCode >= 100 && statusCode < 200:
+		return false
+	case statusCode == http.StatusNoContent, statusCode == http.StatusNotModified:
+		return false
+	}
+	return true
+}
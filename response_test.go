@@ -0,0 +1,45 @@
+package gohttp
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNewResponse(t *testing.T) {
+	testCases := map[string]struct {
+		statusCode int
+		body       string
+		expected   string
+	}{
+		"with body": {
+			statusCode: http.StatusNotFound,
+			body:       "not found",
+			expected: "HTTP/1.1 404 Not Found\r\n" +
+				"Content-Length: 9\r\n" +
+				"\r\n" +
+				"not found",
+		},
+		"without body": {
+			statusCode: http.StatusOK,
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 0\r\n" +
+				"\r\n",
+		},
+		"status without body": {
+			statusCode: http.StatusNotModified,
+			expected: "HTTP/1.1 304 Not Modified\r\n" +
+				"\r\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, err := SerializeResponse(NewResponse(tc.statusCode, []byte(tc.body)))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(actual) != tc.expected {
+			t.Errorf("'%s': expected response %q, got %q", name, tc.expected, string(actual))
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Package rewrite rewrites the targets of parsed requests before they are
+// serialized and sent upstream: it strips and prepends path prefixes,
+// rewrites paths using regular expressions, and adds, removes, and renames
+// query parameters.
+//
+// Paths are rewritten in their escaped form, so that encoded characters
+// like %2F survive a rewrite, and query parameters are rewritten without
+// re-encoding or reordering the parameters left untouched. Dot segments,
+// including percent-encoded ones like %2e%2e, are removed before and after
+// rewriting, so that a request can't escape a prefix added by a rewrite
+// once the upstream normalizes the path. A Rewrite is a
+// pipeline.Processor, so that each route can be given its own rules by
+// registering them under the route's name.
+package rewrite
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Spec declares the rewriting rules of a route. The path is rewritten in
+// the order StripPrefix, Pattern, AddPrefix, and the query in the order
+// RemoveQuery, RenameQuery, AddQuery. Prefixes and query parameters are
+// declared unescaped.
+type Spec struct {
+	// StripPrefix is removed from paths starting with it. It only matches
+	// whole path segments, so that /api doesn't match /apiv2.
+	StripPrefix string
+	// AddPrefix is prepended to the path.
+	AddPrefix string
+	// Pattern is a regular expression matched against the escaped path.
+	Pattern string
+	// Replacement replaces the matches of Pattern and may refer to capture
+	// groups using $1 or ${name}. It has to be escaped.
+	Replacement string
+	// RemoveQuery lists query parameters to remove.
+	RemoveQuery []string
+	// RenameQuery maps query parameter names to their new names.
+	RenameQuery map[string]string
+	// AddQuery maps query parameter names to values appended to the query.
+	AddQuery map[string]string
+}
+
+// Rewrite rewrites request targets using compiled rules. It is safe for
+// concurrent use.
+type Rewrite struct {
+	name        string
+	stripPrefix string
+	addPrefix   string
+	pattern     *regexp.Regexp
+	replacement string
+	remove      map[string]bool
+	rename      map[string]string
+	add         string
+}
+
+// Compile compiles the given rules into a Rewrite with the given processor
+// name.
+func Compile(name string, spec Spec) (*Rewrite, error) {
+	rewrite := &Rewrite{
+		name:        name,
+		stripPrefix: strings.TrimSuffix(escapePath(spec.StripPrefix), "/"),
+		addPrefix:   strings.TrimSuffix(escapePath(spec.AddPrefix), "/"),
+		replacement: spec.Replacement,
+	}
+
+	if spec.Pattern != "" {
+		pattern, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite %s: %w", name, err)
+		}
+		rewrite.pattern = pattern
+	}
+
+	if len(spec.RemoveQuery) > 0 {
+		rewrite.remove = make(map[string]bool, len(spec.RemoveQuery))
+		for _, key := range spec.RemoveQuery {
+			rewrite.remove[key] = true
+		}
+	}
+
+	if len(spec.RenameQuery) > 0 {
+		rewrite.rename = make(map[string]string, len(spec.RenameQuery))
+		for from, to := range spec.RenameQuery {
+			rewrite.rename[from] = url.QueryEscape(to)
+		}
+	}
+
+	// The added parameters are encoded once, sorted by name so that the
+	// rewritten query doesn't depend on the map order.
+	keys := make([]string, 0, len(spec.AddQuery))
+	for key := range spec.AddQuery {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(spec.AddQuery[key]))
+	}
+	rewrite.add = strings.Join(pairs, "&")
+
+	return rewrite, nil
+}
+
+// Name returns the name of the rewrite, e.g. the name of its route.
+func (r *Rewrite) Name() string {
+	return r.name
+}
+
+// ProcessRequest rewrites the request target. It returns an error if the
+// rewritten path isn't validly escaped, and never returns a response.
+func (r *Rewrite) ProcessRequest(request *http.Request) (*http.Response, error) {
+	return nil, r.Apply(request.URL)
+}
+
+// ProcessResponse doesn't modify the response.
+func (r *Rewrite) ProcessResponse(request *http.Request, response *http.Response) error {
+	return nil
+}
+
+// Apply rewrites the path and query of the given URL in place.
+func (r *Rewrite) Apply(u *url.URL) error {
+	escaped := r.rewritePath(u.EscapedPath())
+
+	path, err := url.PathUnescape(escaped)
+	if err != nil {
+		return err
+	}
+
+	u.Path = path
+	u.RawPath = ""
+	if escapePath(path) != escaped {
+		u.RawPath = escaped
+	}
+
+	u.RawQuery = r.rewriteQuery(u.RawQuery)
+
+	return nil
+}
+
+func (r *Rewrite) rewritePath(path string) string {
+	path = removeDotSegments(decodeDots(path))
+
+	if r.stripPrefix != "" && strings.HasPrefix(path, r.stripPrefix) {
+		rest := path[len(r.stripPrefix):]
+		if rest == "" || rest[0] == '/' {
+			path = rest
+		}
+	}
+
+	if r.pattern != nil {
+		// The replacement may contain dot segments, e.g. via a capture
+		// group, which must not climb above the added prefix.
+		path = removeDotSegments(decodeDots(r.pattern.ReplaceAllString(path, r.replacement)))
+	}
+
+	path = r.addPrefix + path
+
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+
+	return path
+}
+
+// decodeDots decodes percent-encoded dots. A dot is an unreserved character,
+// so decoding it doesn't change the meaning of the path (RFC 3986, section
+// 6.2.2.2.), but it reveals encoded dot segments.
+func decodeDots(path string) string {
+	if !strings.Contains(path, "%2") {
+		return path
+	}
+	return strings.NewReplacer("%2e", ".", "%2E", ".").Replace(path)
+}
+
+// removeDotSegments resolves the "." and ".." segments of a path (RFC 3986,
+// section 5.2.4.). A ".." segment never removes the root.
+func removeDotSegments(path string) string {
+	segments := strings.Split(path, "/")
+	output := make([]string, 0, len(segments))
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		switch segment {
+		case ".":
+		case "..":
+			if n := len(output); n > 1 || n == 1 && output[0] != "" {
+				output = output[:len(output)-1]
+			}
+		default:
+			output = append(output, segment)
+			continue
+		}
+
+		// A trailing dot segment leaves a trailing slash.
+		if last {
+			output = append(output, "")
+		}
+	}
+
+	return strings.Join(output, "/")
+}
+
+func (r *Rewrite) rewriteQuery(query string) string {
+	if r.remove == nil && r.rename == nil {
+		return join(query, r.add)
+	}
+
+	pairs := strings.Split(query, "&")
+	kept := pairs[:0]
+
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+
+		rawKey, value := pair, ""
+		if i := strings.IndexByte(pair, '='); i >= 0 {
+			rawKey, value = pair[:i], pair[i:]
+		}
+
+		// Parameters with an invalid encoding are compared by their raw
+		// name, so that they are passed on rather than dropped.
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+
+		if r.remove[key] {
+			continue
+		}
+
+		if to, ok := r.rename[key]; ok {
+			pair = to + value
+		}
+
+		kept = append(kept, pair)
+	}
+
+	return join(strings.Join(kept, "&"), r.add)
+}
+
+func join(query, add string) string {
+	if query == "" || add == "" {
+		return query + add
+	}
+	return query + "&" + add
+}
+
+// escapePath returns the escaped form of an unescaped path.
+func escapePath(path string) string {
+	return (&url.URL{Path: path}).EscapedPath()
+}
@@ -0,0 +1,167 @@
+package rewrite
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestRewrite_Apply(t *testing.T) {
+	testCases := map[string]struct {
+		spec          Spec
+		target        string
+		expected      string
+		expectedError bool
+	}{
+		"strip prefix": {
+			spec:     Spec{StripPrefix: "/api/"},
+			target:   "/api/users?id=1",
+			expected: "/users?id=1",
+		},
+		"strip whole path": {
+			spec:     Spec{StripPrefix: "/api"},
+			target:   "/api",
+			expected: "/",
+		},
+		"strip partial segment": {
+			spec:     Spec{StripPrefix: "/api"},
+			target:   "/apiv2/users",
+			expected: "/apiv2/users",
+		},
+		"add prefix": {
+			spec:     Spec{StripPrefix: "/api", AddPrefix: "/v2/"},
+			target:   "/api/users",
+			expected: "/v2/users",
+		},
+		"add unescaped prefix": {
+			spec:     Spec{AddPrefix: "/my files"},
+			target:   "/a",
+			expected: "/my%20files/a",
+		},
+		"encoded slash": {
+			spec:     Spec{StripPrefix: "/files"},
+			target:   "/files/a%2Fb/c",
+			expected: "/a%2Fb/c",
+		},
+		"pattern": {
+			spec:     Spec{Pattern: `^/users/(\d+)/posts$`, Replacement: "/posts/by-user/$1"},
+			target:   "/users/42/posts",
+			expected: "/posts/by-user/42",
+		},
+		"pattern with named group": {
+			spec:     Spec{Pattern: `^/(?P<lang>[a-z]{2})/(.*)$`, Replacement: "/${2}/${lang}"},
+			target:   "/de/docs/caf%C3%A9",
+			expected: "/docs/caf%C3%A9/de",
+		},
+		"invalid replacement escape": {
+			spec:          Spec{Pattern: `^/a$`, Replacement: "/%zz"},
+			target:        "/a",
+			expectedError: true,
+		},
+		"dot segments": {
+			spec:     Spec{StripPrefix: "/api", AddPrefix: "/public"},
+			target:   "/api/../admin",
+			expected: "/public/admin",
+		},
+		"encoded dot segments": {
+			spec:     Spec{StripPrefix: "/api", AddPrefix: "/public"},
+			target:   "/api/%2e%2e/admin",
+			expected: "/public/admin",
+		},
+		"mixed case encoded dot segments": {
+			spec:     Spec{StripPrefix: "/api", AddPrefix: "/public"},
+			target:   "/api/.%2E/%2E./admin",
+			expected: "/public/admin",
+		},
+		"dot segments within prefix": {
+			spec:     Spec{StripPrefix: "/api", AddPrefix: "/public"},
+			target:   "/api/v1/./../users/",
+			expected: "/public/users/",
+		},
+		"trailing dot segment": {
+			spec:     Spec{AddPrefix: "/public"},
+			target:   "/a/b/..",
+			expected: "/public/a/",
+		},
+		"dot segments from replacement": {
+			spec:     Spec{Pattern: `^/files/(.*)$`, Replacement: "/static/../../$1", AddPrefix: "/public"},
+			target:   "/files/secret",
+			expected: "/public/secret",
+		},
+		"encoded slash is no separator": {
+			spec:     Spec{StripPrefix: "/files", AddPrefix: "/public"},
+			target:   "/files/..%2F..%2Fsecret",
+			expected: "/public/..%2F..%2Fsecret",
+		},
+		"remove query": {
+			spec:     Spec{RemoveQuery: []string{"token", "debug mode"}},
+			target:   "/?a=1&token=secret&b=%2B&debug+mode=1&token",
+			expected: "/?a=1&b=%2B",
+		},
+		"rename query": {
+			spec:     Spec{RenameQuery: map[string]string{"q": "search term"}},
+			target:   "/?q=a%26b&page=2",
+			expected: "/?search+term=a%26b&page=2",
+		},
+		"add query": {
+			spec:     Spec{AddQuery: map[string]string{"source": "gateway", "b": "x&y"}},
+			target:   "/search",
+			expected: "/search?b=x%26y&source=gateway",
+		},
+		"invalid query encoding": {
+			spec:     Spec{RemoveQuery: []string{"a"}, AddQuery: map[string]string{"c": "3"}},
+			target:   "/?a=1&%zz=2",
+			expected: "/?%zz=2&c=3",
+		},
+	}
+
+	for name, tc := range testCases {
+		rewrite, err := Compile(name, tc.spec)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		request := httptest.NewRequest("GET", tc.target, nil)
+
+		_, err = rewrite.ProcessRequest(request)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+			continue
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if target := request.URL.RequestURI(); target != tc.expected {
+			t.Errorf("'%s': expected target %s, got %s", name, tc.expected, target)
+		}
+	}
+}
+
+func TestRewrite_Serialize(t *testing.T) {
+	source := "GET /api/a%2Fb?token=1&q=x HTTP/1.1\r\nHost: example.com\r\n\r\n"
+
+	request, err := gohttp.ParseRequest(bufio.NewReader(strings.NewReader(source)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	rewrite, _ := Compile("api", Spec{StripPrefix: "/api", AddPrefix: "/v1", RemoveQuery: []string{"token"}})
+	if _, err := rewrite.ProcessRequest(request); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	serialized, err := gohttp.SerializeRequest(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := "GET /v1/a%2Fb?q=x HTTP/1.1\r\n"
+	if !strings.HasPrefix(string(serialized), expected) {
+		t.Errorf("expected request line %q, got %q", expected, string(serialized))
+	}
+}
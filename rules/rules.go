@@ -0,0 +1,235 @@
+// Package rules provides a rule engine evaluating parsed requests against
+// user-declared conditions on the method, target, header fields, and body.
+// Matching rules can block the request, strip a header field, tag the
+// request, or log it.
+//
+// Rules are declared using Spec and compiled into an Engine once. The
+// compiled matchers check the cheapest conditions first, so that a request
+// is only matched against the body pattern if everything else matched.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// Action is the action taken when a rule matches.
+type Action int
+
+const (
+	// Block rejects the request with the status code of the rule.
+	Block Action = iota
+	// StripHeader removes the header field named by the rule.
+	StripHeader
+	// Tag adds the tag of the rule to the result.
+	Tag
+	// Log reports the match to the log function of the engine.
+	Log
+)
+
+// Spec declares a rule. All non-empty conditions have to match for the rule
+// to match.
+type Spec struct {
+	// ID identifies the rule in results and logs.
+	ID string
+	// Methods restricts the rule to the given methods.
+	Methods []string
+	// Target is a regular expression matched against the request target.
+	Target string
+	// Headers maps header field names to regular expressions that at least
+	// one value of the field has to match. An empty expression only checks
+	// for the presence of the field.
+	Headers map[string]string
+	// Body is a regular expression matched against the request body.
+	Body string
+	// Action is the action taken if the rule matches.
+	Action Action
+	// StatusCode is the status code used by Block. Defaults to 403.
+	StatusCode int
+	// Header is the header field removed by StripHeader.
+	Header string
+	// Tag is the tag added by Tag.
+	Tag string
+}
+
+type headerMatcher struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+type rule struct {
+	spec    Spec
+	methods map[string]bool
+	target  *regexp.Regexp
+	headers []headerMatcher
+	body    *regexp.Regexp
+}
+
+func (r *rule) matches(request *http.Request, body []byte) bool {
+	if r.methods != nil && !r.methods[request.Method] {
+		return false
+	}
+
+	if r.target != nil && !r.target.MatchString(request.URL.RequestURI()) {
+		return false
+	}
+
+	for _, header := range r.headers {
+		values := request.Header.Values(header.name)
+		if len(values) == 0 {
+			return false
+		}
+		if header.pattern != nil && !anyMatches(header.pattern, values) {
+			return false
+		}
+	}
+
+	if r.body != nil && !r.body.Match(body) {
+		return false
+	}
+
+	return true
+}
+
+func anyMatches(pattern *regexp.Regexp, values []string) bool {
+	for _, value := range values {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is the outcome of evaluating a request.
+type Result struct {
+	// Blocked indicates whether a Block rule matched.
+	Blocked bool
+	// StatusCode is the status code of the blocking rule.
+	StatusCode int
+	// BlockedBy is the ID of the blocking rule.
+	BlockedBy string
+	// Tags holds the tags of all matching Tag rules.
+	Tags []string
+	// Matched holds the IDs of all matching rules in evaluation order.
+	Matched []string
+}
+
+// Response returns the response for a blocked request, ready to be
+// serialized, or nil if the request hasn't been blocked.
+func (r Result) Response() *http.Response {
+	if !r.Blocked {
+		return nil
+	}
+	return gohttp.NewResponse(r.StatusCode, nil)
+}
+
+// Engine evaluates requests against compiled rules. It is safe for
+// concurrent use.
+type Engine struct {
+	rules []*rule
+	logf  func(ruleID string, request *http.Request)
+}
+
+// Compile compiles the given rules into an Engine. Rules are evaluated in
+// the given order.
+func Compile(specs ...Spec) (*Engine, error) {
+	engine := &Engine{}
+
+	for _, spec := range specs {
+		r, err := compile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", spec.ID, err)
+		}
+		engine.rules = append(engine.rules, r)
+	}
+
+	return engine, nil
+}
+
+func compile(spec Spec) (*rule, error) {
+	r := &rule{spec: spec}
+
+	if spec.Action == Block && r.spec.StatusCode == 0 {
+		r.spec.StatusCode = http.StatusForbidden
+	}
+
+	if spec.Action == StripHeader && spec.Header == "" {
+		return nil, errors.New("missing header to strip")
+	}
+
+	if len(spec.Methods) > 0 {
+		r.methods = make(map[string]bool)
+		for _, method := range spec.Methods {
+			r.methods[strings.ToUpper(method)] = true
+		}
+	}
+
+	var err error
+
+	if spec.Target != "" {
+		if r.target, err = regexp.Compile(spec.Target); err != nil {
+			return nil, err
+		}
+	}
+
+	for name, pattern := range spec.Headers {
+		matcher := headerMatcher{name: http.CanonicalHeaderKey(name)}
+		if pattern != "" {
+			if matcher.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, err
+			}
+		}
+		r.headers = append(r.headers, matcher)
+	}
+
+	if spec.Body != "" {
+		if r.body, err = regexp.Compile(spec.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// OnLog sets the function called for each matching Log rule.
+func (e *Engine) OnLog(logf func(ruleID string, request *http.Request)) {
+	e.logf = logf
+}
+
+// Evaluate evaluates the request and its body against all rules, applying
+// StripHeader actions to the request in place. Evaluation stops at the
+// first matching Block rule.
+func (e *Engine) Evaluate(request *http.Request, body []byte) Result {
+	var result Result
+
+	for _, r := range e.rules {
+		if !r.matches(request, body) {
+			continue
+		}
+
+		result.Matched = append(result.Matched, r.spec.ID)
+
+		switch r.spec.Action {
+		case Block:
+			result.Blocked = true
+			result.StatusCode = r.spec.StatusCode
+			result.BlockedBy = r.spec.ID
+			return result
+		case StripHeader:
+			request.Header.Del(r.spec.Header)
+		case Tag:
+			result.Tags = append(result.Tags, r.spec.Tag)
+		case Log:
+			if e.logf != nil {
+				e.logf(r.spec.ID, request)
+			}
+		}
+	}
+
+	return result
+}
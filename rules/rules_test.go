@@ -0,0 +1,140 @@
+package rules
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEngine_Evaluate(t *testing.T) {
+	engine, err := Compile(
+		Spec{ID: "strip-internal", Headers: map[string]string{"X-Internal": ""}, Action: StripHeader, Header: "X-Internal"},
+		Spec{ID: "tag-api", Target: `^/api/`, Action: Tag, Tag: "api"},
+		Spec{ID: "log-admin", Target: `^/admin`, Action: Log},
+		Spec{ID: "block-traversal", Target: `\.\./`, Action: Block, StatusCode: http.StatusBadRequest},
+		Spec{ID: "block-sqli", Methods: []string{"post"}, Body: `(?i)union\s+select`, Action: Block},
+		Spec{ID: "block-scanner", Headers: map[string]string{"User-Agent": `(?i)sqlmap`}, Action: Block},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var logged []string
+	engine.OnLog(func(ruleID string, request *http.Request) {
+		logged = append(logged, ruleID)
+	})
+
+	testCases := map[string]struct {
+		method         string
+		target         string
+		headers        http.Header
+		body           string
+		expected       Result
+		expectedLogged []string
+	}{
+		"no match": {
+			method:   "GET",
+			target:   "/",
+			expected: Result{},
+		},
+		"tag": {
+			method:   "GET",
+			target:   "/api/users",
+			expected: Result{Tags: []string{"api"}, Matched: []string{"tag-api"}},
+		},
+		"log": {
+			method:         "GET",
+			target:         "/admin/settings",
+			expected:       Result{Matched: []string{"log-admin"}},
+			expectedLogged: []string{"log-admin"},
+		},
+		"block target": {
+			method:   "GET",
+			target:   "/api/../etc/passwd",
+			expected: Result{Blocked: true, StatusCode: 400, BlockedBy: "block-traversal", Tags: []string{"api"}, Matched: []string{"tag-api", "block-traversal"}},
+		},
+		"block body": {
+			method:   "POST",
+			target:   "/search",
+			body:     "q=1 UNION SELECT password FROM users",
+			expected: Result{Blocked: true, StatusCode: 403, BlockedBy: "block-sqli", Matched: []string{"block-sqli"}},
+		},
+		"body rule restricted to method": {
+			method:   "PUT",
+			target:   "/search",
+			body:     "q=1 UNION SELECT password FROM users",
+			expected: Result{},
+		},
+		"block header": {
+			method:   "GET",
+			target:   "/",
+			headers:  http.Header{"User-Agent": {"sqlmap/1.4"}},
+			expected: Result{Blocked: true, StatusCode: 403, BlockedBy: "block-scanner", Matched: []string{"block-scanner"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		logged = nil
+
+		request := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		for key, values := range tc.headers {
+			request.Header[key] = values
+		}
+
+		actual := engine.Evaluate(request, []byte(tc.body))
+
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("'%s': expected result %+v, got %+v", name, tc.expected, actual)
+		}
+
+		if !reflect.DeepEqual(logged, tc.expectedLogged) {
+			t.Errorf("'%s': expected logged rules %v, got %v", name, tc.expectedLogged, logged)
+		}
+	}
+}
+
+func TestEngine_EvaluateStripHeader(t *testing.T) {
+	engine, _ := Compile(Spec{ID: "strip", Headers: map[string]string{"x-internal-user": ""}, Action: StripHeader, Header: "X-Internal-User"})
+
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("X-Internal-User", "admin")
+
+	result := engine.Evaluate(request, nil)
+
+	if request.Header.Get("X-Internal-User") != "" {
+		t.Errorf("expected header to be stripped")
+	}
+
+	if result.Blocked || len(result.Matched) != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	testCases := map[string]Spec{
+		"invalid target":     {ID: "a", Target: "("},
+		"invalid header":     {ID: "b", Headers: map[string]string{"Host": "["}},
+		"invalid body":       {ID: "c", Body: "(?P<"},
+		"missing strip name": {ID: "d", Action: StripHeader},
+	}
+
+	for name, spec := range testCases {
+		if _, err := Compile(spec); err == nil {
+			t.Errorf("'%s': expected an error, got none", name)
+		}
+	}
+}
+
+func TestResult_Response(t *testing.T) {
+	if (Result{}).Response() != nil {
+		t.Errorf("expected no response for a request that hasn't been blocked")
+	}
+
+	response := Result{Blocked: true, StatusCode: http.StatusForbidden}.Response()
+
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status code %d, got %d", http.StatusForbidden, response.StatusCode)
+	}
+}
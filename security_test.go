@@ -0,0 +1,68 @@
+package gohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders_Apply(t *testing.T) {
+	testCases := map[string]struct {
+		headers  SecurityHeaders
+		existing http.Header
+		expected map[string]string
+	}{
+		"strict": {
+			headers:  StrictSecurityHeaders(),
+			existing: http.Header{},
+			expected: map[string]string{
+				"X-Content-Type-Options": "nosniff",
+				"X-Frame-Options":        "DENY",
+				"Referrer-Policy":        "no-referrer",
+			},
+		},
+		"relaxed": {
+			headers:  RelaxedSecurityHeaders(),
+			existing: http.Header{},
+			expected: map[string]string{
+				"Content-Security-Policy": "",
+				"X-Frame-Options":         "SAMEORIGIN",
+				"Permissions-Policy":      "",
+			},
+		},
+		"existing header field": {
+			headers:  StrictSecurityHeaders(),
+			existing: http.Header{"X-Frame-Options": {"SAMEORIGIN"}},
+			expected: map[string]string{
+				"X-Frame-Options": "SAMEORIGIN",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc.headers.Apply(tc.existing)
+
+		for field, expected := range tc.expected {
+			if actual := tc.existing.Get(field); actual != expected {
+				t.Errorf("'%s': expected %s %q, got %q", name, field, expected, actual)
+			}
+		}
+	}
+}
+
+func TestSecurityHeaders_Middleware(t *testing.T) {
+	handler := StrictSecurityHeaders().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Referrer-Policy", "origin")
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+
+	if recorder.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("expected X-Content-Type-Options to be set")
+	}
+
+	if recorder.Header().Get("Referrer-Policy") != "origin" {
+		t.Errorf("expected the handler to override Referrer-Policy, got %s", recorder.Header().Get("Referrer-Policy"))
+	}
+}
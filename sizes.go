@@ -0,0 +1,100 @@
+package gohttp
+
+import (
+	"bytes"
+)
+
+// Sizes summarizes the sizes of a message in bytes as transmitted, e.g. for
+// access logs, metrics, or billing.
+type Sizes struct {
+	// StartLine is the size of the request or status line, including its
+	// line ending.
+	StartLine int64
+	// Header is the size of the header section, including the empty line
+	// terminating it.
+	Header int64
+	// Body is the size of the body as transmitted, i.e. including the
+	// chunked framing and trailer fields of a chunked body.
+	Body int64
+}
+
+// Total returns the total size of the message.
+func (s Sizes) Total() int64 {
+	return s.StartLine + s.Header + s.Body
+}
+
+// WithSizes measures a parsed message and stores the result in sizes. The
+// start line and the header section are measured during parsing, while the
+// body size grows as the body is read.
+func WithSizes(sizes *Sizes) Option {
+	return func(c *config) {
+		c.sizes = sizes
+	}
+}
+
+// MeasureMessage measures a serialized message, e.g. the output of
+// SerializeRequest or SerializeResponse.
+func MeasureMessage(message []byte) Sizes {
+	var sizes Sizes
+
+	i := bytes.IndexByte(message, '\n')
+	if i < 0 {
+		sizes.StartLine = int64(len(message))
+		return sizes
+	}
+
+	sizes.StartLine = int64(i + 1)
+	rest := message[i+1:]
+
+	end := len(rest)
+	if bytes.HasPrefix(rest, []byte("\r\n")) {
+		end = 2
+	} else if j := bytes.Index(rest, []byte("\r\n\r\n")); j >= 0 {
+		end = j + 4
+	}
+
+	sizes.Header = int64(end)
+	sizes.Body = int64(len(rest) - end)
+
+	return sizes
+}
+
+// countingSource counts the bytes consumed from a source.
+type countingSource struct {
+	Source
+	count *int64
+}
+
+func (c *countingSource) Read(p []byte) (int, error) {
+	n, err := c.Source.Read(p)
+	*c.count += int64(n)
+	return n, err
+}
+
+// ReadSlice allows readLine to read lines efficiently if the underlying
+// source supports it, and reads byte by byte otherwise.
+func (c *countingSource) ReadSlice(delim byte) ([]byte, error) {
+	if reader, ok := c.Source.(interface {
+		ReadSlice(delim byte) ([]byte, error)
+	}); ok {
+		line, err := reader.ReadSlice(delim)
+		*c.count += int64(len(line))
+		return line, err
+	}
+
+	var line []byte
+	var b [1]byte
+
+	for {
+		n, err := c.Read(b[:])
+		if n > 0 {
+			line = append(line, b[0])
+			if b[0] == delim {
+				return line, nil
+			}
+		}
+		if err != nil {
+			return line, err
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package gohttp
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWithSizes(t *testing.T) {
+	testCases := map[string]struct {
+		source   string
+		expected Sizes
+	}{
+		"without body": {
+			source:   "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: Sizes{StartLine: 16, Header: 21},
+		},
+		"content length": {
+			source:   "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello",
+			expected: Sizes{StartLine: 17, Header: 21, Body: 5},
+		},
+		"chunked": {
+			source:   "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\nX-Sum: 1\r\n\r\n",
+			expected: Sizes{StartLine: 17, Header: 30, Body: 25},
+		},
+	}
+
+	for name, tc := range testCases {
+		var sizes Sizes
+
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(tc.source)), WithSizes(&sizes))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		_, _ = ioutil.ReadAll(request.Body)
+
+		if sizes != tc.expected {
+			t.Errorf("'%s': expected sizes %+v, got %+v", name, tc.expected, sizes)
+		}
+
+		if sizes.Total() != int64(len(tc.source)) {
+			t.Errorf("'%s': expected total %d, got %d", name, len(tc.source), sizes.Total())
+		}
+
+		if measured := MeasureMessage([]byte(tc.source)); measured != tc.expected {
+			t.Errorf("'%s': expected measured sizes %+v, got %+v", name, tc.expected, measured)
+		}
+	}
+}
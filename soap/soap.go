@@ -0,0 +1,193 @@
+// Package soap provides helpers for inspecting SOAP and other XML bodies of
+// parsed messages, e.g. when putting a proxy in front of legacy services.
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// Namespace11 is the envelope namespace of SOAP 1.1.
+	Namespace11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	// Namespace12 is the envelope namespace of SOAP 1.2.
+	Namespace12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// ErrNoEnvelope is returned if a body isn't a SOAP envelope.
+var ErrNoEnvelope = errors.New("body is not a SOAP envelope")
+
+// Version returns the SOAP version of an envelope, i.e. "1.1" or "1.2", or
+// an empty string if the body isn't a SOAP envelope.
+func Version(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+
+		if element, ok := token.(xml.StartElement); ok {
+			if element.Name.Local != "Envelope" {
+				return ""
+			}
+
+			switch element.Name.Space {
+			case Namespace11:
+				return "1.1"
+			case Namespace12:
+				return "1.2"
+			}
+
+			return ""
+		}
+	}
+}
+
+// IsEnvelope reports whether the body is a SOAP 1.1 or 1.2 envelope.
+func IsEnvelope(body []byte) bool {
+	return Version(body) != ""
+}
+
+// Action returns the SOAP action of a request. SOAP 1.1 uses the SOAPAction
+// header field, while SOAP 1.2 uses the action parameter of the Content-Type.
+func Action(header http.Header) string {
+	if action := header.Get("SOAPAction"); action != "" {
+		return strings.Trim(action, `"`)
+	}
+
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Type")); err == nil {
+		return params["action"]
+	}
+
+	return ""
+}
+
+// Operation returns the local name of the first element within the Body
+// element of a SOAP envelope, which usually names the invoked operation.
+func Operation(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	var inBody bool
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", ErrNoEnvelope
+		}
+		if err != nil {
+			return "", err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if inBody {
+			return element.Name.Local, nil
+		}
+
+		isEnvelopeNamespace := element.Name.Space == Namespace11 || element.Name.Space == Namespace12
+		if isEnvelopeNamespace && element.Name.Local == "Body" {
+			inBody = true
+		}
+	}
+}
+
+// Validate checks whether the body is well-formed XML.
+func Validate(body []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Indent pretty-prints an XML body, using indent for each nesting level.
+// Whitespace between elements is replaced, while namespace prefixes are
+// preserved as they are.
+func Indent(body []byte, indent string) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	var buf bytes.Buffer
+	var depth int
+	// inline indicates that the current element has no child elements so
+	// far, so that its end tag is written on the same line.
+	var inline bool
+
+	newline := func() {
+		if buf.Len() > 0 {
+			buf.WriteString("\n" + strings.Repeat(indent, depth))
+		}
+	}
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			newline()
+			buf.WriteString("<" + qualifiedName(t.Name))
+			for _, attr := range t.Attr {
+				buf.WriteString(" " + qualifiedName(attr.Name) + `="` + attributeEscaper.Replace(attr.Value) + `"`)
+			}
+			buf.WriteString(">")
+			depth++
+			inline = true
+		case xml.EndElement:
+			depth--
+			if !inline {
+				newline()
+			}
+			buf.WriteString("</" + qualifiedName(t.Name) + ">")
+			inline = false
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				buf.WriteString(textEscaper.Replace(string(t)))
+			}
+		case xml.Comment:
+			newline()
+			buf.WriteString("<!--" + string(t) + "-->")
+			inline = false
+		case xml.ProcInst:
+			newline()
+			buf.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+		case xml.Directive:
+			newline()
+			buf.WriteString("<!" + string(t) + ">")
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+var (
+	textEscaper      = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attributeEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+// qualifiedName returns the name of a raw token including its prefix.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
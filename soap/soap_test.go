@@ -0,0 +1,99 @@
+package soap
+
+import (
+	"net/http"
+	"testing"
+)
+
+const envelope11 = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Header/>
+  <soap:Body>
+    <m:GetPrice xmlns:m="https://example.com/prices"><m:Item>Apples</m:Item></m:GetPrice>
+  </soap:Body>
+</soap:Envelope>`
+
+func TestVersion(t *testing.T) {
+	testCases := map[string]struct {
+		body     string
+		expected string
+	}{
+		"SOAP 1.1": {
+			body:     envelope11,
+			expected: "1.1",
+		},
+		"SOAP 1.2": {
+			body:     `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body/></env:Envelope>`,
+			expected: "1.2",
+		},
+		"plain XML": {
+			body: `<Envelope><Body/></Envelope>`,
+		},
+		"no XML": {
+			body: `{"json": true}`,
+		},
+	}
+
+	for name, tc := range testCases {
+		if actual := Version([]byte(tc.body)); actual != tc.expected {
+			t.Errorf("'%s': expected version %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestAction(t *testing.T) {
+	testCases := map[string]struct {
+		header   http.Header
+		expected string
+	}{
+		"SOAPAction": {
+			header:   http.Header{"Soapaction": {`"https://example.com/GetPrice"`}},
+			expected: "https://example.com/GetPrice",
+		},
+		"Content-Type action": {
+			header:   http.Header{"Content-Type": {`application/soap+xml; charset=utf-8; action="urn:GetPrice"`}},
+			expected: "urn:GetPrice",
+		},
+		"none": {
+			header: http.Header{"Content-Type": {"text/xml"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		if actual := Action(tc.header); actual != tc.expected {
+			t.Errorf("'%s': expected action %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestOperation(t *testing.T) {
+	operation, err := Operation([]byte(envelope11))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if operation != "GetPrice" {
+		t.Errorf("expected operation %s, got %s", "GetPrice", operation)
+	}
+
+	if _, err := Operation([]byte(`<a><b/></a>`)); err != ErrNoEnvelope {
+		t.Errorf("expected error %v, got %v", ErrNoEnvelope, err)
+	}
+}
+
+func TestValidateAndIndent(t *testing.T) {
+	if err := Validate([]byte(`<a><b></a>`)); err == nil {
+		t.Errorf("expected an error for malformed XML")
+	}
+
+	actual, err := Indent([]byte(`<s:a xmlns:s="urn:s">  <s:b k="v">x &amp; y</s:b><c/></s:a>`), "  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := "<s:a xmlns:s=\"urn:s\">\n  <s:b k=\"v\">x &amp; y</s:b>\n  <c></c>\n</s:a>"
+
+	if string(actual) != expected {
+		t.Errorf("expected indented XML %q, got %q", expected, string(actual))
+	}
+}
@@ -0,0 +1,147 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+var (
+	// ErrDeadlineUnsupported is returned by Source.SetReadDeadline if the
+	// underlying reader doesn't support deadlines.
+	ErrDeadlineUnsupported = errors.New("source does not support read deadlines")
+	// ErrLineTooLong is returned by the parser for a line exceeding the
+	// length set with WithMaxLineLength.
+	ErrLineTooLong = errors.New("line too long")
+)
+
+// WithMaxLineLength limits the length of the lines of a message head, e.g. a
+// request line with a long URL or a header field with large cookies. The
+// length includes the line ending. Longer lines are rejected with
+// ErrLineTooLong. By default, lines have no length limit.
+func WithMaxLineLength(length int) Option {
+	return func(c *config) {
+		c.maxLineLength = length
+	}
+}
+
+// WithReadBufferSize sets the initial size of the read buffer of a Parser.
+// Lines longer than the buffer are still read by growing them as needed. It
+// has no effect on the other parse functions, which read from a Source
+// created by the caller.
+func WithReadBufferSize(size int) Option {
+	return func(c *config) {
+		c.readBufferSize = size
+	}
+}
+
+// Source is a buffered source for parsing HTTP messages. In contrast to a
+// bare *bufio.Reader, it allows the parser to apply read deadlines.
+type Source interface {
+	io.Reader
+	// Peek returns the next n bytes without advancing the reader.
+	Peek(n int) ([]byte, error)
+	// SetReadDeadline sets the deadline for future Read and Peek calls. A
+	// zero value for t means that reads won't time out.
+	SetReadDeadline(t time.Time) error
+}
+
+// bufferedSource is a Source backed by a *bufio.Reader. The deadline
+// function is nil if the underlying reader doesn't support deadlines.
+type bufferedSource struct {
+	*bufio.Reader
+	setReadDeadline func(t time.Time) error
+}
+
+func (b *bufferedSource) SetReadDeadline(t time.Time) error {
+	if b.setReadDeadline == nil {
+		return ErrDeadlineUnsupported
+	}
+	return b.setReadDeadline(t)
+}
+
+// NewConnSource creates a Source reading from the given connection. This
+// includes *tls.Conn, whose deadlines apply to the underlying connection.
+func NewConnSource(conn net.Conn) Source {
+	return &bufferedSource{
+		Reader:          bufio.NewReader(conn),
+		setReadDeadline: conn.SetReadDeadline,
+	}
+}
+
+// NewBytesSource creates a Source reading from an in-memory byte slice. As
+// reading from memory can't block, setting a deadline has no effect.
+func NewBytesSource(b []byte) Source {
+	return &bufferedSource{
+		Reader: bufio.NewReader(bytes.NewReader(b)),
+		setReadDeadline: func(time.Time) error {
+			return nil
+		},
+	}
+}
+
+// NewBufioSource creates a Source reading from the given *bufio.Reader. The
+// reader may be used by the caller afterwards, e.g. for the next message.
+// Since the underlying reader is unknown, SetReadDeadline always returns
+// ErrDeadlineUnsupported.
+func NewBufioSource(reader *bufio.Reader) Source {
+	return &bufferedSource{
+		Reader: reader,
+	}
+}
+
+// readLine reads from the source until and including the next LF. Lines
+// longer than the configured maximum length are rejected with
+// ErrLineTooLong.
+func readLine(source Source, config config) (string, error) {
+	if reader, ok := source.(interface {
+		ReadSlice(delim byte) ([]byte, error)
+	}); ok {
+		return readBufferedLine(reader.ReadSlice, config.maxLineLength)
+	}
+
+	var line []byte
+	var b [1]byte
+
+	for {
+		n, err := source.Read(b[:])
+		if n > 0 {
+			if config.maxLineLength > 0 && len(line) >= config.maxLineLength {
+				return "", ErrLineTooLong
+			}
+			line = append(line, b[0])
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// readBufferedLine reads a line using ReadSlice, growing the line beyond the
+// size of the read buffer as needed.
+func readBufferedLine(readSlice func(delim byte) ([]byte, error), maxLength int) (string, error) {
+	var line []byte
+
+	for {
+		slice, err := readSlice('\n')
+
+		if maxLength > 0 && len(line)+len(slice) > maxLength {
+			return "", ErrLineTooLong
+		}
+
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			if line == nil {
+				return string(slice), err
+			}
+			return string(append(line, slice...)), err
+		}
+
+		line = append(line, slice...)
+	}
+}
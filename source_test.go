@@ -0,0 +1,96 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRequestSource(t *testing.T) {
+	message := "GET /index.html HTTP/1.1\r\nHost: example.com\r\nContent-Length: 5\r\n\r\nhello"
+
+	testCases := map[string]Source{
+		"bytes": NewBytesSource([]byte(message)),
+		"bufio": NewBufioSource(bufio.NewReader(strings.NewReader(message))),
+	}
+
+	for name, source := range testCases {
+		request, err := ParseRequestSource(source)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if request.URL.Path != "/index.html" {
+			t.Errorf("'%s': expected path %s, got %s", name, "/index.html", request.URL.Path)
+		}
+
+		if host := request.Header.Get("Host"); host != "example.com" {
+			t.Errorf("'%s': expected host %s, got %s", name, "example.com", host)
+		}
+	}
+}
+
+func TestSource_SetReadDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	source := NewConnSource(server)
+
+	if err := source.SetReadDeadline(time.Now().Add(10 * time.Millisecond)); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	_, err := ParseRequestSource(source)
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("expected a timeout error, got %v", err)
+	}
+
+	if err := NewBytesSource(nil).SetReadDeadline(time.Now()); err != nil {
+		t.Errorf("expected no error for a bytes source, got %v", err)
+	}
+
+	bufioSource := NewBufioSource(bufio.NewReader(strings.NewReader("")))
+	if err := bufioSource.SetReadDeadline(time.Now()); !errors.Is(err, ErrDeadlineUnsupported) {
+		t.Errorf("expected error %v, got %v", ErrDeadlineUnsupported, err)
+	}
+}
+
+func TestWithMaxLineLength(t *testing.T) {
+	cookie := "Cookie: " + strings.Repeat("a", 8000) + "\r\n"
+
+	testCases := map[string]struct {
+		maxLength     int
+		expectedError error
+	}{
+		"unlimited": {
+			maxLength: 0,
+		},
+		"within limit": {
+			maxLength: len(cookie),
+		},
+		"exceeding limit": {
+			maxLength:     len(cookie) - 1,
+			expectedError: ErrLineTooLong,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "GET / HTTP/1.1\r\n" + cookie + "\r\n"
+
+		// The read buffer is smaller than the line, so it has to be grown.
+		reader := bufio.NewReaderSize(strings.NewReader(source), 16)
+
+		request, err := ParseRequest(reader, WithMaxLineLength(tc.maxLength))
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if err == nil && len(request.Header.Get("Cookie")) != 8000 {
+			t.Errorf("'%s': expected a cookie of length %d, got %d", name, 8000, len(request.Header.Get("Cookie")))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gohttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// TeeResponse streams a serialized response to the first writer, typically
+// the client connection, and writes byte-identical copies to all other
+// writers, e.g. capture files or ring buffers. The body is not buffered.
+//
+// A failing sink doesn't affect the first writer: the sink is skipped for
+// the rest of the response and its error is returned once the response has
+// been written completely.
+func TeeResponse(r *http.Response, writers ...io.Writer) error {
+	if len(writers) == 0 {
+		return errors.New("no writers given")
+	}
+
+	tee := &teeWriter{
+		primary: writers[0],
+		sinks:   writers[1:],
+		errs:    make([]error, len(writers)-1),
+	}
+
+	if err := WriteResponse(tee, r); err != nil {
+		return err
+	}
+
+	for _, err := range tee.errs {
+		if err != nil {
+			return fmt.Errorf("sink: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type teeWriter struct {
+	primary io.Writer
+	sinks   []io.Writer
+	errs    []error
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	n, err := t.primary.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	for i, sink := range t.sinks {
+		if t.errs[i] != nil {
+			continue
+		}
+		if _, err := sink.Write(p[:n]); err != nil {
+			t.errs[i] = err
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,54 @@
+package gohttp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("sink failed")
+}
+
+func TestTeeResponse(t *testing.T) {
+	expected := "HTTP/1.1 200 OK\r\n" +
+		"Content-Length: 5\r\n" +
+		"\r\n" +
+		"hello"
+
+	newResponse := func() *http.Response {
+		return &http.Response{
+			Proto:  "HTTP/1.1",
+			Status: "200 OK",
+			Header: http.Header{"Content-Length": {"5"}},
+			Body:   ioutil.NopCloser(strings.NewReader("hello")),
+		}
+	}
+
+	var client, first, second bytes.Buffer
+
+	if err := TeeResponse(newResponse(), &client, &first, &second); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	for name, buf := range map[string]*bytes.Buffer{"client": &client, "first sink": &first, "second sink": &second} {
+		if buf.String() != expected {
+			t.Errorf("'%s': expected response %q, got %q", name, expected, buf.String())
+		}
+	}
+
+	client.Reset()
+
+	if err := TeeResponse(newResponse(), &client, failingWriter{}); err == nil {
+		t.Errorf("expected the sink error to be returned")
+	}
+
+	if client.String() != expected {
+		t.Errorf("expected the client to get the full response despite the failing sink, got %q", client.String())
+	}
+}
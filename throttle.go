@@ -0,0 +1,193 @@
+package gohttp
+
+import (
+	"io"
+	"time"
+)
+
+type throttleConfig struct {
+	bytesPerSecond int
+	latency        time.Duration
+	stallAfter     int64
+	stallDuration  time.Duration
+}
+
+// ThrottleOption represents a function that modifies the passed throttle
+// configuration.
+type ThrottleOption func(*throttleConfig)
+
+// WithBytesPerSecond limits the throughput to the given number of bytes per
+// second. A value of 0 disables the limit.
+func WithBytesPerSecond(n int) ThrottleOption {
+	return func(c *throttleConfig) {
+		c.bytesPerSecond = n
+	}
+}
+
+// WithLatency delays the first byte by the given duration.
+func WithLatency(d time.Duration) ThrottleOption {
+	return func(c *throttleConfig) {
+		c.latency = d
+	}
+}
+
+// WithStall pauses the transfer for the given duration once n bytes have
+// been transferred. This can be used to simulate a peer that hangs in the
+// middle of a message body.
+func WithStall(n int64, d time.Duration) ThrottleOption {
+	return func(c *throttleConfig) {
+		c.stallAfter = n
+		c.stallDuration = d
+	}
+}
+
+// throttle implements the shaping logic shared by ThrottleReader and
+// ThrottleWriter.
+type throttle struct {
+	config  throttleConfig
+	start   time.Time
+	total   int64
+	started bool
+	stalled bool
+	sleep   func(time.Duration)
+	now     func() time.Time
+}
+
+func newThrottle(options ...ThrottleOption) throttle {
+	var config throttleConfig
+
+	for _, option := range options {
+		option(&config)
+	}
+
+	return throttle{
+		config: config,
+		sleep:  time.Sleep,
+		now:    time.Now,
+	}
+}
+
+// begin injects the initial latency before the first byte is transferred.
+func (t *throttle) begin() {
+	if t.started {
+		return
+	}
+	t.started = true
+
+	if t.config.latency > 0 {
+		t.sleep(t.config.latency)
+	}
+	t.start = t.now()
+}
+
+// chunkSize returns the number of bytes that may be transferred at once.
+func (t *throttle) chunkSize(n int) int {
+	if t.config.stallAfter > 0 && !t.stalled {
+		if remaining := t.config.stallAfter - t.total; remaining > 0 && int64(n) > remaining {
+			n = int(remaining)
+		}
+	}
+
+	if t.config.bytesPerSecond > 0 && n > t.config.bytesPerSecond {
+		n = t.config.bytesPerSecond
+	}
+
+	return n
+}
+
+// wait accounts for n transferred bytes and sleeps until the configured
+// throughput is met again.
+func (t *throttle) wait(n int) {
+	t.total += int64(n)
+
+	if t.config.stallAfter > 0 && !t.stalled && t.total >= t.config.stallAfter {
+		t.stalled = true
+		t.sleep(t.config.stallDuration)
+		// The stall must not be compensated by a subsequent burst.
+		t.start = t.start.Add(t.config.stallDuration)
+	}
+
+	if t.config.bytesPerSecond <= 0 {
+		return
+	}
+
+	expected := time.Duration(t.total) * time.Second / time.Duration(t.config.bytesPerSecond)
+
+	if elapsed := t.now().Sub(t.start); elapsed < expected {
+		t.sleep(expected - elapsed)
+	}
+}
+
+// ThrottleReader wraps an io.Reader and shapes its throughput. It is meant
+// for testing how a system handles slow or flaky HTTP peers.
+type ThrottleReader struct {
+	reader   io.Reader
+	throttle throttle
+}
+
+// NewThrottleReader creates a new ThrottleReader reading from the given
+// io.Reader.
+func NewThrottleReader(reader io.Reader, options ...ThrottleOption) *ThrottleReader {
+	return &ThrottleReader{
+		reader:   reader,
+		throttle: newThrottle(options...),
+	}
+}
+
+// Read reads up to len(p) bytes into p, respecting the configured limits.
+func (t *ThrottleReader) Read(p []byte) (int, error) {
+	t.throttle.begin()
+
+	if len(p) == 0 {
+		return t.reader.Read(p)
+	}
+
+	n, err := t.reader.Read(p[:t.throttle.chunkSize(len(p))])
+	t.throttle.wait(n)
+
+	return n, err
+}
+
+// ThrottleWriter wraps an io.Writer and shapes its throughput. It is meant
+// for testing how a system handles slow or flaky HTTP peers.
+type ThrottleWriter struct {
+	writer   io.Writer
+	throttle throttle
+}
+
+// NewThrottleWriter creates a new ThrottleWriter writing to the given
+// io.Writer.
+func NewThrottleWriter(writer io.Writer, options ...ThrottleOption) *ThrottleWriter {
+	return &ThrottleWriter{
+		writer:   writer,
+		throttle: newThrottle(options...),
+	}
+}
+
+// Write writes p to the underlying io.Writer, respecting the configured
+// limits. Write blocks until all bytes have been written or an error occurs.
+// If the underlying io.Writer makes no progress, Write fails with
+// io.ErrShortWrite.
+func (t *ThrottleWriter) Write(p []byte) (int, error) {
+	t.throttle.begin()
+
+	var written int
+
+	for written < len(p) {
+		size := t.throttle.chunkSize(len(p) - written)
+
+		n, err := t.writer.Write(p[written : written+size])
+		written += n
+		t.throttle.wait(n)
+
+		if err != nil {
+			return written, err
+		}
+
+		if n == 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+
+	return written, nil
+}
@@ -0,0 +1,146 @@
+package gohttp
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces the sleep and now functions of a throttle so that tests
+// don't have to actually wait.
+type fakeClock struct {
+	current time.Time
+	slept   time.Duration
+}
+
+func (f *fakeClock) install(t *throttle) {
+	t.sleep = func(d time.Duration) {
+		f.slept += d
+		f.current = f.current.Add(d)
+	}
+	t.now = func() time.Time {
+		return f.current
+	}
+}
+
+func TestThrottleReader(t *testing.T) {
+	testCases := map[string]struct {
+		source   string
+		options  []ThrottleOption
+		expected time.Duration
+	}{
+		"bytes per second": {
+			source:   strings.Repeat("a", 25),
+			options:  []ThrottleOption{WithBytesPerSecond(10)},
+			expected: 2500 * time.Millisecond,
+		},
+		"latency": {
+			source:   "hello",
+			options:  []ThrottleOption{WithLatency(time.Second)},
+			expected: time.Second,
+		},
+		"stall": {
+			source:   strings.Repeat("a", 20),
+			options:  []ThrottleOption{WithStall(10, 3*time.Second)},
+			expected: 3 * time.Second,
+		},
+		"no limits": {
+			source:   "hello",
+			expected: 0,
+		},
+	}
+
+	for name, tc := range testCases {
+		var clock fakeClock
+
+		reader := NewThrottleReader(strings.NewReader(tc.source), tc.options...)
+		clock.install(&reader.throttle)
+
+		actual, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(actual) != tc.source {
+			t.Errorf("'%s': expected data %s, got %s", name, tc.source, string(actual))
+		}
+
+		if clock.slept != tc.expected {
+			t.Errorf("'%s': expected delay %v, got %v", name, tc.expected, clock.slept)
+		}
+	}
+}
+
+func TestThrottleWriter(t *testing.T) {
+	testCases := map[string]struct {
+		data     string
+		options  []ThrottleOption
+		expected time.Duration
+	}{
+		"bytes per second": {
+			data:     strings.Repeat("a", 30),
+			options:  []ThrottleOption{WithBytesPerSecond(20)},
+			expected: 1500 * time.Millisecond,
+		},
+		"latency and stall": {
+			data:     strings.Repeat("a", 30),
+			options:  []ThrottleOption{WithLatency(time.Second), WithStall(15, 2*time.Second)},
+			expected: 3 * time.Second,
+		},
+	}
+
+	for name, tc := range testCases {
+		var clock fakeClock
+		var buf bytes.Buffer
+
+		writer := NewThrottleWriter(&buf, tc.options...)
+		clock.install(&writer.throttle)
+
+		n, err := writer.Write([]byte(tc.data))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if n != len(tc.data) || buf.String() != tc.data {
+			t.Errorf("'%s': expected data %s, got %s", name, tc.data, buf.String())
+		}
+
+		if clock.slept != tc.expected {
+			t.Errorf("'%s': expected delay %v, got %v", name, tc.expected, clock.slept)
+		}
+	}
+}
+
+// stuckWriter accepts at most limit bytes and then makes no progress without
+// returning an error.
+type stuckWriter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+func (s *stuckWriter) Write(p []byte) (int, error) {
+	if len(p) > s.limit-s.buf.Len() {
+		p = p[:s.limit-s.buf.Len()]
+	}
+	return s.buf.Write(p)
+}
+
+func TestThrottleWriter_NoProgress(t *testing.T) {
+	var clock fakeClock
+	sink := &stuckWriter{limit: 5}
+
+	writer := NewThrottleWriter(sink, WithBytesPerSecond(20))
+	clock.install(&writer.throttle)
+
+	n, err := writer.Write([]byte("hello world"))
+	if err != io.ErrShortWrite {
+		t.Fatalf("expected error %v, got %v", io.ErrShortWrite, err)
+	}
+
+	if n != 5 || sink.buf.String() != "hello" {
+		t.Errorf("expected 5 bytes written, got %d", n)
+	}
+}
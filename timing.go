@@ -0,0 +1,73 @@
+package gohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MessageTiming holds the points in time at which the parts of a message have
+// been received, enabling latency breakdowns in logs and exports.
+type MessageTiming struct {
+	// FirstByte is the time the first byte of the message was available.
+	FirstByte time.Time
+	// HeadersComplete is the time the header section has been parsed.
+	HeadersComplete time.Time
+	// BodyComplete is the time the body has been read completely. It equals
+	// HeadersComplete for messages without a body, and is zero as long as
+	// the body hasn't been read to the end.
+	BodyComplete time.Time
+}
+
+// WithTiming records the timing of a parsed message in timing.
+func WithTiming(timing *MessageTiming) Option {
+	return func(c *config) {
+		c.timing = timing
+	}
+}
+
+// startTiming waits for the first byte of a message and records its time.
+func startTiming(source Source, config config) {
+	if config.timing == nil {
+		return
+	}
+
+	*config.timing = MessageTiming{}
+
+	// Peek blocks until the first byte is available. A failure will be
+	// reported by reading the start line.
+	_, _ = source.Peek(1)
+	config.timing.FirstByte = time.Now()
+}
+
+// completeHeaders records the time the header section has been parsed and
+// returns a body recording the time it has been read completely.
+func completeHeaders(body io.ReadCloser, config config) io.ReadCloser {
+	if config.timing == nil {
+		return body
+	}
+
+	config.timing.HeadersComplete = time.Now()
+
+	if body == http.NoBody {
+		config.timing.BodyComplete = config.timing.HeadersComplete
+		return body
+	}
+
+	return &timingBody{ReadCloser: body, timing: config.timing}
+}
+
+// timingBody records the time the end of the body has been reached.
+type timingBody struct {
+	io.ReadCloser
+	timing *MessageTiming
+}
+
+func (t *timingBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if errors.Is(err, io.EOF) && t.timing.BodyComplete.IsZero() {
+		t.timing.BodyComplete = time.Now()
+	}
+	return n, err
+}
@@ -0,0 +1,46 @@
+package gohttp
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWithTiming(t *testing.T) {
+	testCases := map[string]struct {
+		source  string
+		hasBody bool
+	}{
+		"without body": {
+			source: "GET / HTTP/1.1\r\n\r\n",
+		},
+		"with body": {
+			source:  "POST / HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello",
+			hasBody: true,
+		},
+	}
+
+	for name, tc := range testCases {
+		var timing MessageTiming
+
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(tc.source)), WithTiming(&timing))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if timing.FirstByte.IsZero() || timing.HeadersComplete.Before(timing.FirstByte) {
+			t.Errorf("'%s': unexpected head timing %+v", name, timing)
+		}
+
+		if timing.BodyComplete.IsZero() != tc.hasBody {
+			t.Errorf("'%s': expected body complete to be set only for messages without body, got %v", name, timing.BodyComplete)
+		}
+
+		_, _ = ioutil.ReadAll(request.Body)
+
+		if timing.BodyComplete.IsZero() || timing.BodyComplete.Before(timing.HeadersComplete) {
+			t.Errorf("'%s': unexpected body timing %+v", name, timing)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Transaction is a serialized request paired with its serialized response.
+// It is the unit that capture and export functionality works with.
+type Transaction struct {
+	// Time is the time the transaction has been recorded.
+	Time time.Time
+	// Request is the serialized request.
+	Request []byte
+	// Response is the serialized response.
+	Response []byte
+	// RequestSizes are the sizes of the serialized request.
+	RequestSizes Sizes
+	// ResponseSizes are the sizes of the serialized response.
+	ResponseSizes Sizes
+	// RequestTiming and ResponseTiming are the timings of receiving the
+	// messages, if recorded with WithTiming. NewTransaction doesn't set them.
+	RequestTiming  MessageTiming
+	ResponseTiming MessageTiming
+}
+
+// NewTransaction serializes a request and its response into a Transaction.
+// The bodies are read completely and replaced, so that both messages can
+// still be used afterwards.
+func NewTransaction(request *http.Request, response *http.Response) (Transaction, error) {
+	transaction := Transaction{
+		Time: time.Now(),
+	}
+
+	body, err := bufferBody(request.Body)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	request.Body = body()
+	if transaction.Request, err = SerializeRequest(request); err != nil {
+		return Transaction{}, err
+	}
+	request.Body = body()
+	transaction.RequestSizes = MeasureMessage(transaction.Request)
+
+	if response == nil {
+		return transaction, nil
+	}
+
+	if body, err = bufferBody(response.Body); err != nil {
+		return Transaction{}, err
+	}
+
+	response.Body = body()
+	if transaction.Response, err = SerializeResponse(response, WithRequestMethod(request.Method)); err != nil {
+		return Transaction{}, err
+	}
+	response.Body = body()
+	transaction.ResponseSizes = MeasureMessage(transaction.Response)
+
+	return transaction, nil
+}
+
+// Parse parses the serialized request and response of the transaction. The
+// response is nil if the transaction doesn't have one. It is parsed as a
+// response to the request method, so that a response to a HEAD request isn't
+// expected to have a body.
+func (t Transaction) Parse() (*http.Request, *http.Response, error) {
+	request, err := ParseRequest(bufio.NewReader(bytes.NewReader(t.Request)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if t.Response == nil {
+		return request, nil, nil
+	}
+
+	response, err := ParseResponse(bufio.NewReader(bytes.NewReader(t.Response)), WithRequestMethod(request.Method))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return request, response, nil
+}
+
+// bufferBody reads and closes a body and returns a function creating new
+// readers over the buffered content.
+func bufferBody(body io.ReadCloser) (func() io.ReadCloser, error) {
+	if body == nil || body == http.NoBody {
+		return func() io.ReadCloser { return http.NoBody }, nil
+	}
+
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	return func() io.ReadCloser {
+		return ioutil.NopCloser(bytes.NewReader(content))
+	}, nil
+}
@@ -0,0 +1,79 @@
+package gohttp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNewTransaction(t *testing.T) {
+	target, _ := url.Parse("/submit")
+
+	request := &http.Request{
+		Method: "POST",
+		URL:    target,
+		Proto:  "HTTP/1.1",
+		Header: http.Header{"Content-Length": {"5"}},
+		Body:   ioutil.NopCloser(strings.NewReader("hello")),
+	}
+
+	response := NewResponse(http.StatusOK, []byte("world"))
+
+	transaction, err := NewTransaction(request, response)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expectedRequest := "POST /submit HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello"
+	if string(transaction.Request) != expectedRequest {
+		t.Errorf("expected request %q, got %q", expectedRequest, string(transaction.Request))
+	}
+
+	expectedResponse := "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nworld"
+	if string(transaction.Response) != expectedResponse {
+		t.Errorf("expected response %q, got %q", expectedResponse, string(transaction.Response))
+	}
+
+	expectedSizes := Sizes{StartLine: 23, Header: 21, Body: 5}
+	if transaction.RequestSizes != expectedSizes {
+		t.Errorf("expected request sizes %+v, got %+v", expectedSizes, transaction.RequestSizes)
+	}
+
+	requestBody, _ := ioutil.ReadAll(request.Body)
+	if string(requestBody) != "hello" {
+		t.Errorf("expected the request body to be preserved, got %q", string(requestBody))
+	}
+
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	if string(responseBody) != "world" {
+		t.Errorf("expected the response body to be preserved, got %q", string(responseBody))
+	}
+}
+
+func TestTransaction_Parse(t *testing.T) {
+	transaction := Transaction{
+		Request:  []byte("HEAD / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		Response: []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n"),
+	}
+
+	request, response, err := transaction.Parse()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if request.Method != http.MethodHead {
+		t.Errorf("expected method %s, got %s", http.MethodHead, request.Method)
+	}
+
+	if body, err := ioutil.ReadAll(response.Body); err != nil || len(body) != 0 {
+		t.Errorf("expected no response body, got %q (%v)", string(body), err)
+	}
+
+	transaction.Response = nil
+
+	if _, response, err = transaction.Parse(); err != nil || response != nil {
+		t.Errorf("expected no response, got %v (%v)", response, err)
+	}
+}
@@ -0,0 +1,162 @@
+package gohttp
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrChunkedNotFinal indicates a Transfer-Encoding header field in which
+	// chunked isn't the final transfer coding. For requests, this makes the
+	// body length undeterminable (RFC 7230, section 3.3.3.).
+	ErrChunkedNotFinal = errors.New("chunked is not the final transfer coding")
+	// ErrUnsupportedTransferCoding is returned if transfer decoding has been
+	// enabled and a transfer coding can't be decoded.
+	ErrUnsupportedTransferCoding = errors.New("unsupported transfer coding")
+)
+
+// WithTransferDecoding defines whether the transfer codings applied on top of
+// the chunked transfer coding, e.g. gzip, are decoded. Decoded transfer
+// codings are removed from the Transfer-Encoding header field and from the
+// TransferEncoding field of the parsed message. Use WithDecompressionLimits
+// to protect against decompression bombs.
+func WithTransferDecoding(decode bool) Option {
+	return func(c *config) {
+		c.decodeTransferCodings = decode
+	}
+}
+
+// parseTransferEncoding returns the transfer codings of all Transfer-Encoding
+// header fields in the order they have been applied. The identity coding is
+// omitted, and chunked may only be the final transfer coding.
+func parseTransferEncoding(headers http.Header) ([]string, error) {
+	var codings []string
+
+	for _, value := range headers.Values("Transfer-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			coding = strings.ToLower(strings.TrimSpace(coding))
+			if coding == "" || coding == "identity" {
+				continue
+			}
+			codings = append(codings, coding)
+		}
+	}
+
+	for i, coding := range codings {
+		if coding == "chunked" && i != len(codings)-1 {
+			return nil, ErrChunkedNotFinal
+		}
+	}
+
+	return codings, nil
+}
+
+// hasTransferCodings reports whether the message body has any transfer
+// codings other than identity.
+func hasTransferCodings(headers http.Header) bool {
+	codings, err := parseTransferEncoding(headers)
+	return err != nil || len(codings) > 0
+}
+
+// newTransferReader returns the body reader for a message with the given
+// transfer codings. If the final coding isn't chunked, the body is delimited
+// by the end of the source, i.e. by closing the connection. It also returns
+// the transfer codings that are still applied to the body.
+func newTransferReader(source Source, codings []string, config config, trailer http.Header) (io.ReadCloser, []string, error) {
+	var body io.Reader = source
+
+	if codings[len(codings)-1] == "chunked" {
+		body = newChunkedReader(source, config, trailer)
+	}
+
+	if !config.decodeTransferCodings {
+		return ioutil.NopCloser(body), codings, nil
+	}
+
+	remaining := codings
+	if remaining[len(remaining)-1] == "chunked" {
+		remaining = remaining[:len(remaining)-1]
+	}
+
+	var compressed *compressedCounter
+	if config.decompressionLimits.enabled() && len(remaining) > 0 {
+		compressed = &compressedCounter{reader: body}
+		body = compressed
+	}
+
+	for i := len(remaining) - 1; i >= 0; i-- {
+		decoded, err := newTransferDecoder(remaining[i], body)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = decoded
+	}
+
+	if compressed != nil {
+		body = &limitedDecoder{
+			reader:     body,
+			compressed: compressed,
+			limits:     config.decompressionLimits,
+		}
+	}
+
+	return ioutil.NopCloser(body), codings[len(remaining):], nil
+}
+
+// newTransferDecoder returns a reader decoding the given transfer coding.
+// The decoder is created on the first read, so that parsing the message
+// doesn't block on reading the body.
+func newTransferDecoder(coding string, r io.Reader) (io.Reader, error) {
+	var newDecoder func(io.Reader) (io.Reader, error)
+
+	switch coding {
+	case "gzip", "x-gzip":
+		newDecoder = func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		}
+	case "deflate":
+		newDecoder = func(r io.Reader) (io.Reader, error) {
+			return zlib.NewReader(r)
+		}
+	default:
+		return nil, ErrUnsupportedTransferCoding
+	}
+
+	return &lazyReader{source: r, newReader: newDecoder}, nil
+}
+
+// lazyReader creates its underlying reader on the first read.
+type lazyReader struct {
+	source    io.Reader
+	newReader func(io.Reader) (io.Reader, error)
+	reader    io.Reader
+	err       error
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.reader == nil && l.err == nil {
+		l.reader, l.err = l.newReader(l.source)
+	}
+
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	return l.reader.Read(p)
+}
+
+// setTransferEncoding updates the Transfer-Encoding header field after
+// transfer codings have been decoded.
+func setTransferEncoding(headers http.Header, codings []string) {
+	if len(codings) == 0 {
+		headers.Del("Transfer-Encoding")
+		return
+	}
+
+	headers.Set("Transfer-Encoding", strings.Join(codings, ", "))
+}
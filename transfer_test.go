@@ -0,0 +1,145 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTransferEncoding(t *testing.T) {
+	testCases := map[string]struct {
+		values        []string
+		expected      []string
+		expectedError error
+	}{
+		"chunked": {
+			values:   []string{"chunked"},
+			expected: []string{"chunked"},
+		},
+		"multiple codings": {
+			values:   []string{"gzip, Chunked"},
+			expected: []string{"gzip", "chunked"},
+		},
+		"multiple fields": {
+			values:   []string{"gzip", "identity", "chunked"},
+			expected: []string{"gzip", "chunked"},
+		},
+		"chunked not final": {
+			values:        []string{"chunked, gzip"},
+			expectedError: ErrChunkedNotFinal,
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, err := parseTransferEncoding(map[string][]string{"Transfer-Encoding": tc.values})
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("'%s': expected codings %v, got %v", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseResponse_TransferCodings(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write([]byte("hello world"))
+	_ = writer.Close()
+
+	chunked := fmt.Sprintf("%x\r\n%s\r\n0\r\n\r\n", compressed.Len(), compressed.String())
+
+	testCases := map[string]struct {
+		transferEncoding string
+		body             string
+		options          []Option
+		expectedCodings  []string
+		expectedBody     string
+	}{
+		"gzip and chunked": {
+			transferEncoding: "gzip, chunked",
+			body:             chunked,
+			expectedCodings:  []string{"gzip", "chunked"},
+			expectedBody:     compressed.String(),
+		},
+		"gzip and chunked decoded": {
+			transferEncoding: "gzip, chunked",
+			body:             chunked,
+			options:          []Option{WithTransferDecoding(true)},
+			expectedCodings:  []string{"chunked"},
+			expectedBody:     "hello world",
+		},
+		"gzip until close decoded": {
+			transferEncoding: "gzip",
+			body:             compressed.String(),
+			options:          []Option{WithTransferDecoding(true)},
+			expectedBody:     "hello world",
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "HTTP/1.1 200 OK\r\nTransfer-Encoding: " + tc.transferEncoding + "\r\n\r\n" + tc.body
+
+		response, err := ParseResponse(bufio.NewReader(strings.NewReader(source)), tc.options...)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if len(response.TransferEncoding) != len(tc.expectedCodings) ||
+			(len(tc.expectedCodings) > 0 && !reflect.DeepEqual(response.TransferEncoding, tc.expectedCodings)) {
+			t.Errorf("'%s': expected transfer codings %v, got %v", name, tc.expectedCodings, response.TransferEncoding)
+		}
+
+		if header := response.Header.Get("Transfer-Encoding"); header != strings.Join(tc.expectedCodings, ", ") {
+			t.Errorf("'%s': expected Transfer-Encoding %s, got %s", name, strings.Join(tc.expectedCodings, ", "), header)
+		}
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(body) != tc.expectedBody {
+			t.Errorf("'%s': expected body %q, got %q", name, tc.expectedBody, string(body))
+		}
+	}
+}
+
+func TestParseRequest_TransferCodings(t *testing.T) {
+	testCases := map[string]struct {
+		transferEncoding string
+		options          []Option
+		expectedError    error
+	}{
+		"chunked not final": {
+			transferEncoding: "chunked, gzip",
+			expectedError:    ErrChunkedNotFinal,
+		},
+		"gzip only": {
+			transferEncoding: "gzip",
+			expectedError:    ErrChunkedNotFinal,
+		},
+		"unsupported coding": {
+			transferEncoding: "br, chunked",
+			options:          []Option{WithTransferDecoding(true)},
+			expectedError:    ErrUnsupportedTransferCoding,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := "POST / HTTP/1.1\r\nTransfer-Encoding: " + tc.transferEncoding + "\r\n\r\n0\r\n\r\n"
+
+		_, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), tc.options...)
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+	}
+}
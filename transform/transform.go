@@ -0,0 +1,225 @@
+// Package transform provides declarative header transformations for
+// requests and responses: setting, adding, removing, and renaming header
+// fields, rewriting their values using regular expressions, and copying
+// request header fields into the response.
+//
+// Transformations are declared using Spec and compiled into a Transform
+// once. A Transform is a pipeline.Processor, so that each route can be
+// given its own transformations by registering them under the route's
+// name. Header field names are canonicalized and patterns are compiled at
+// compile time. Each message gets its own copy of set values, so that
+// modifying the header of one message never affects another one.
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// Op is a header transformation.
+type Op int
+
+const (
+	// Set replaces all values of Header with Value.
+	Set Op = iota
+	// Add appends Value to the values of Header.
+	Add
+	// Remove removes Header.
+	Remove
+	// Rename moves the values of Header to To, appending them to the
+	// existing values of To.
+	Rename
+	// Replace replaces the matches of Pattern in each value of Header
+	// with Value, which may refer to capture groups using $1 or ${name}.
+	Replace
+	// Copy appends the values of the request header field Header to the
+	// response header field To. It is only valid for responses.
+	Copy
+)
+
+// Message is the message a transformation is applied to.
+type Message int
+
+const (
+	// Request applies the transformation to the request.
+	Request Message = iota
+	// Response applies the transformation to the response.
+	Response
+)
+
+// Spec declares a header transformation.
+type Spec struct {
+	// Op is the transformation.
+	Op Op
+	// Message is the message the transformation is applied to.
+	Message Message
+	// Header is the header field the transformation is applied to.
+	Header string
+	// To is the target field of Rename and Copy. Copy defaults to Header.
+	To string
+	// Value is the value used by Set and Add, and the replacement used by
+	// Replace.
+	Value string
+	// Pattern is the regular expression used by Replace.
+	Pattern string
+}
+
+type op struct {
+	op      Op
+	header  string
+	to      string
+	value   string
+	pattern *regexp.Regexp
+}
+
+// Transform applies compiled header transformations. It is safe for
+// concurrent use.
+type Transform struct {
+	name     string
+	request  []op
+	response []op
+}
+
+// Compile compiles the given transformations into a Transform with the
+// given processor name. Transformations are applied in the given order.
+func Compile(name string, specs ...Spec) (*Transform, error) {
+	transform := &Transform{name: name}
+
+	for i, spec := range specs {
+		o, err := compile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("transformation %d: %w", i, err)
+		}
+
+		if spec.Message == Response {
+			transform.response = append(transform.response, o)
+		} else {
+			transform.request = append(transform.request, o)
+		}
+	}
+
+	return transform, nil
+}
+
+func compile(spec Spec) (op, error) {
+	if spec.Header == "" {
+		return op{}, errors.New("missing header")
+	}
+
+	o := op{
+		op:     spec.Op,
+		header: http.CanonicalHeaderKey(spec.Header),
+		value:  spec.Value,
+	}
+
+	switch spec.Op {
+	case Set, Add, Remove:
+	case Rename:
+		if spec.To == "" {
+			return op{}, errors.New("missing target header")
+		}
+		o.to = http.CanonicalHeaderKey(spec.To)
+	case Replace:
+		pattern, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return op{}, err
+		}
+		o.pattern = pattern
+	case Copy:
+		if spec.Message != Response {
+			return op{}, errors.New("copy is only valid for responses")
+		}
+		o.to = o.header
+		if spec.To != "" {
+			o.to = http.CanonicalHeaderKey(spec.To)
+		}
+	default:
+		return op{}, fmt.Errorf("unknown operation %d", spec.Op)
+	}
+
+	return o, nil
+}
+
+// Name returns the name of the transformation, e.g. the name of its route.
+func (t *Transform) Name() string {
+	return t.name
+}
+
+// ProcessRequest applies the request transformations to the request. It
+// never returns a response.
+func (t *Transform) ProcessRequest(request *http.Request) (*http.Response, error) {
+	if request.Header == nil {
+		request.Header = make(http.Header)
+	}
+
+	apply(t.request, request.Header, nil)
+
+	return nil, nil
+}
+
+// ProcessResponse applies the response transformations to the response.
+func (t *Transform) ProcessResponse(request *http.Request, response *http.Response) error {
+	if response.Header == nil {
+		response.Header = make(http.Header)
+	}
+
+	var source http.Header
+	if request != nil {
+		source = request.Header
+	}
+
+	apply(t.response, response.Header, source)
+
+	return nil
+}
+
+func apply(ops []op, header, source http.Header) {
+	for i := range ops {
+		o := &ops[i]
+
+		switch o.op {
+		case Set:
+			header[o.header] = []string{o.value}
+		case Add:
+			header[o.header] = append(header[o.header], o.value)
+		case Remove:
+			delete(header, o.header)
+		case Rename:
+			values, ok := header[o.header]
+			if !ok {
+				continue
+			}
+			delete(header, o.header)
+			header[o.to] = append(header[o.to], values...)
+		case Replace:
+			if values, ok := header[o.header]; ok {
+				header[o.header] = replace(o, values)
+			}
+		case Copy:
+			if values, ok := source[o.header]; ok {
+				header[o.to] = append(header[o.to], values...)
+			}
+		}
+	}
+}
+
+// replace rewrites the values using the pattern of the operation. The values
+// are copied before the first rewrite, since they may be shared by a Set
+// operation.
+func replace(o *op, values []string) []string {
+	rewritten := values
+
+	for i, value := range values {
+		if !o.pattern.MatchString(value) {
+			continue
+		}
+		if &rewritten[0] == &values[0] {
+			rewritten = append([]string(nil), values...)
+		}
+		rewritten[i] = o.pattern.ReplaceAllString(value, o.value)
+	}
+
+	return rewritten
+}
@@ -0,0 +1,168 @@
+package transform
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dominikbraun/gohttp/pipeline"
+)
+
+func TestTransform(t *testing.T) {
+	testCases := map[string]struct {
+		specs            []Spec
+		requestHeader    http.Header
+		expectedRequest  http.Header
+		expectedResponse http.Header
+	}{
+		"set": {
+			specs:           []Spec{{Op: Set, Header: "x-forwarded-proto", Value: "https"}},
+			requestHeader:   http.Header{"X-Forwarded-Proto": {"http", "ws"}},
+			expectedRequest: http.Header{"X-Forwarded-Proto": {"https"}},
+		},
+		"set and add": {
+			specs: []Spec{
+				{Op: Set, Header: "Via", Value: "1.1 gateway"},
+				{Op: Add, Header: "Via", Value: "1.1 cache"},
+			},
+			requestHeader:   http.Header{},
+			expectedRequest: http.Header{"Via": {"1.1 gateway", "1.1 cache"}},
+		},
+		"remove": {
+			specs:           []Spec{{Op: Remove, Header: "Cookie"}},
+			requestHeader:   http.Header{"Cookie": {"session=1"}, "Accept": {"*/*"}},
+			expectedRequest: http.Header{"Accept": {"*/*"}},
+		},
+		"rename": {
+			specs:           []Spec{{Op: Rename, Header: "X-User", To: "X-Upstream-User"}},
+			requestHeader:   http.Header{"X-User": {"alice"}, "X-Upstream-User": {"gateway"}},
+			expectedRequest: http.Header{"X-Upstream-User": {"gateway", "alice"}},
+		},
+		"rename missing": {
+			specs:           []Spec{{Op: Rename, Header: "X-User", To: "X-Upstream-User"}},
+			requestHeader:   http.Header{},
+			expectedRequest: http.Header{},
+		},
+		"replace": {
+			specs:           []Spec{{Op: Replace, Header: "Host", Pattern: `^(\w+)\.internal$`, Value: "$1.example.com"}},
+			requestHeader:   http.Header{"Host": {"api.internal"}},
+			expectedRequest: http.Header{"Host": {"api.example.com"}},
+		},
+		"replace set value": {
+			specs: []Spec{
+				{Op: Set, Header: "X-Env", Value: "staging"},
+				{Op: Replace, Header: "X-Env", Pattern: `staging`, Value: "prod"},
+			},
+			requestHeader:   http.Header{},
+			expectedRequest: http.Header{"X-Env": {"prod"}},
+		},
+		"copy to response": {
+			specs: []Spec{
+				{Op: Copy, Message: Response, Header: "X-Request-Id"},
+				{Op: Copy, Message: Response, Header: "Origin", To: "Access-Control-Allow-Origin"},
+				{Op: Remove, Message: Response, Header: "Server"},
+			},
+			requestHeader:    http.Header{"X-Request-Id": {"42"}, "Origin": {"https://example.com"}},
+			expectedRequest:  http.Header{"X-Request-Id": {"42"}, "Origin": {"https://example.com"}},
+			expectedResponse: http.Header{"X-Request-Id": {"42"}, "Access-Control-Allow-Origin": {"https://example.com"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		transform, err := Compile(name, tc.specs...)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		// Each transformation is applied twice to make sure that shared
+		// values aren't modified by applying it.
+		for i := 0; i < 2; i++ {
+			request := httptest.NewRequest("GET", "/", nil)
+			request.Header = tc.requestHeader.Clone()
+			response := &http.Response{Header: http.Header{"Server": {"upstream"}}}
+
+			_, _ = transform.ProcessRequest(request)
+			_ = transform.ProcessResponse(request, response)
+
+			if !reflect.DeepEqual(request.Header, tc.expectedRequest) {
+				t.Errorf("'%s': expected request header %v, got %v", name, tc.expectedRequest, request.Header)
+			}
+
+			expectedResponse := tc.expectedResponse
+			if expectedResponse == nil {
+				expectedResponse = http.Header{"Server": {"upstream"}}
+			}
+
+			if !reflect.DeepEqual(response.Header, expectedResponse) {
+				t.Errorf("'%s': expected response header %v, got %v", name, expectedResponse, response.Header)
+			}
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	testCases := map[string]struct {
+		spec        Spec
+		expectedErr bool
+	}{
+		"valid":               {spec: Spec{Op: Set, Header: "X-A", Value: "1"}},
+		"missing header":      {spec: Spec{Op: Set, Value: "1"}, expectedErr: true},
+		"rename without to":   {spec: Spec{Op: Rename, Header: "X-A"}, expectedErr: true},
+		"invalid pattern":     {spec: Spec{Op: Replace, Header: "X-A", Pattern: "("}, expectedErr: true},
+		"copy to the request": {spec: Spec{Op: Copy, Header: "X-A"}, expectedErr: true},
+	}
+
+	for name, tc := range testCases {
+		_, err := Compile(name, tc.spec)
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedErr, err)
+		}
+	}
+}
+
+func TestTransform_Pipeline(t *testing.T) {
+	registry := pipeline.NewRegistry()
+
+	for route, value := range map[string]string{"api": "api", "static": "static"} {
+		transform, _ := Compile(route, Spec{Op: Set, Header: "X-Route", Value: value})
+		if err := registry.Register(transform); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+
+	p, err := registry.Pipeline("static")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	request := httptest.NewRequest("GET", "/", nil)
+	if _, err := p.ProcessRequest(request); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if route := request.Header.Get("X-Route"); route != "static" {
+		t.Errorf("expected route %s, got %s", "static", route)
+	}
+}
+
+func TestTransform_SetIsolation(t *testing.T) {
+	transform, err := Compile("upstream", Spec{Op: Set, Header: "X-Upstream", Value: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	first := httptest.NewRequest("GET", "/", nil)
+	_, _ = transform.ProcessRequest(first)
+
+	// Modifying the values of the first message in place, e.g. by an
+	// anonymizer, must not affect subsequent messages.
+	first.Header["X-Upstream"][0] = "ip-1"
+
+	second := httptest.NewRequest("GET", "/", nil)
+	_, _ = transform.ProcessRequest(second)
+
+	if upstream := second.Header.Get("X-Upstream"); upstream != "10.0.0.1" {
+		t.Errorf("expected upstream %s, got %s", "10.0.0.1", upstream)
+	}
+}
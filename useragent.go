@@ -0,0 +1,150 @@
+package gohttp
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Product is a product token of a User-Agent or Server header field, like
+// "curl/7.68.0", along with the comments following it (RFC 7231, section
+// 5.5.3.).
+type Product struct {
+	Name    string
+	Version string
+	// Comments are the comments following the product, without parentheses.
+	Comments []string
+}
+
+// String formats the product as it appears in a header field.
+func (p Product) String() string {
+	s := p.Name
+	if p.Version != "" {
+		s += "/" + p.Version
+	}
+
+	for _, comment := range p.Comments {
+		s += " (" + comment + ")"
+	}
+
+	return s
+}
+
+// ParseProducts parses the product tokens and comments of a User-Agent or
+// Server header field value. Comments that precede the first product are
+// assigned to a product with an empty name.
+func ParseProducts(value string) ([]Product, error) {
+	var products []Product
+
+	for i := 0; i < len(value); {
+		switch c := value[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			comment, n, err := parseComment(value[i:])
+			if err != nil {
+				return nil, err
+			}
+			if len(products) == 0 {
+				products = append(products, Product{})
+			}
+			products[len(products)-1].Comments = append(products[len(products)-1].Comments, comment)
+			i += n
+		default:
+			end := i
+			for end < len(value) && (isTokenChar(value[end]) || value[end] == '/') {
+				end++
+			}
+			if end == i {
+				return nil, errors.New("invalid product syntax")
+			}
+
+			tokens := strings.SplitN(value[i:end], "/", 2)
+			product := Product{Name: tokens[0]}
+			if len(tokens) == 2 {
+				product.Version = tokens[1]
+			}
+
+			products = append(products, product)
+			i = end
+		}
+	}
+
+	return products, nil
+}
+
+// parseComment parses a comment at the beginning of s, which may contain
+// nested comments and quoted-pairs. It returns the comment without the
+// outer parentheses and the number of bytes consumed.
+func parseComment(s string) (string, int, error) {
+	var builder strings.Builder
+	var depth int
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				builder.WriteByte(s[i])
+			}
+			continue
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return builder.String(), i + 1, nil
+			}
+		}
+		builder.WriteByte(s[i])
+	}
+
+	return "", 0, errors.New("unterminated comment")
+}
+
+// Classifier assigns a class like "browser" or "bot" to a client or server
+// described by its products. It returns an empty string if no class applies.
+type Classifier func(products []Product) string
+
+// ClassifyUserAgent parses the User-Agent header field and classifies the
+// client using the given classifier.
+func ClassifyUserAgent(header http.Header, classifier Classifier) string {
+	products, err := ParseProducts(header.Get("User-Agent"))
+	if err != nil || len(products) == 0 {
+		return ""
+	}
+	return classifier(products)
+}
+
+// BasicClassifier is a lightweight classifier distinguishing bots, command
+// line tools and libraries, and browsers by well-known product names. A
+// client without any products is classified as "unknown".
+func BasicClassifier(products []Product) string {
+	if len(products) == 0 {
+		return "unknown"
+	}
+
+	for _, product := range products {
+		name := strings.ToLower(product.Name)
+		if strings.Contains(name, "bot") || strings.Contains(name, "crawler") || strings.Contains(name, "spider") {
+			return "bot"
+		}
+		for _, comment := range product.Comments {
+			if strings.Contains(strings.ToLower(comment), "bot") {
+				return "bot"
+			}
+		}
+	}
+
+	switch strings.ToLower(products[0].Name) {
+	case "curl", "wget", "httpie", "go-http-client", "python-requests", "okhttp", "java":
+		return "tool"
+	case "mozilla", "opera":
+		return "browser"
+	}
+
+	return ""
+}
@@ -0,0 +1,88 @@
+package gohttp
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseProducts(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected []Product
+	}{
+		"single product": {
+			value:    "curl/7.68.0",
+			expected: []Product{{Name: "curl", Version: "7.68.0"}},
+		},
+		"browser": {
+			value: "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0 Safari/537.36",
+			expected: []Product{
+				{Name: "Mozilla", Version: "5.0", Comments: []string{"X11; Linux x86_64"}},
+				{Name: "AppleWebKit", Version: "537.36", Comments: []string{"KHTML, like Gecko"}},
+				{Name: "Chrome", Version: "83.0"},
+				{Name: "Safari", Version: "537.36"},
+			},
+		},
+		"nested comment": {
+			value:    `Server (a (nested) \) comment)`,
+			expected: []Product{{Name: "Server", Comments: []string{"a (nested) ) comment"}}},
+		},
+		"leading comment": {
+			value:    "(compatible) Bot/1.0",
+			expected: []Product{{Comments: []string{"compatible"}}, {Name: "Bot", Version: "1.0"}},
+		},
+	}
+
+	for name, tc := range testCases {
+		actual, err := ParseProducts(tc.value)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("'%s': expected products %v, got %v", name, tc.expected, actual)
+		}
+	}
+
+	if _, err := ParseProducts("Server (unterminated"); err == nil {
+		t.Errorf("expected an error for an unterminated comment")
+	}
+}
+
+func TestClassifyUserAgent(t *testing.T) {
+	testCases := map[string]struct {
+		userAgent string
+		expected  string
+	}{
+		"browser": {
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Gecko/20100101 Firefox/77.0",
+			expected:  "browser",
+		},
+		"bot": {
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			expected:  "bot",
+		},
+		"tool": {
+			userAgent: "curl/7.68.0",
+			expected:  "tool",
+		},
+		"unknown": {
+			userAgent: "MyApp/1.0",
+		},
+	}
+
+	for name, tc := range testCases {
+		header := http.Header{"User-Agent": {tc.userAgent}}
+
+		if actual := ClassifyUserAgent(header, BasicClassifier); actual != tc.expected {
+			t.Errorf("'%s': expected class %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
+
+func TestBasicClassifier_NoProducts(t *testing.T) {
+	if actual := BasicClassifier(nil); actual != "unknown" {
+		t.Errorf("expected class %s, got %s", "unknown", actual)
+	}
+}
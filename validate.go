@@ -0,0 +1,140 @@
+package gohttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrBodyTooLarge is returned if a body to be validated or buffered
+	// exceeds the respective limit.
+	ErrBodyTooLarge = errors.New("body too large")
+	// ErrInvalidJSON is returned by JSONValidator for malformed JSON.
+	ErrInvalidJSON = errors.New("invalid JSON")
+)
+
+// BodyValidator validates the body of a message with a certain media type,
+// e.g. against a JSON Schema or a protobuf descriptor.
+type BodyValidator interface {
+	ValidateBody(mediaType string, body []byte) error
+}
+
+// BodyValidatorFunc is a function implementing BodyValidator.
+type BodyValidatorFunc func(mediaType string, body []byte) error
+
+// ValidateBody calls f(mediaType, body).
+func (f BodyValidatorFunc) ValidateBody(mediaType string, body []byte) error {
+	return f(mediaType, body)
+}
+
+// JSONValidator is a BodyValidator that only checks whether the body is
+// well-formed JSON.
+var JSONValidator = BodyValidatorFunc(func(mediaType string, body []byte) error {
+	if !json.Valid(body) {
+		return ErrInvalidJSON
+	}
+	return nil
+})
+
+// BodyValidationError is returned by the parser if a body has been rejected
+// by a validator or is too large to be validated.
+type BodyValidationError struct {
+	// MediaType is the media type of the rejected body.
+	MediaType string
+	// Err is the error returned by the validator, or ErrBodyTooLarge.
+	Err error
+}
+
+func (b *BodyValidationError) Error() string {
+	return fmt.Sprintf("invalid %s body: %s", b.MediaType, b.Err.Error())
+}
+
+func (b *BodyValidationError) Unwrap() error {
+	return b.Err
+}
+
+// StatusCode returns the status code for responding to a request with an
+// invalid body: 413 if the body is too large, and 400 otherwise.
+func (b *BodyValidationError) StatusCode() int {
+	if errors.Is(b.Err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+type bodyValidator struct {
+	mediaType string
+	validator BodyValidator
+	maxBytes  int64
+}
+
+// WithBodyValidator validates the bodies of parsed messages with the given
+// media type during parsing, e.g. "application/json" or "application/*".
+// Matching bodies are buffered up to maxBytes, and larger bodies are
+// rejected. Bodies of other media types are streamed as usual.
+func WithBodyValidator(mediaType string, validator BodyValidator, maxBytes int64) Option {
+	return func(c *config) {
+		c.bodyValidators = append(c.bodyValidators, bodyValidator{
+			mediaType: strings.ToLower(mediaType),
+			validator: validator,
+			maxBytes:  maxBytes,
+		})
+	}
+}
+
+// validateBody runs the first validator matching the Content-Type of a
+// message and returns the buffered body.
+func validateBody(headers http.Header, body io.ReadCloser, config config) (io.ReadCloser, error) {
+	if len(config.bodyValidators) == 0 || body == http.NoBody {
+		return body, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(headers.Get("Content-Type"))
+	if err != nil {
+		return body, nil
+	}
+
+	for _, v := range config.bodyValidators {
+		if !matchesMediaType(mediaType, v.mediaType) {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(io.LimitReader(body, v.maxBytes+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if int64(len(content)) > v.maxBytes {
+			return nil, &BodyValidationError{MediaType: mediaType, Err: ErrBodyTooLarge}
+		}
+
+		if err := v.validator.ValidateBody(mediaType, content); err != nil {
+			return nil, &BodyValidationError{MediaType: mediaType, Err: err}
+		}
+
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	}
+
+	return body, nil
+}
+
+// matchesMediaType reports whether the media type matches the pattern, which
+// is either a media type or a media range like "application/*".
+func matchesMediaType(mediaType, pattern string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package gohttp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWithBodyValidator(t *testing.T) {
+	testCases := map[string]struct {
+		contentType    string
+		body           string
+		expectedError  error
+		expectedStatus int
+	}{
+		"valid JSON": {
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name": "gohttp"}`,
+		},
+		"invalid JSON": {
+			contentType:    "application/json",
+			body:           `{"name": }`,
+			expectedError:  ErrInvalidJSON,
+			expectedStatus: 400,
+		},
+		"too large": {
+			contentType:    "application/json",
+			body:           `["` + strings.Repeat("a", 32) + `"]`,
+			expectedError:  ErrBodyTooLarge,
+			expectedStatus: 413,
+		},
+		"other media type": {
+			contentType: "text/plain",
+			body:        `{"name": }`,
+		},
+	}
+
+	for name, tc := range testCases {
+		source := fmt.Sprintf("POST / HTTP/1.1\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s", tc.contentType, len(tc.body), tc.body)
+
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(source)), WithBodyValidator("application/*", JSONValidator, 32))
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+			continue
+		}
+
+		if err != nil {
+			var validationError *BodyValidationError
+			if !errors.As(err, &validationError) || validationError.StatusCode() != tc.expectedStatus {
+				t.Errorf("'%s': expected a BodyValidationError with status code %d, got %v", name, tc.expectedStatus, err)
+			}
+			continue
+		}
+
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if string(body) != tc.body {
+			t.Errorf("'%s': expected body %s, got %s", name, tc.body, string(body))
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package gohttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// VHostMux is an http.Handler that routes requests to different handlers
+// depending on the requested host.
+//
+// Hosts are registered either exactly, like "example.com", or using a
+// wildcard for the leftmost label, like "*.example.com". Just like TLS
+// certificate wildcards, a wildcard matches exactly one label. Exact hosts
+// take precedence over wildcards, and the default handler is used if no host
+// matches.
+//
+// Certificates are registered using the same host patterns and precedence
+// rules, and are selected by the TLS server name (SNI) in GetCertificate.
+// This way, the TLS and the Host-based routing can't disagree: a request
+// whose Host header field resolves to a different pattern than its server
+// name is rejected.
+type VHostMux struct {
+	mutex              sync.RWMutex
+	hosts              map[string]http.Handler
+	defaultHandler     http.Handler
+	certificates       map[string]*tls.Certificate
+	defaultCertificate *tls.Certificate
+}
+
+// ErrNoCertificate is returned by GetCertificate if no certificate has been
+// registered for the requested server name.
+var ErrNoCertificate = errors.New("no certificate for server name")
+
+// NewVHostMux creates a new, empty VHostMux.
+func NewVHostMux() *VHostMux {
+	return &VHostMux{
+		hosts:        make(map[string]http.Handler),
+		certificates: make(map[string]*tls.Certificate),
+	}
+}
+
+// Handle registers the handler for the given host pattern.
+func (v *VHostMux) Handle(pattern string, handler http.Handler) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.hosts[normalizeHost(pattern)] = handler
+}
+
+// HandleDefault registers the handler used for requests without a matching
+// host, including HTTP/1.0 requests that don't specify a host at all.
+func (v *VHostMux) HandleDefault(handler http.Handler) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.defaultHandler = handler
+}
+
+// Handler returns the handler for the given host, or nil if neither a host
+// pattern matches nor a default handler is registered.
+func (v *VHostMux) Handler(host string) http.Handler {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	if pattern, ok := v.hostPattern(host); ok {
+		return v.hosts[pattern]
+	}
+
+	return v.defaultHandler
+}
+
+// HandleCertificate registers the certificate for the given host pattern.
+func (v *VHostMux) HandleCertificate(pattern string, certificate *tls.Certificate) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.certificates[normalizeHost(pattern)] = certificate
+}
+
+// HandleDefaultCertificate registers the certificate used for server names
+// without a matching pattern, including clients that don't send one.
+func (v *VHostMux) HandleDefaultCertificate(certificate *tls.Certificate) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.defaultCertificate = certificate
+}
+
+// GetCertificate returns the certificate for the server name of a TLS
+// handshake. It can be used as tls.Config.GetCertificate.
+func (v *VHostMux) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	certificate := v.defaultCertificate
+
+	pattern, ok := matchPattern(hello.ServerName, func(pattern string) bool {
+		_, ok := v.certificates[pattern]
+		return ok
+	})
+	if ok {
+		certificate = v.certificates[pattern]
+	}
+
+	if certificate == nil {
+		return nil, ErrNoCertificate
+	}
+
+	return certificate, nil
+}
+
+// hostPattern returns the pattern of the handler matching the host.
+func (v *VHostMux) hostPattern(host string) (string, bool) {
+	return matchPattern(host, func(pattern string) bool {
+		_, ok := v.hosts[pattern]
+		return ok
+	})
+}
+
+// matchPattern returns the registered pattern matching the host, preferring
+// an exact pattern over a wildcard one.
+func matchPattern(host string, registered func(pattern string) bool) (string, bool) {
+	if host == "" {
+		return "", false
+	}
+
+	host = normalizeHost(host)
+
+	if registered(host) {
+		return host, true
+	}
+
+	if i := strings.IndexByte(host, '.'); i > 0 && registered("*"+host[i:]) {
+		return "*" + host[i:], true
+	}
+
+	return "", false
+}
+
+// ServeHTTP dispatches the request to the handler registered for its host.
+//
+// For absolute-form request targets, the host of the target is used and the
+// Host header field is ignored (RFC 7230, section 5.4.). HTTP/1.1 requests
+// without a host are answered with 400 Bad Request.
+//
+// Requests received over TLS whose host resolves to a different pattern than
+// their server name are answered with 421 Misdirected Request (RFC 7540,
+// section 9.1.2.), so that a connection established for one host can't be
+// used to reach the handler of another one.
+func (v *VHostMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := RequestHost(r)
+
+	if host == "" && r.ProtoAtLeast(1, 1) {
+		http.Error(w, "missing host", http.StatusBadRequest)
+		return
+	}
+
+	if r.TLS != nil && r.TLS.ServerName != "" && !v.samePattern(host, r.TLS.ServerName) {
+		http.Error(w, "misdirected request", http.StatusMisdirectedRequest)
+		return
+	}
+
+	handler := v.Handler(host)
+	if handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// samePattern reports whether the host and the server name resolve to the
+// same handler pattern, or both to the default handler.
+func (v *VHostMux) samePattern(host, serverName string) bool {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	hostPattern, _ := v.hostPattern(host)
+	serverNamePattern, _ := v.hostPattern(serverName)
+
+	return hostPattern == serverNamePattern
+}
+
+// RequestHost returns the host requested by a parsed request, including the
+// port if specified. The host of an absolute-form target takes precedence
+// over the Host header field.
+func RequestHost(r *http.Request) string {
+	if r.URL != nil && r.URL.Host != "" {
+		return r.URL.Host
+	}
+
+	if host := r.Header.Get("Host"); host != "" {
+		return host
+	}
+
+	return r.Host
+}
+
+// normalizeHost removes the port, IPv6 brackets, and a trailing dot from a
+// host and converts it to lower case.
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	host = strings.TrimSuffix(host, ".")
+
+	return strings.ToLower(host)
+}
@@ -0,0 +1,178 @@
+package gohttp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVHostMux(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name))
+		})
+	}
+
+	mux := NewVHostMux()
+	mux.Handle("example.com", named("exact"))
+	mux.Handle("*.example.com", named("wildcard"))
+	mux.Handle("api.example.com", named("api"))
+	mux.HandleDefault(named("default"))
+
+	testCases := map[string]struct {
+		source         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		"exact host": {
+			source:         "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "exact",
+		},
+		"exact host with port and upper case": {
+			source:         "GET / HTTP/1.1\r\nHost: EXAMPLE.com:8080\r\n\r\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "exact",
+		},
+		"exact host before wildcard": {
+			source:         "GET / HTTP/1.1\r\nHost: api.example.com\r\n\r\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "api",
+		},
+		"wildcard host": {
+			source:         "GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "wildcard",
+		},
+		"wildcard matches one label only": {
+			source:         "GET / HTTP/1.1\r\nHost: a.b.example.com\r\n\r\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "default",
+		},
+		"absolute-form target": {
+			source:         "GET http://www.example.com/ HTTP/1.1\r\nHost: other.org\r\n\r\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "wildcard",
+		},
+		"missing host on HTTP/1.0": {
+			source:         "GET / HTTP/1.0\r\n\r\n",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "default",
+		},
+		"missing host on HTTP/1.1": {
+			source:         "GET / HTTP/1.1\r\n\r\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, tc := range testCases {
+		request, err := ParseRequest(bufio.NewReader(strings.NewReader(tc.source)))
+		if err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("'%s': expected status code %d, got %d", name, tc.expectedStatus, recorder.Code)
+		}
+
+		if tc.expectedBody != "" && recorder.Body.String() != tc.expectedBody {
+			t.Errorf("'%s': expected handler %s, got %s", name, tc.expectedBody, recorder.Body.String())
+		}
+	}
+}
+
+func TestVHostMux_GetCertificate(t *testing.T) {
+	exact, wildcard, fallback := &tls.Certificate{}, &tls.Certificate{}, &tls.Certificate{}
+
+	mux := NewVHostMux()
+	mux.HandleCertificate("api.example.com", exact)
+	mux.HandleCertificate("*.example.com", wildcard)
+
+	testCases := map[string]struct {
+		serverName    string
+		withDefault   bool
+		expected      *tls.Certificate
+		expectedError error
+	}{
+		"exact":                      {serverName: "API.example.com", expected: exact},
+		"wildcard":                   {serverName: "www.example.com", expected: wildcard},
+		"wildcard matches one label": {serverName: "a.b.example.com", expectedError: ErrNoCertificate},
+		"no server name":             {serverName: "", expectedError: ErrNoCertificate},
+		"default":                    {serverName: "other.org", withDefault: true, expected: fallback},
+	}
+
+	for name, tc := range testCases {
+		mux.HandleDefaultCertificate(nil)
+		if tc.withDefault {
+			mux.HandleDefaultCertificate(fallback)
+		}
+
+		certificate, err := mux.GetCertificate(&tls.ClientHelloInfo{ServerName: tc.serverName})
+		if !errors.Is(err, tc.expectedError) {
+			t.Errorf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if certificate != tc.expected {
+			t.Errorf("'%s': expected certificate %p, got %p", name, tc.expected, certificate)
+		}
+	}
+}
+
+func TestVHostMux_ServerName(t *testing.T) {
+	mux := NewVHostMux()
+	mux.Handle("*.example.com", http.NotFoundHandler())
+	mux.Handle("admin.example.com", http.NotFoundHandler())
+	mux.HandleDefault(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	testCases := map[string]struct {
+		host           string
+		serverName     string
+		expectedStatus int
+	}{
+		"same host":                {host: "www.example.com", serverName: "www.example.com", expectedStatus: http.StatusNotFound},
+		"same wildcard":            {host: "a.example.com", serverName: "b.example.com", expectedStatus: http.StatusNotFound},
+		"exact host, wildcard SNI": {host: "admin.example.com", serverName: "www.example.com", expectedStatus: http.StatusMisdirectedRequest},
+		"default host":             {host: "other.org", serverName: "www.example.com", expectedStatus: http.StatusMisdirectedRequest},
+		"both default":             {host: "other.org", serverName: "another.org", expectedStatus: http.StatusOK},
+		"no server name":           {host: "admin.example.com", expectedStatus: http.StatusNotFound},
+	}
+
+	for name, tc := range testCases {
+		request := httptest.NewRequest("GET", "/", nil)
+		request.Host = tc.host
+		request.TLS = &tls.ConnectionState{ServerName: tc.serverName}
+
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+
+		if recorder.Code != tc.expectedStatus {
+			t.Errorf("'%s': expected status code %d, got %d", name, tc.expectedStatus, recorder.Code)
+		}
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	testCases := map[string]struct {
+		host     string
+		expected string
+	}{
+		"plain host":        {host: "example.com", expected: "example.com"},
+		"host with port":    {host: "example.com:443", expected: "example.com"},
+		"trailing dot":      {host: "Example.COM.", expected: "example.com"},
+		"IPv6 with port":    {host: "[::1]:8080", expected: "::1"},
+		"IPv6 without port": {host: "[::1]", expected: "::1"},
+	}
+
+	for name, tc := range testCases {
+		if actual := normalizeHost(tc.host); actual != tc.expected {
+			t.Errorf("'%s': expected host %s, got %s", name, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+// Package warc reads and writes Web ARChive files (WARC 1.1). Transactions
+// are stored as pairs of request and response records, so that captures
+// made with the gohttp package can be consumed by standard archiving tools
+// and vice versa.
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// version is the WARC version line written and accepted.
+const version = "WARC/1.1"
+
+// defaultMaxRecordSize is the maximum size of a content block read by a
+// Reader unless specified otherwise.
+const defaultMaxRecordSize = 256 << 20
+
+// ErrRecordTooLarge is returned for a record whose content block exceeds the
+// maximum record size of a Reader.
+var ErrRecordTooLarge = errors.New("WARC record too large")
+
+// Record is a single WARC record.
+type Record struct {
+	// Header holds the WARC named fields of the record.
+	Header http.Header
+	// Block is the content block, e.g. a serialized HTTP message.
+	Block []byte
+}
+
+// Type returns the WARC-Type of the record, e.g. "request".
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// ParseRequest parses the content block of a request record.
+func (r *Record) ParseRequest(options ...gohttp.Option) (*http.Request, error) {
+	return gohttp.ParseRequest(bufio.NewReader(bytes.NewReader(r.Block)), options...)
+}
+
+// ParseResponse parses the content block of a response record.
+func (r *Record) ParseResponse(options ...gohttp.Option) (*http.Response, error) {
+	return gohttp.ParseResponse(bufio.NewReader(bytes.NewReader(r.Block)), options...)
+}
+
+// Writer writes transactions as WARC records.
+type Writer struct {
+	w io.Writer
+	// Scheme is used to build the WARC-Target-URI of requests in origin-form.
+	// Defaults to "http".
+	Scheme string
+	// Filter decides which transactions are written, e.g. a filter created
+	// with capture.Transaction. If nil, all transactions are written.
+	Filter func(transaction gohttp.Transaction) bool
+}
+
+// NewWriter creates a new Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w:      w,
+		Scheme: "http",
+	}
+}
+
+// WriteTransaction writes a request record and a response record for the
+// transaction. Both records reference each other via WARC-Concurrent-To.
+// Transactions rejected by the Filter are skipped.
+func (w *Writer) WriteTransaction(transaction gohttp.Transaction) error {
+	if w.Filter != nil && !w.Filter(transaction) {
+		return nil
+	}
+
+	request, err := gohttp.ParseRequest(bufio.NewReader(bytes.NewReader(transaction.Request)))
+	if err != nil {
+		return err
+	}
+
+	date := transaction.Time
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	targetURI := w.targetURI(request)
+	requestID, responseID := newRecordID(), newRecordID()
+
+	requestRecord := &Record{
+		Header: http.Header{},
+		Block:  transaction.Request,
+	}
+	setFields(requestRecord.Header, "request", requestID, responseID, targetURI, date)
+
+	if err := w.WriteRecord(requestRecord); err != nil {
+		return err
+	}
+
+	if transaction.Response == nil {
+		return nil
+	}
+
+	responseRecord := &Record{
+		Header: http.Header{},
+		Block:  transaction.Response,
+	}
+	setFields(responseRecord.Header, "response", responseID, requestID, targetURI, date)
+
+	return w.WriteRecord(responseRecord)
+}
+
+// WriteRecord writes a single record. The WARC-Block-Digest, the
+// WARC-Payload-Digest for HTTP messages, and the Content-Length are set
+// automatically.
+func (w *Writer) WriteRecord(record *Record) error {
+	record.Header.Set("WARC-Block-Digest", digest(record.Block))
+
+	if strings.HasPrefix(record.Header.Get("Content-Type"), "application/http") {
+		record.Header.Set("WARC-Payload-Digest", digest(payload(record.Block)))
+	}
+
+	record.Header.Set("Content-Length", strconv.Itoa(len(record.Block)))
+
+	var buf bytes.Buffer
+
+	buf.WriteString(version + "\r\n")
+
+	// Write the named fields in a stable order, starting with the mandatory
+	// ones as recommended by the specification.
+	for _, name := range orderedFields(record.Header) {
+		for _, value := range record.Header[name] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", warcFieldName(name), value)
+		}
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(record.Block)
+	buf.WriteString("\r\n\r\n")
+
+	_, err := w.w.Write(buf.Bytes())
+	return err
+}
+
+func (w *Writer) targetURI(request *http.Request) string {
+	if request.URL.IsAbs() {
+		return request.URL.String()
+	}
+	return w.Scheme + "://" + gohttp.RequestHost(request) + request.URL.RequestURI()
+}
+
+// Reader reads WARC records.
+type Reader struct {
+	r *bufio.Reader
+	// MaxRecordSize is the maximum size of a content block in bytes. Larger
+	// records are rejected with ErrRecordTooLarge. If zero, a maximum of
+	// 256 MiB is used.
+	MaxRecordSize int64
+}
+
+// NewReader creates a new Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Next reads the next record. It returns io.EOF if there are no more
+// records.
+func (r *Reader) Next() (*Record, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) && line == "" {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	if strings.TrimRight(line, "\r\n") != version {
+		return nil, fmt.Errorf("unsupported WARC version line %q", strings.TrimSpace(line))
+	}
+
+	record := &Record{
+		Header: http.Header{},
+	}
+
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		if line == "\r\n" || line == "\n" {
+			break
+		}
+
+		tokens := strings.SplitN(line, ":", 2)
+		if len(tokens) != 2 {
+			return nil, errors.New("invalid WARC named field syntax")
+		}
+
+		record.Header.Add(strings.TrimSpace(tokens[0]), strings.TrimSpace(tokens[1]))
+	}
+
+	length, err := strconv.ParseInt(record.Header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, errors.New("invalid WARC Content-Length")
+	}
+
+	max := r.MaxRecordSize
+	if max <= 0 {
+		max = defaultMaxRecordSize
+	}
+
+	if length > max {
+		return nil, ErrRecordTooLarge
+	}
+
+	// The block is read incrementally rather than allocated up front, so
+	// that a record claiming a large length without providing the data
+	// doesn't allocate the claimed size.
+	if record.Block, err = ioutil.ReadAll(io.LimitReader(r.r, length)); err != nil {
+		return nil, err
+	}
+
+	if int64(len(record.Block)) != length {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(r.r, trailer); err != nil || string(trailer) != "\r\n\r\n" {
+		return nil, errors.New("missing record terminator")
+	}
+
+	return record, nil
+}
+
+// ReadTransactions reads all records and pairs request records with the
+// response records following them. Other record types are skipped.
+func (r *Reader) ReadTransactions() ([]gohttp.Transaction, error) {
+	var transactions []gohttp.Transaction
+	var pending *gohttp.Transaction
+
+	for {
+		record, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch record.Type() {
+		case "request":
+			if pending != nil {
+				transactions = append(transactions, *pending)
+			}
+			date, _ := time.Parse(time.RFC3339Nano, record.Header.Get("WARC-Date"))
+			pending = &gohttp.Transaction{
+				Time:         date,
+				Request:      record.Block,
+				RequestSizes: gohttp.MeasureMessage(record.Block),
+			}
+		case "response":
+			if pending != nil {
+				pending.Response = record.Block
+				pending.ResponseSizes = gohttp.MeasureMessage(record.Block)
+				transactions = append(transactions, *pending)
+				pending = nil
+			}
+		}
+	}
+
+	if pending != nil {
+		transactions = append(transactions, *pending)
+	}
+
+	return transactions, nil
+}
+
+func setFields(header http.Header, recordType, id, concurrentTo, targetURI string, date time.Time) {
+	header.Set("WARC-Type", recordType)
+	header.Set("WARC-Record-ID", id)
+	header.Set("WARC-Date", date.UTC().Format(time.RFC3339Nano))
+	header.Set("WARC-Target-URI", targetURI)
+	header.Set("WARC-Concurrent-To", concurrentTo)
+	header.Set("Content-Type", "application/http;msgtype="+recordType)
+}
+
+// mandatoryFields are written first, in this order.
+var mandatoryFields = []string{"Warc-Type", "Warc-Record-Id", "Warc-Date", "Content-Length"}
+
+func orderedFields(header http.Header) []string {
+	names := make([]string, 0, len(header))
+
+	for _, name := range mandatoryFields {
+		if _, ok := header[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	var rest []string
+	for name := range header {
+		if !contains(mandatoryFields, name) {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(names, rest...)
+}
+
+// warcFieldName restores the conventional spelling of WARC field names that
+// has been lost by canonicalization, e.g. "Warc-Record-Id".
+func warcFieldName(name string) string {
+	if !strings.HasPrefix(name, "Warc-") {
+		return name
+	}
+
+	name = "WARC-" + strings.TrimPrefix(name, "Warc-")
+
+	switch {
+	case strings.HasSuffix(name, "-Id"):
+		name = strings.TrimSuffix(name, "-Id") + "-ID"
+	case strings.HasSuffix(name, "-Uri"):
+		name = strings.TrimSuffix(name, "-Uri") + "-URI"
+	}
+
+	return name
+}
+
+// digest returns the SHA-1 digest in the form commonly used in WARC files.
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// payload returns the body of a serialized HTTP message.
+func payload(message []byte) []byte {
+	if i := bytes.Index(message, []byte("\r\n\r\n")); i >= 0 {
+		return message[i+4:]
+	}
+	return nil
+}
+
+func newRecordID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+
+	// Set the version (4) and the variant (RFC 4122).
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
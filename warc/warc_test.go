@@ -0,0 +1,150 @@
+package warc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+func TestWriterAndReader(t *testing.T) {
+	transaction := gohttp.Transaction{
+		Time:     time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Request:  []byte("GET /index.html HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		Response: []byte("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello"),
+	}
+
+	var buf bytes.Buffer
+
+	writer := NewWriter(&buf)
+	writer.Scheme = "https"
+
+	if err := writer.WriteTransaction(transaction); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	output := buf.String()
+
+	expectedFields := []string{
+		"WARC/1.1\r\nWARC-Type: request\r\n",
+		"WARC-Target-URI: https://example.com/index.html\r\n",
+		"WARC-Date: 2020-01-02T03:04:05Z\r\n",
+		"Content-Type: application/http;msgtype=response\r\n",
+		// SHA-1 of "hello".
+		"WARC-Payload-Digest: sha1:VL2MMHO4YXUKFWV63YHTWSBM3GXKSQ2N\r\n",
+	}
+
+	for _, field := range expectedFields {
+		if !strings.Contains(output, field) {
+			t.Errorf("expected output to contain %q, got %q", field, output)
+		}
+	}
+
+	reader := NewReader(&buf)
+
+	transactions, err := reader.ReadTransactions()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if len(transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(transactions))
+	}
+
+	actual := transactions[0]
+
+	if !bytes.Equal(actual.Request, transaction.Request) || !bytes.Equal(actual.Response, transaction.Response) {
+		t.Errorf("expected transaction %v, got %v", transaction, actual)
+	}
+
+	if !actual.Time.Equal(transaction.Time) {
+		t.Errorf("expected time %v, got %v", transaction.Time, actual.Time)
+	}
+}
+
+func TestRecord_ParseResponse(t *testing.T) {
+	var buf bytes.Buffer
+
+	record := &Record{
+		Header: map[string][]string{
+			"Warc-Type":    {"response"},
+			"Content-Type": {"application/http;msgtype=response"},
+		},
+		Block: []byte("HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n"),
+	}
+
+	if err := NewWriter(&buf).WriteRecord(record); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	read, err := NewReader(&buf).Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if read.Type() != "response" {
+		t.Errorf("expected type %s, got %s", "response", read.Type())
+	}
+
+	response, err := read.ParseResponse()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if response.StatusCode != 404 {
+		t.Errorf("expected status code %d, got %d", 404, response.StatusCode)
+	}
+}
+
+func TestReader_Invalid(t *testing.T) {
+	testCases := map[string]string{
+		"wrong version":      "WARC/0.9\r\n\r\n",
+		"missing length":     "WARC/1.1\r\nWARC-Type: request\r\n\r\n",
+		"missing terminator": "WARC/1.1\r\nContent-Length: 2\r\n\r\nab",
+		"truncated block":    "WARC/1.1\r\nContent-Length: 10\r\n\r\nab",
+		"huge length":        "WARC/1.1\r\nContent-Length: 1000000000000000000\r\n\r\nab",
+		"large length":       "WARC/1.1\r\nContent-Length: 4000000000\r\n\r\nab",
+	}
+
+	for name, source := range testCases {
+		if _, err := NewReader(strings.NewReader(source)).Next(); err == nil {
+			t.Errorf("'%s': expected an error, got none", name)
+		}
+	}
+}
+
+func TestReader_MaxRecordSize(t *testing.T) {
+	source := "WARC/1.1\r\nContent-Length: 5\r\n\r\nhello\r\n\r\n"
+
+	reader := NewReader(strings.NewReader(source))
+	reader.MaxRecordSize = 4
+
+	if _, err := reader.Next(); !errors.Is(err, ErrRecordTooLarge) {
+		t.Errorf("expected error %v, got %v", ErrRecordTooLarge, err)
+	}
+
+	reader = NewReader(strings.NewReader(source))
+	reader.MaxRecordSize = 5
+
+	if record, err := reader.Next(); err != nil || string(record.Block) != "hello" {
+		t.Errorf("expected block %s, got %v", "hello", err)
+	}
+}
+
+func TestWarcFieldName(t *testing.T) {
+	testCases := map[string]string{
+		"Warc-Record-Id":               "WARC-Record-ID",
+		"Warc-Target-Uri":              "WARC-Target-URI",
+		"Warc-Identified-Payload-Type": "WARC-Identified-Payload-Type",
+		"Content-Type":                 "Content-Type",
+	}
+
+	for name, expected := range testCases {
+		if actual := warcFieldName(name); actual != expected {
+			t.Errorf("'%s': expected %s, got %s", name, expected, actual)
+		}
+	}
+}
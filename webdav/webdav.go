@@ -0,0 +1,243 @@
+// Package webdav provides helpers for WebDAV messages (RFC 4918): parsing
+// the Depth, Destination, and Overwrite header fields and PROPFIND bodies,
+// and building 207 Multi-Status responses. The WebDAV methods themselves are
+// registered as known methods in the gohttp package.
+package webdav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dominikbraun/gohttp"
+)
+
+// Namespace is the XML namespace of WebDAV elements.
+const Namespace = "DAV:"
+
+var (
+	// ErrInvalidDepth is returned for a Depth value other than 0, 1, and
+	// infinity.
+	ErrInvalidDepth = errors.New("invalid Depth header field")
+	// ErrNoDestination is returned if a request has no Destination.
+	ErrNoDestination = errors.New("missing Destination header field")
+	// ErrInvalidOverwrite is returned for an Overwrite value other than T
+	// and F.
+	ErrInvalidOverwrite = errors.New("invalid Overwrite header field")
+	// ErrInvalidPropfind is returned for a body that isn't a propfind
+	// element.
+	ErrInvalidPropfind = errors.New("invalid PROPFIND body")
+)
+
+// Depth is the value of the Depth header field.
+type Depth int
+
+const (
+	// DepthZero applies a method to the resource only.
+	DepthZero Depth = 0
+	// DepthOne applies a method to the resource and its members.
+	DepthOne Depth = 1
+	// DepthInfinity applies a method to the resource and all its
+	// descendants.
+	DepthInfinity Depth = -1
+)
+
+// ParseDepth parses the Depth header field. If the field is missing, the
+// given default is returned, which is DepthInfinity for PROPFIND requests
+// (RFC 4918, section 9.1.).
+func ParseDepth(header http.Header, defaultDepth Depth) (Depth, error) {
+	value := header.Get("Depth")
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return defaultDepth, nil
+	case "0":
+		return DepthZero, nil
+	case "1":
+		return DepthOne, nil
+	case "infinity":
+		return DepthInfinity, nil
+	}
+
+	return 0, ErrInvalidDepth
+}
+
+// Destination returns the URL of the Destination header field of a COPY or
+// MOVE request, resolved against the request URL.
+func Destination(r *http.Request) (*url.URL, error) {
+	value := r.Header.Get("Destination")
+	if value == "" {
+		return nil, ErrNoDestination
+	}
+
+	destination, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.URL == nil {
+		return destination, nil
+	}
+
+	return r.URL.ResolveReference(destination), nil
+}
+
+// Overwrite parses the Overwrite header field, which is true if the field
+// is missing (RFC 4918, section 10.6.).
+func Overwrite(header http.Header) (bool, error) {
+	switch strings.TrimSpace(header.Get("Overwrite")) {
+	case "", "T", "t":
+		return true, nil
+	case "F", "f":
+		return false, nil
+	}
+
+	return false, ErrInvalidOverwrite
+}
+
+// Propfind is a parsed PROPFIND request body.
+type Propfind struct {
+	// AllProp requests all properties. It is also set for an empty body.
+	AllProp bool
+	// PropName requests the names of all properties.
+	PropName bool
+	// Props are the names of the requested properties, if neither AllProp
+	// nor PropName is set.
+	Props []xml.Name
+}
+
+type propfindBody struct {
+	XMLName  xml.Name   `xml:"DAV: propfind"`
+	AllProp  *struct{}  `xml:"DAV: allprop"`
+	PropName *struct{}  `xml:"DAV: propname"`
+	Prop     *propNames `xml:"DAV: prop"`
+}
+
+type propNames struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+// ParsePropfind parses the body of a PROPFIND request. An empty body
+// requests all properties (RFC 4918, section 9.1.).
+func ParsePropfind(body []byte) (*Propfind, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return &Propfind{AllProp: true}, nil
+	}
+
+	var parsed propfindBody
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		return nil, ErrInvalidPropfind
+	}
+
+	propfind := &Propfind{
+		AllProp:  parsed.AllProp != nil,
+		PropName: parsed.PropName != nil,
+	}
+
+	if parsed.Prop != nil {
+		for _, name := range parsed.Prop.Names {
+			propfind.Props = append(propfind.Props, name.XMLName)
+		}
+	}
+
+	if !propfind.AllProp && !propfind.PropName && parsed.Prop == nil {
+		return nil, ErrInvalidPropfind
+	}
+
+	return propfind, nil
+}
+
+// Property is a property of a resource. Value holds the raw XML content of
+// the property element.
+type Property struct {
+	Name  xml.Name
+	Value string
+}
+
+// Propstat groups properties sharing the same status.
+type Propstat struct {
+	Props      []Property
+	StatusCode int
+}
+
+// Response describes a single resource within a Multi-Status response.
+// Either StatusCode or Propstats should be set.
+type Response struct {
+	Href       string
+	StatusCode int
+	Propstats  []Propstat
+}
+
+type multistatusXML struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []responseXML `xml:"response"`
+}
+
+type responseXML struct {
+	Href      string        `xml:"href"`
+	Status    string        `xml:"status,omitempty"`
+	Propstats []propstatXML `xml:"propstat"`
+}
+
+type propstatXML struct {
+	Props  []propertyXML `xml:"prop>property"`
+	Status string        `xml:"status"`
+}
+
+type propertyXML struct {
+	XMLName xml.Name
+	Value   string `xml:",innerxml"`
+}
+
+// NewMultiStatus creates a 207 Multi-Status response for the given resources
+// (RFC 4918, section 13.).
+func NewMultiStatus(responses ...Response) (*http.Response, error) {
+	multistatus := multistatusXML{}
+
+	for _, r := range responses {
+		response := responseXML{
+			Href: r.Href,
+		}
+
+		if r.StatusCode != 0 {
+			response.Status = statusLine(r.StatusCode)
+		}
+
+		for _, p := range r.Propstats {
+			propstat := propstatXML{
+				Status: statusLine(p.StatusCode),
+			}
+
+			for _, property := range p.Props {
+				propstat.Props = append(propstat.Props, propertyXML{
+					XMLName: property.Name,
+					Value:   property.Value,
+				})
+			}
+
+			response.Propstats = append(response.Propstats, propstat)
+		}
+
+		multistatus.Responses = append(multistatus.Responses, response)
+	}
+
+	body, err := xml.Marshal(multistatus)
+	if err != nil {
+		return nil, err
+	}
+
+	response := gohttp.NewResponse(http.StatusMultiStatus, append([]byte(xml.Header), body...))
+	response.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	return response, nil
+}
+
+func statusLine(statusCode int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", statusCode, http.StatusText(statusCode))
+}
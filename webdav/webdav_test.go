@@ -0,0 +1,167 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDepth(t *testing.T) {
+	testCases := map[string]struct {
+		value         string
+		expectedDepth Depth
+		expectedError error
+	}{
+		"missing":  {value: "", expectedDepth: DepthInfinity},
+		"zero":     {value: "0", expectedDepth: DepthZero},
+		"one":      {value: "1", expectedDepth: DepthOne},
+		"infinity": {value: "Infinity", expectedDepth: DepthInfinity},
+		"invalid":  {value: "2", expectedError: ErrInvalidDepth},
+	}
+
+	for name, tc := range testCases {
+		header := http.Header{}
+		if tc.value != "" {
+			header.Set("Depth", tc.value)
+		}
+
+		depth, err := ParseDepth(header, DepthInfinity)
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if depth != tc.expectedDepth {
+			t.Errorf("'%s': expected depth %d, got %d", name, tc.expectedDepth, depth)
+		}
+	}
+}
+
+func TestDestination(t *testing.T) {
+	request := httptest.NewRequest("MOVE", "http://example.com/a/b.txt", nil)
+
+	if _, err := Destination(request); !errors.Is(err, ErrNoDestination) {
+		t.Errorf("expected error %v, got %v", ErrNoDestination, err)
+	}
+
+	request.Header.Set("Destination", "/c/d.txt")
+
+	destination, err := Destination(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if destination.String() != "http://example.com/c/d.txt" {
+		t.Errorf("expected destination %s, got %s", "http://example.com/c/d.txt", destination.String())
+	}
+}
+
+func TestOverwrite(t *testing.T) {
+	testCases := map[string]struct {
+		value         string
+		expected      bool
+		expectedError error
+	}{
+		"missing": {value: "", expected: true},
+		"true":    {value: "T", expected: true},
+		"false":   {value: "F", expected: false},
+		"invalid": {value: "yes", expectedError: ErrInvalidOverwrite},
+	}
+
+	for name, tc := range testCases {
+		header := http.Header{"Overwrite": []string{tc.value}}
+
+		overwrite, err := Overwrite(header)
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if overwrite != tc.expected {
+			t.Errorf("'%s': expected overwrite %v, got %v", name, tc.expected, overwrite)
+		}
+	}
+}
+
+func TestParsePropfind(t *testing.T) {
+	testCases := map[string]struct {
+		body          string
+		expected      *Propfind
+		expectedError error
+	}{
+		"empty": {
+			body:     "",
+			expected: &Propfind{AllProp: true},
+		},
+		"allprop": {
+			body:     `<?xml version="1.0"?><D:propfind xmlns:D="DAV:"><D:allprop/></D:propfind>`,
+			expected: &Propfind{AllProp: true},
+		},
+		"propname": {
+			body:     `<propfind xmlns="DAV:"><propname/></propfind>`,
+			expected: &Propfind{PropName: true},
+		},
+		"prop": {
+			body: `<D:propfind xmlns:D="DAV:" xmlns:X="urn:x"><D:prop><D:getetag/><X:color/></D:prop></D:propfind>`,
+			expected: &Propfind{Props: []xml.Name{
+				{Space: "DAV:", Local: "getetag"},
+				{Space: "urn:x", Local: "color"},
+			}},
+		},
+		"wrong root": {
+			body:          `<propertyupdate xmlns="DAV:"/>`,
+			expectedError: ErrInvalidPropfind,
+		},
+	}
+
+	for name, tc := range testCases {
+		propfind, err := ParsePropfind([]byte(tc.body))
+		if !errors.Is(err, tc.expectedError) {
+			t.Fatalf("'%s': expected error %v, got %v", name, tc.expectedError, err)
+		}
+
+		if !reflect.DeepEqual(propfind, tc.expected) {
+			t.Errorf("'%s': expected %+v, got %+v", name, tc.expected, propfind)
+		}
+	}
+}
+
+func TestNewMultiStatus(t *testing.T) {
+	response, err := NewMultiStatus(
+		Response{
+			Href: "/a.txt",
+			Propstats: []Propstat{{
+				StatusCode: http.StatusOK,
+				Props: []Property{
+					{Name: xml.Name{Space: Namespace, Local: "getcontentlength"}, Value: "12"},
+				},
+			}},
+		},
+		Response{
+			Href:       "/b.txt",
+			StatusCode: http.StatusNotFound,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if response.StatusCode != http.StatusMultiStatus {
+		t.Errorf("expected status code %d, got %d", http.StatusMultiStatus, response.StatusCode)
+	}
+
+	body, _ := ioutil.ReadAll(response.Body)
+
+	for _, expected := range []string{
+		`<multistatus xmlns="DAV:">`,
+		`<href>/a.txt</href><propstat><prop><getcontentlength xmlns="DAV:">12</getcontentlength></prop><status>HTTP/1.1 200 OK</status></propstat>`,
+		`<href>/b.txt</href><status>HTTP/1.1 404 Not Found</status>`,
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected body to contain %s, got %s", expected, string(body))
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var (
+	_ http.ResponseWriter = (*ResponseWriter)(nil)
+	_ http.Flusher        = (*ResponseWriter)(nil)
+	_ http.Hijacker       = (*ResponseWriter)(nil)
+)
+
+// ResponseWriter is an http.ResponseWriter writing responses to a raw
+// connection using this package's serializer.
+//
+// The header section is buffered until the first flush or until the response
+// is finished. Responses finished without flushing are sent with a
+// Content-Length header field. Flushing earlier, as well as declaring
+// trailers, switches to the chunked transfer coding on HTTP/1.1 connections.
+// ResponseWriter also implements http.Flusher and http.Hijacker.
+type ResponseWriter struct {
+	conn        net.Conn
+	rw          *bufio.ReadWriter
+	proto       string
+	method      string
+	header      http.Header
+	status      int
+	wroteHeader bool
+	headerSent  bool
+	chunked     bool
+	body        bytes.Buffer
+	hijacked    bool
+	finished    bool
+}
+
+// NewResponseWriter creates a new ResponseWriter for the given request. The
+// response is written to rw, which must be backed by conn.
+func NewResponseWriter(conn net.Conn, rw *bufio.ReadWriter, request *http.Request) *ResponseWriter {
+	proto := "HTTP/1.1"
+	if request != nil && request.ProtoMajor == 1 && request.ProtoMinor == 0 {
+		proto = "HTTP/1.0"
+	}
+
+	var method string
+	if request != nil {
+		method = request.Method
+	}
+
+	return &ResponseWriter{
+		conn:   conn,
+		rw:     rw,
+		proto:  proto,
+		method: method,
+		header: make(http.Header),
+	}
+}
+
+// Header returns the header fields that will be sent. Changing the header
+// fields after the header section has been sent has no effect, except for
+// declared trailers.
+func (w *ResponseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader sets the status code of the response. Only the first call has
+// an effect.
+func (w *ResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader || w.hijacked {
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = statusCode
+}
+
+// Write writes body data. If WriteHeader hasn't been called yet, Write sets
+// the status code to 200. For status codes that don't allow a body, e.g. 204
+// or 304, Write fails with http.ErrBodyNotAllowed.
+func (w *ResponseWriter) Write(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+
+	if w.finished {
+		return 0, errors.New("response has already been finished")
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if !bodyAllowedForStatus(w.status) {
+		return 0, http.ErrBodyNotAllowed
+	}
+
+	if !w.headerSent {
+		return w.body.Write(p)
+	}
+
+	if err := w.writeChunk(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush sends the header section and any buffered body data to the client.
+func (w *ResponseWriter) Flush() {
+	if w.hijacked || w.finished {
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if !w.headerSent {
+		if err := w.sendHeader(); err != nil {
+			return
+		}
+	}
+
+	if err := w.writeChunk(w.body.Bytes()); err != nil {
+		return
+	}
+	w.body.Reset()
+
+	_ = w.rw.Flush()
+}
+
+// Hijack lets the caller take over the connection. After a call to Hijack,
+// the ResponseWriter must not be used anymore.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.hijacked {
+		return nil, nil, http.ErrHijacked
+	}
+
+	if w.finished {
+		return nil, nil, errors.New("response has already been finished")
+	}
+
+	w.hijacked = true
+
+	return w.conn, w.rw, nil
+}
+
+// Finish completes the response, including the final chunk and trailers if
+// the chunked transfer coding is used, and flushes it to the connection.
+func (w *ResponseWriter) Finish() error {
+	if w.hijacked || w.finished {
+		return nil
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.finished = true
+
+	if !w.headerSent && (!w.hasTrailers() || w.proto == "HTTP/1.0") {
+		return w.sendResponse()
+	}
+
+	if !w.headerSent {
+		if err := w.sendHeader(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.writeChunk(w.body.Bytes()); err != nil {
+		return err
+	}
+	w.body.Reset()
+
+	if w.chunked {
+		if _, err := w.rw.WriteString("0\r\n"); err != nil {
+			return err
+		}
+		if err := writeHeaderFields(w.trailers(), w.rw); err != nil {
+			return err
+		}
+	}
+
+	return w.rw.Flush()
+}
+
+// sendResponse sends the entire response using SerializeResponse with a
+// Content-Length header field.
+func (w *ResponseWriter) sendResponse() error {
+	header := w.headerSnapshot()
+
+	if header.Get("Content-Length") == "" && bodyAllowedForStatus(w.status) {
+		header.Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+
+	response := &http.Response{
+		Proto:  w.proto,
+		Status: statusText(w.status),
+		Header: header,
+		Body:   ioutil.NopCloser(&w.body),
+	}
+
+	serialized, err := SerializeResponse(response, WithRequestMethod(w.method))
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.rw.Write(serialized); err != nil {
+		return err
+	}
+
+	return w.rw.Flush()
+}
+
+// sendHeader sends the status line and the header section. Unless a
+// Content-Length has been set, the chunked transfer coding is used for
+// HTTP/1.1 and the connection is closed after the body for HTTP/1.0.
+func (w *ResponseWriter) sendHeader() error {
+	header := w.headerSnapshot()
+
+	if header.Get("Content-Length") == "" && bodyAllowedForStatus(w.status) && w.method != http.MethodHead {
+		if w.proto == "HTTP/1.0" {
+			header.Set("Connection", "close")
+		} else {
+			header.Set("Transfer-Encoding", "chunked")
+			w.chunked = true
+		}
+	}
+
+	w.headerSent = true
+
+	if _, err := fmt.Fprintf(w.rw, "%s %s\r\n", w.proto, statusText(w.status)); err != nil {
+		return err
+	}
+
+	return writeHeaderFields(header, w.rw)
+}
+
+// writeChunk writes body data, framed as a chunk if the chunked transfer
+// coding is used. Body data of responses to HEAD requests is discarded.
+func (w *ResponseWriter) writeChunk(p []byte) error {
+	if len(p) == 0 || w.method == http.MethodHead {
+		return nil
+	}
+
+	if !w.chunked {
+		_, err := w.rw.Write(p)
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w.rw, "%x\r\n", len(p)); err != nil {
+		return err
+	}
+
+	if _, err := w.rw.Write(p); err != nil {
+		return err
+	}
+
+	_, err := w.rw.WriteString("\r\n")
+	return err
+}
+
+// headerSnapshot returns a copy of the header fields without any trailers.
+func (w *ResponseWriter) headerSnapshot() http.Header {
+	header := w.header.Clone()
+
+	for name := range header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			delete(header, name)
+		}
+	}
+
+	for _, name := range w.declaredTrailers() {
+		delete(header, name)
+	}
+
+	return header
+}
+
+// trailers returns the trailer fields, which are either declared using the
+// Trailer header field or prefixed with http.TrailerPrefix.
+func (w *ResponseWriter) trailers() http.Header {
+	trailers := make(http.Header)
+
+	for _, name := range w.declaredTrailers() {
+		if values, ok := w.header[name]; ok {
+			trailers[name] = values
+		}
+	}
+
+	for name, values := range w.header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			trailers[http.CanonicalHeaderKey(strings.TrimPrefix(name, http.TrailerPrefix))] = values
+		}
+	}
+
+	return trailers
+}
+
+func (w *ResponseWriter) declaredTrailers() []string {
+	var names []string
+
+	for _, value := range w.header["Trailer"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return names
+}
+
+func (w *ResponseWriter) hasTrailers() bool {
+	if len(w.header["Trailer"]) > 0 {
+		return true
+	}
+
+	for name := range w.header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,129 @@
+package gohttp
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	testCases := map[string]struct {
+		proto    string
+		handler  func(w *ResponseWriter)
+		expected string
+	}{
+		"implicit status code": {
+			proto: "HTTP/1.1",
+			handler: func(w *ResponseWriter) {
+				_, _ = w.Write([]byte("hello"))
+			},
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Content-Length: 5\r\n" +
+				"\r\n" +
+				"hello",
+		},
+		"explicit status code without body": {
+			proto: "HTTP/1.1",
+			handler: func(w *ResponseWriter) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			expected: "HTTP/1.1 204 No Content\r\n" +
+				"\r\n",
+		},
+		"body for status without body": {
+			proto: "HTTP/1.1",
+			handler: func(w *ResponseWriter) {
+				w.WriteHeader(http.StatusNotModified)
+				w.Flush()
+				_, _ = w.Write([]byte("hello"))
+			},
+			expected: "HTTP/1.1 304 Not Modified\r\n" +
+				"\r\n",
+		},
+		"flush": {
+			proto: "HTTP/1.1",
+			handler: func(w *ResponseWriter) {
+				_, _ = w.Write([]byte("hello"))
+				w.Flush()
+				_, _ = w.Write([]byte(" world"))
+			},
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"\r\n" +
+				"5\r\nhello\r\n" +
+				"6\r\n world\r\n" +
+				"0\r\n" +
+				"\r\n",
+		},
+		"flush on HTTP/1.0": {
+			proto: "HTTP/1.0",
+			handler: func(w *ResponseWriter) {
+				w.Flush()
+				_, _ = w.Write([]byte("hello"))
+			},
+			expected: "HTTP/1.0 200 OK\r\n" +
+				"Connection: close\r\n" +
+				"\r\n" +
+				"hello",
+		},
+		"prefixed trailer": {
+			proto: "HTTP/1.1",
+			handler: func(w *ResponseWriter) {
+				_, _ = w.Write([]byte("hello"))
+				w.Header().Set(http.TrailerPrefix+"Checksum", "abc")
+			},
+			expected: "HTTP/1.1 200 OK\r\n" +
+				"Transfer-Encoding: chunked\r\n" +
+				"\r\n" +
+				"5\r\nhello\r\n" +
+				"0\r\n" +
+				"Checksum: abc\r\n" +
+				"\r\n",
+		},
+	}
+
+	for name, tc := range testCases {
+		var buf bytes.Buffer
+
+		rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader("")), bufio.NewWriter(&buf))
+		request := &http.Request{Proto: tc.proto}
+		request.ProtoMajor, request.ProtoMinor, _ = http.ParseHTTPVersion(tc.proto)
+
+		w := NewResponseWriter(nil, rw, request)
+		tc.handler(w)
+
+		if err := w.Finish(); err != nil {
+			t.Fatalf("'%s': unexpected error: %s", name, err.Error())
+		}
+
+		if buf.String() != tc.expected {
+			t.Errorf("'%s': expected response %q, got %q", name, tc.expected, buf.String())
+		}
+	}
+}
+
+func TestResponseWriter_Hijack(t *testing.T) {
+	var buf bytes.Buffer
+
+	rw := bufio.NewReadWriter(bufio.NewReader(strings.NewReader("")), bufio.NewWriter(&buf))
+	w := NewResponseWriter(nil, rw, &http.Request{ProtoMajor: 1, ProtoMinor: 1})
+
+	_, hijackedRW, err := w.Hijack()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	if hijackedRW != rw {
+		t.Errorf("expected the original bufio.ReadWriter")
+	}
+
+	if _, err := w.Write([]byte("hello")); err != http.ErrHijacked {
+		t.Errorf("expected error %v, got %v", http.ErrHijacked, err)
+	}
+
+	if err := w.Finish(); err != nil || buf.Len() != 0 {
+		t.Errorf("expected no response to be written, got %q", buf.String())
+	}
+}